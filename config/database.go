@@ -52,6 +52,33 @@ func ConnectDB() {
 		panic("Failed to create payment_status enum: " + err.Error())
 	}
 
+	// Fully refunded invoices (added after the enum was first created)
+	if err := db.Exec(`ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'refunded'`).Error; err != nil {
+		panic("Failed to extend payment_status enum: " + err.Error())
+	}
+
+	// Create invoice_status enum type for invoices (required before creating invoices table)
+	if err := db.Exec(`
+		DO $$ BEGIN
+			CREATE TYPE invoice_status AS ENUM ('draft', 'finalized', 'void');
+		EXCEPTION
+			WHEN duplicate_object THEN null;
+		END $$;
+	`).Error; err != nil {
+		panic("Failed to create invoice_status enum: " + err.Error())
+	}
+
+	// Create appointment_status enum type for appointments (required before creating appointments table)
+	if err := db.Exec(`
+		DO $$ BEGIN
+			CREATE TYPE appointment_status AS ENUM ('booked', 'confirmed', 'checked-in', 'completed', 'cancelled', 'no-show');
+		EXCEPTION
+			WHEN duplicate_object THEN null;
+		END $$;
+	`).Error; err != nil {
+		panic("Failed to create appointment_status enum: " + err.Error())
+	}
+
 	//Optimize connection pool settings
 	// sqlDB.SetMaxIdleConns(25)                 // Increase idle connections
 	// sqlDB.SetMaxOpenConns(100)                // Increase max connections
@@ -0,0 +1,825 @@
+// controllers/appointment.go
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AppointmentStatus string
+
+const (
+	AppointmentBooked    AppointmentStatus = "booked"
+	AppointmentConfirmed AppointmentStatus = "confirmed"
+	AppointmentCheckedIn AppointmentStatus = "checked-in"
+	AppointmentCompleted AppointmentStatus = "completed"
+	AppointmentCancelled AppointmentStatus = "cancelled"
+	AppointmentNoShow    AppointmentStatus = "no-show"
+)
+
+// appointmentTransitions lists the statuses an appointment may move to from each status.
+// Completed, cancelled and no-show are final. Completion goes through CompleteAppointment.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	AppointmentBooked:    {AppointmentConfirmed, AppointmentCheckedIn, AppointmentCancelled, AppointmentNoShow},
+	AppointmentConfirmed: {AppointmentCheckedIn, AppointmentCancelled, AppointmentNoShow},
+	AppointmentCheckedIn: {AppointmentCompleted},
+}
+
+// inactiveAppointmentStatuses are ignored when checking a staff member's calendar for clashes
+var inactiveAppointmentStatuses = []string{string(AppointmentCancelled), string(AppointmentNoShow)}
+
+// CreateAppointmentInput defines the expected JSON structure for booking an appointment
+type CreateAppointmentInput struct {
+	CustomerID  uuid.UUID   `json:"customerId" binding:"required"`
+	StaffUserID uuid.UUID   `json:"staffId" binding:"required"`
+	ServiceIDs  []uuid.UUID `json:"serviceIds" binding:"required,min=1"`
+	StartTime   time.Time   `json:"startTime" binding:"required"`
+	Notes       string      `json:"notes"`
+}
+
+// UpdateAppointmentInput defines the expected JSON structure for rescheduling or editing an appointment
+type UpdateAppointmentInput struct {
+	CustomerID  *uuid.UUID   `json:"customerId"`
+	StaffUserID *uuid.UUID   `json:"staffId"`
+	ServiceIDs  *[]uuid.UUID `json:"serviceIds"`
+	StartTime   *time.Time   `json:"startTime"`
+	Notes       *string      `json:"notes"`
+}
+
+// UpdateAppointmentStatusInput defines the expected JSON structure for moving an appointment through its lifecycle
+type UpdateAppointmentStatusInput struct {
+	Status string `json:"status" binding:"required,oneof=confirmed checked-in cancelled no-show"`
+	Reason string `json:"reason"`
+}
+
+// CompleteAppointmentInput defines the expected JSON structure for completing an appointment.
+// When CreateInvoice is set the booked services are billed through the regular invoice pricing.
+type CompleteAppointmentInput struct {
+	CreateInvoice bool           `json:"createInvoice"`
+	Discount      models.Money   `json:"discount" binding:"min=0"`
+	RedeemPoints  int            `json:"redeemPoints" binding:"min=0"` // Loyalty points taken as a further discount
+	PromotionCode string         `json:"promotionCode"`
+	Tax           float64        `json:"tax" binding:"min=0,max=100"`
+	PlaceOfSupply string         `json:"placeOfSupply" binding:"omitempty,len=2,numeric"` // GST state code; defaults to the salon's
+	Payments      []PaymentInput `json:"payments" binding:"dive"`
+	SendReceipt   bool           `json:"sendReceipt"`  // Message the customer a receipt link
+	SkipPackages  bool           `json:"skipPackages"` // Bill at full price even when a package covers the service
+	Notes         string         `json:"notes"`
+}
+
+// appointmentConflictError is returned when a booking does not fit the salon's hours or the staff member's calendar
+type appointmentConflictError struct {
+	message string
+}
+
+func (e appointmentConflictError) Error() string {
+	return e.message
+}
+
+// CreateAppointment books a new appointment for a customer with a staff member
+func CreateAppointment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input CreateAppointmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Validate customer exists in the same salon
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	appointmentServices, duration, err := buildAppointmentServices(config.DB, salonUUID, input.ServiceIDs)
+	if err != nil {
+		respondAppointmentError(c, err)
+		return
+	}
+
+	appointment := models.Appointment{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		CustomerID:      input.CustomerID,
+		StaffUserID:     input.StaffUserID,
+		StartTime:       input.StartTime,
+		EndTime:         input.StartTime.Add(time.Duration(duration) * time.Minute),
+		Status:          string(AppointmentBooked),
+		Notes:           input.Notes,
+		Services:        appointmentServices,
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := checkAppointmentSlot(tx, salonUUID, appointment.StaffUserID, appointment.StartTime, appointment.EndTime, uuid.Nil); err != nil {
+		tx.Rollback()
+		respondAppointmentError(c, err)
+		return
+	}
+
+	if err := tx.Create(&appointment).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create appointment")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, appointment)
+}
+
+// GetAppointments retrieves the salon's appointments, optionally filtered by
+// date (YYYY-MM-DD), from/to range, staffId, customerId and status
+func GetAppointments(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	query := config.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("salon_id = ?", salonUUID)
+
+	if date := c.Query("date"); date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid date format, expected YYYY-MM-DD")
+			return
+		}
+		query = query.Where("start_time >= ? AND start_time < ?", day, day.AddDate(0, 0, 1))
+	}
+	if from := c.Query("from"); from != "" {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid from time, expected RFC3339")
+			return
+		}
+		query = query.Where("start_time >= ?", fromTime)
+	}
+	if to := c.Query("to"); to != "" {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid to time, expected RFC3339")
+			return
+		}
+		query = query.Where("start_time < ?", toTime)
+	}
+	if staffID := c.Query("staffId"); staffID != "" {
+		staffUUID, err := uuid.Parse(staffID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid staff ID format")
+			return
+		}
+		query = query.Where("staff_user_id = ?", staffUUID)
+	}
+	if customerID := c.Query("customerId"); customerID != "" {
+		customerUUID, err := uuid.Parse(customerID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+			return
+		}
+		query = query.Where("customer_id = ?", customerUUID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var appointments []models.Appointment
+	if err := query.Order("start_time").Find(&appointments).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve appointments")
+		return
+	}
+
+	c.JSON(http.StatusOK, appointments)
+}
+
+// GetAppointment retrieves a specific appointment by ID
+func GetAppointment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	appointmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid appointment ID format")
+		return
+	}
+
+	var appointment models.Appointment
+	if err := config.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("salon_id = ? AND id = ?", salonUUID, appointmentUUID).
+		First(&appointment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Appointment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// UpdateAppointment reschedules or edits an appointment that has not started yet
+func UpdateAppointment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	appointmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid appointment ID format")
+		return
+	}
+
+	var input UpdateAppointmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var appointment models.Appointment
+	if err := tx.Preload("Services").
+		Where("salon_id = ? AND id = ?", salonUUID, appointmentUUID).
+		First(&appointment).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Appointment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	status := AppointmentStatus(appointment.Status)
+	if status != AppointmentBooked && status != AppointmentConfirmed {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Only booked or confirmed appointments can be changed")
+		return
+	}
+
+	if input.CustomerID != nil {
+		var customer models.Customer
+		if err := tx.Where("salon_id = ? AND id = ?", salonUUID, *input.CustomerID).
+			First(&customer).Error; err != nil {
+			tx.Rollback()
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return
+		}
+		appointment.CustomerID = *input.CustomerID
+	}
+
+	if input.StaffUserID != nil {
+		appointment.StaffUserID = *input.StaffUserID
+	}
+
+	if input.StartTime != nil {
+		appointment.StartTime = *input.StartTime
+	}
+
+	if input.ServiceIDs != nil {
+		if len(*input.ServiceIDs) == 0 {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, "At least one service is required")
+			return
+		}
+
+		appointmentServices, _, err := buildAppointmentServices(tx, salonUUID, *input.ServiceIDs)
+		if err != nil {
+			tx.Rollback()
+			respondAppointmentError(c, err)
+			return
+		}
+
+		if err := tx.Where("appointment_id = ?", appointment.ID).Delete(&models.AppointmentService{}).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing services")
+			return
+		}
+		for i := range appointmentServices {
+			appointmentServices[i].AppointmentID = appointment.ID
+		}
+		appointment.Services = appointmentServices
+	}
+
+	if input.Notes != nil {
+		appointment.Notes = *input.Notes
+	}
+
+	// End time always follows the (possibly new) start time and services
+	duration := 0
+	for _, s := range appointment.Services {
+		duration += s.Duration
+	}
+	appointment.EndTime = appointment.StartTime.Add(time.Duration(duration) * time.Minute)
+
+	if input.StaffUserID != nil || input.StartTime != nil || input.ServiceIDs != nil {
+		if err := checkAppointmentSlot(tx, salonUUID, appointment.StaffUserID, appointment.StartTime, appointment.EndTime, appointment.ID); err != nil {
+			tx.Rollback()
+			respondAppointmentError(c, err)
+			return
+		}
+	}
+
+	if err := tx.Save(&appointment).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update appointment")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// UpdateAppointmentStatus confirms, checks in, cancels or marks an appointment as a no-show
+func UpdateAppointmentStatus(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	appointmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid appointment ID format")
+		return
+	}
+
+	var input UpdateAppointmentStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var appointment models.Appointment
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, appointmentUUID).
+		First(&appointment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Appointment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	next := AppointmentStatus(input.Status)
+	if !canTransitionAppointment(AppointmentStatus(appointment.Status), next) {
+		utils.RespondWithError(c, http.StatusConflict, "Cannot change appointment from "+appointment.Status+" to "+input.Status)
+		return
+	}
+
+	appointment.Status = input.Status
+	updates := map[string]interface{}{"status": input.Status}
+	if next == AppointmentCancelled {
+		appointment.CancellationReason = input.Reason
+		updates["cancellation_reason"] = input.Reason
+	}
+
+	if err := config.DB.Model(&appointment).Updates(updates).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update appointment status")
+		return
+	}
+
+	c.JSON(http.StatusOK, appointment)
+}
+
+// CompleteAppointment marks an appointment as completed and optionally bills its services
+func CompleteAppointment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	appointmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid appointment ID format")
+		return
+	}
+
+	// The body is optional; completing without one just closes the appointment
+	var input CompleteAppointmentInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var appointment models.Appointment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonUUID, appointmentUUID).
+		First(&appointment).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Appointment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if err := tx.Where("appointment_id = ?", appointment.ID).Order("position").
+		Find(&appointment.Services).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	switch AppointmentStatus(appointment.Status) {
+	case AppointmentBooked, AppointmentConfirmed, AppointmentCheckedIn:
+	default:
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Cannot complete an appointment that is "+appointment.Status)
+		return
+	}
+
+	var invoice *models.Invoice
+	if input.CreateInvoice {
+		items := make([]InvoiceItemInput, 0, len(appointment.Services))
+		for _, s := range appointment.Services {
+			items = append(items, InvoiceItemInput{
+				ServiceID:         &s.ServiceID,
+				Quantity:          1,
+				PerformedByUserID: &appointment.StaffUserID,
+			})
+		}
+
+		// Billed exactly as CreateInvoice bills
+		var ok bool
+		invoice, ok = createInvoiceFromInput(c, tx, salonUUID, uuid.Must(uuid.Parse(userID.(string))), CreateInvoiceInput{
+			CustomerID:    appointment.CustomerID,
+			Items:         items,
+			Discount:      input.Discount,
+			RedeemPoints:  input.RedeemPoints,
+			PromotionCode: input.PromotionCode,
+			Tax:           input.Tax,
+			PlaceOfSupply: input.PlaceOfSupply,
+			Payments:      input.Payments,
+			Status:        string(InvoiceFinalized),
+			SkipPackages:  input.SkipPackages,
+			Notes:         input.Notes,
+		})
+		if !ok {
+			tx.Rollback()
+			return
+		}
+		appointment.InvoiceID = &invoice.ID
+	}
+
+	appointment.Status = string(AppointmentCompleted)
+	if err := tx.Model(&appointment).Updates(map[string]interface{}{
+		"status":     appointment.Status,
+		"invoice_id": appointment.InvoiceID,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to complete appointment")
+		return
+	}
+
+	tx.Commit()
+
+	if invoice != nil && input.SendReceipt {
+		queueInvoiceReceipt(salonUUID, invoice.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"appointment": appointment,
+		"invoice":     invoice,
+	})
+}
+
+// DeleteAppointment removes an appointment that has not been billed
+func DeleteAppointment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	appointmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid appointment ID format")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var appointment models.Appointment
+	if err := tx.Where("salon_id = ? AND id = ?", salonUUID, appointmentUUID).
+		First(&appointment).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Appointment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if appointment.InvoiceID != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Appointment has been invoiced and cannot be deleted")
+		return
+	}
+
+	if err := tx.Where("appointment_id = ?", appointment.ID).Delete(&models.AppointmentService{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete appointment services")
+		return
+	}
+
+	if err := tx.Delete(&appointment).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete appointment")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Appointment deleted successfully"})
+}
+
+// buildAppointmentServices validates the requested services and returns them in booking order
+// together with their combined duration in minutes
+func buildAppointmentServices(db *gorm.DB, salonID uuid.UUID, serviceIDs []uuid.UUID) ([]models.AppointmentService, int, error) {
+	var appointmentServices []models.AppointmentService
+	duration := 0
+
+	for i, serviceID := range serviceIDs {
+		var service models.Service
+		if err := db.Where("salon_id = ? AND id = ? AND is_active = ?", salonID, serviceID, true).
+			First(&service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, 0, serviceNotFoundError{ServiceID: serviceID}
+			}
+			return nil, 0, err
+		}
+
+		if service.Duration <= 0 {
+			return nil, 0, appointmentConflictError{message: "Service " + service.Name + " has no duration configured"}
+		}
+		duration += service.Duration
+
+		appointmentServices = append(appointmentServices, models.AppointmentService{
+			ID:          uuid.New(),
+			ServiceID:   service.ID,
+			ServiceName: service.Name,
+			Duration:    service.Duration,
+			Price:       service.Price,
+			Position:    i,
+		})
+	}
+
+	return appointmentServices, duration, nil
+}
+
+// checkAppointmentSlot verifies that the staff member is an active user of the salon, that the
+// booking falls inside their working hours and that it does not overlap another of the staff
+// member's bookings, keeping the salon's buffer time free around each booking. The staff row is
+// locked so concurrent bookings for the same person are serialized.
+func checkAppointmentSlot(tx *gorm.DB, salonID, staffID uuid.UUID, start, end time.Time, excludeID uuid.UUID) error {
+	// Working hours are wall-clock times in the salon's zone, which GetAvailability takes to be
+	// the server's local zone, so a booking sent with a UTC offset must land on the same day.
+	start, end = start.In(time.Local), end.In(time.Local)
+
+	var staff models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ? AND is_active = ?", salonID, staffID, true).
+		First(&staff).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return appointmentConflictError{message: "Staff member not found"}
+		}
+		return err
+	}
+
+	var salon models.Salon
+	if err := tx.First(&salon, "id = ?", salonID).Error; err != nil {
+		return err
+	}
+
+	if window, ok := staffWorkingWindow(salon, staff, start); ok {
+		if window.Closed {
+			return appointmentConflictError{message: staff.Name + " is not working on " + start.Weekday().String()}
+		}
+		if start.Before(window.Open) || end.After(window.Close) {
+			return appointmentConflictError{message: "Appointment is outside working hours (" +
+				window.Open.Format("15:04") + "-" + window.Close.Format("15:04") + ")"}
+		}
+	}
+
+	buffer := time.Duration(salon.BookingBufferMinutes) * time.Minute
+
+	var clash models.Appointment
+	err := tx.Where("salon_id = ? AND staff_user_id = ? AND id <> ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
+		salonID, staffID, excludeID, inactiveAppointmentStatuses, end.Add(buffer), start.Add(-buffer)).
+		First(&clash).Error
+	if err == nil {
+		return appointmentConflictError{message: staff.Name + " is already booked from " +
+			clash.StartTime.In(start.Location()).Format("15:04") + " to " + clash.EndTime.In(start.Location()).Format("15:04")}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// respondAppointmentError maps booking validation errors to HTTP responses
+func respondAppointmentError(c *gin.Context, err error) {
+	var conflict appointmentConflictError
+	if errors.As(err, &conflict) {
+		utils.RespondWithError(c, http.StatusConflict, conflict.Error())
+		return
+	}
+	respondInvoiceItemsError(c, err)
+}
+
+func canTransitionAppointment(from, to AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// workingWindow is a single day's opening hours
+type workingWindow struct {
+	Open   time.Time
+	Close  time.Time
+	Closed bool
+}
+
+// workingWindowFor reads the entry for day's weekday from a working hours document such as
+// {"monday": {"open": "09:00", "close": "20:00", "closed": false}}. The second return value is
+// false when the document has no usable entry for that day.
+func workingWindowFor(hours models.JSONB, day time.Time) (workingWindow, bool) {
+	entry, ok := hours[strings.ToLower(day.Weekday().String())].(map[string]interface{})
+	if !ok {
+		return workingWindow{}, false
+	}
+
+	if closed, _ := entry["closed"].(bool); closed {
+		return workingWindow{Closed: true}, true
+	}
+
+	openStr, _ := entry["open"].(string)
+	closeStr, _ := entry["close"].(string)
+	openAt, err := time.Parse("15:04", openStr)
+	if err != nil {
+		return workingWindow{}, false
+	}
+	closeAt, err := time.Parse("15:04", closeStr)
+	if err != nil {
+		return workingWindow{}, false
+	}
+
+	base := utils.BeginningOfDay(day)
+	return workingWindow{
+		Open:  base.Add(time.Duration(openAt.Hour())*time.Hour + time.Duration(openAt.Minute())*time.Minute),
+		Close: base.Add(time.Duration(closeAt.Hour())*time.Hour + time.Duration(closeAt.Minute())*time.Minute),
+	}, true
+}
+
+// staffWorkingWindow returns the hours a staff member takes bookings on day: their own shift
+// when one is configured, always limited to the salon's opening hours
+func staffWorkingWindow(salon models.Salon, staff models.User, day time.Time) (workingWindow, bool) {
+	salonWindow, salonOK := workingWindowFor(salon.WorkingHours, day)
+	staffWindow, staffOK := workingWindowFor(staff.WorkingHours, day)
+	if !staffOK {
+		return salonWindow, salonOK
+	}
+	if !salonOK {
+		return staffWindow, true
+	}
+	if salonWindow.Closed || staffWindow.Closed {
+		return workingWindow{Closed: true}, true
+	}
+
+	window := salonWindow
+	if staffWindow.Open.After(window.Open) {
+		window.Open = staffWindow.Open
+	}
+	if staffWindow.Close.Before(window.Close) {
+		window.Close = staffWindow.Close
+	}
+	if !window.Open.Before(window.Close) {
+		window.Closed = true
+	}
+	return window, true
+}
@@ -44,6 +44,8 @@ type AddEmployeeInput struct {
 	Name     string `json:"name" binding:"required"`
 	Password string `json:"password" binding:"required,min=8"`
 	Role     string `json:"role" binding:"required,oneof=manager employee"`
+
+	WorkingHours models.JSONB `json:"workingHours"` // Optional shifts; defaults to the salon's hours
 }
 
 // Register - Creates salon owner account
@@ -321,6 +323,8 @@ func AddEmployee(c *gin.Context) {
 		Password: input.Password, // Will be hashed in BeforeCreate hook
 		Role:     input.Role,
 		SalonID:  salonUUID,
+
+		WorkingHours: input.WorkingHours,
 	}
 
 	// Create employee
@@ -368,6 +372,8 @@ func GetEmployees(c *gin.Context) {
 			"isActive":  emp.IsActive,
 			"lastLogin": emp.LastLogin,
 			"createdAt": emp.CreatedAt,
+
+			"workingHours": emp.WorkingHours,
 		})
 	}
 
@@ -418,6 +424,8 @@ func UpdateEmployee(c *gin.Context) {
 		Phone    string `json:"phone"`
 		Role     string `json:"role"`
 		IsActive *bool  `json:"isActive"`
+
+		WorkingHours models.JSONB `json:"workingHours"`
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -439,6 +447,9 @@ func UpdateEmployee(c *gin.Context) {
 	if updateData.IsActive != nil {
 		updates["is_active"] = *updateData.IsActive
 	}
+	if updateData.WorkingHours != nil {
+		updates["working_hours"] = updateData.WorkingHours
+	}
 
 	if err := config.DB.Model(&employee).Updates(updates).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update employee")
@@ -455,6 +466,8 @@ func UpdateEmployee(c *gin.Context) {
 			"name":     employee.Name,
 			"role":     employee.Role,
 			"isActive": employee.IsActive,
+
+			"workingHours": employee.WorkingHours,
 		},
 	})
 }
@@ -550,6 +563,32 @@ func Me(c *gin.Context) {
 	})
 }
 
+// requireRole loads the requesting user and checks they hold one of the given roles.
+// On failure it writes the error response and returns false.
+func requireRole(c *gin.Context, message string, roles ...Role) (models.User, bool) {
+	var currentUser models.User
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not authenticated")
+		return currentUser, false
+	}
+
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return currentUser, false
+	}
+
+	for _, role := range roles {
+		if currentUser.Role == string(role) {
+			return currentUser, true
+		}
+	}
+
+	utils.RespondWithError(c, http.StatusForbidden, message)
+	return currentUser, false
+}
+
 // Helper function to create default reminder templates
 func createDefaultReminderTemplates(tx *gorm.DB, salonID uuid.UUID) error {
 	defaultTemplates := []models.ReminderTemplate{
@@ -0,0 +1,185 @@
+// controllers/availability.go
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AvailabilitySlot is a bookable start/end pair
+type AvailabilitySlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// StaffAvailability groups the open slots of one staff member
+type StaffAvailability struct {
+	StaffID   uuid.UUID          `json:"staffId"`
+	StaffName string             `json:"staffName"`
+	Role      string             `json:"role"`
+	Slots     []AvailabilitySlot `json:"slots"`
+}
+
+// AvailabilityResponse is returned by GetAvailability
+type AvailabilityResponse struct {
+	Date          string              `json:"date"`
+	Duration      int                 `json:"duration"` // combined duration of the requested services in minutes
+	BufferMinutes int                 `json:"bufferMinutes"`
+	Staff         []StaffAvailability `json:"staff"`
+}
+
+// GetAvailability returns the open slots for the requested services on a day, grouped by staff member.
+// The salon's booking buffer applies, the same as when the appointment is booked.
+// GET /api/availability?serviceIds=<id>,<id>&date=YYYY-MM-DD[&staffId=<id>]
+func GetAvailability(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// serviceIds may be given comma separated, repeated, or both
+	var serviceIDs []uuid.UUID
+	for _, raw := range c.QueryArray("serviceIds") {
+		for _, part := range strings.Split(raw, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			serviceUUID, err := uuid.Parse(part)
+			if err != nil {
+				utils.RespondWithError(c, http.StatusBadRequest, "Invalid service ID format: "+part)
+				return
+			}
+			serviceIDs = append(serviceIDs, serviceUUID)
+		}
+	}
+	if len(serviceIDs) == 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "serviceIds is required")
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", c.Query("date"), time.Local)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid date format, expected YYYY-MM-DD")
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	bufferMinutes := salon.BookingBufferMinutes
+
+	_, duration, err := buildAppointmentServices(config.DB, salonUUID, serviceIDs)
+	if err != nil {
+		respondAppointmentError(c, err)
+		return
+	}
+
+	staffQuery := config.DB.Where("salon_id = ? AND is_active = ?", salonUUID, true)
+	if staffID := c.Query("staffId"); staffID != "" {
+		staffUUID, err := uuid.Parse(staffID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid staff ID format")
+			return
+		}
+		staffQuery = staffQuery.Where("id = ?", staffUUID)
+	}
+
+	var staff []models.User
+	if err := staffQuery.Order("name").Find(&staff).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch staff")
+		return
+	}
+
+	// Existing bookings for the day, per staff member
+	var bookings []models.Appointment
+	if err := config.DB.Where("salon_id = ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
+		salonUUID, inactiveAppointmentStatuses, day.AddDate(0, 0, 1), day).
+		Find(&bookings).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch appointments")
+		return
+	}
+	bookingsByStaff := make(map[uuid.UUID][]models.Appointment)
+	for _, b := range bookings {
+		bookingsByStaff[b.StaffUserID] = append(bookingsByStaff[b.StaffUserID], b)
+	}
+
+	response := AvailabilityResponse{
+		Date:          day.Format("2006-01-02"),
+		Duration:      duration,
+		BufferMinutes: bufferMinutes,
+		Staff:         []StaffAvailability{},
+	}
+
+	for _, member := range staff {
+		slots := openSlots(salon, member, day, bookingsByStaff[member.ID],
+			time.Duration(duration)*time.Minute, time.Duration(bufferMinutes)*time.Minute, time.Now())
+		response.Staff = append(response.Staff, StaffAvailability{
+			StaffID:   member.ID,
+			StaffName: member.Name,
+			Role:      member.Role,
+			Slots:     slots,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// openSlots walks the staff member's working window in steps of the salon's slot interval and
+// keeps every start time where the whole duration fits without touching an existing booking
+// (including the buffer around it). Start times already in the past are skipped.
+func openSlots(salon models.Salon, staff models.User, day time.Time, bookings []models.Appointment, duration, buffer time.Duration, now time.Time) []AvailabilitySlot {
+	slots := []AvailabilitySlot{}
+
+	window, ok := staffWorkingWindow(salon, staff, day)
+	if !ok {
+		// No hours configured at all: treat the whole day as bookable
+		window = workingWindow{Open: day, Close: day.AddDate(0, 0, 1)}
+	}
+	if window.Closed {
+		return slots
+	}
+
+	step := time.Duration(salon.SlotIntervalMinutes) * time.Minute
+	if step <= 0 {
+		step = 15 * time.Minute
+	}
+
+	for start := window.Open; !start.Add(duration).After(window.Close); start = start.Add(step) {
+		if start.Before(now) {
+			continue
+		}
+		end := start.Add(duration)
+
+		free := true
+		for _, b := range bookings {
+			if b.StartTime.Before(end.Add(buffer)) && b.EndTime.After(start.Add(-buffer)) {
+				free = false
+				break
+			}
+		}
+		if free {
+			slots = append(slots, AvailabilitySlot{Start: start, End: end})
+		}
+	}
+
+	return slots
+}
@@ -0,0 +1,819 @@
+// controllers/commission.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CommissionTierInput defines a monthly revenue threshold and the rate that applies above it
+type CommissionTierInput struct {
+	MinMonthlyRevenue models.Money `json:"minMonthlyRevenue" binding:"min=0"`
+	Rate              float64      `json:"rate" binding:"min=0,max=100"`
+}
+
+// CommissionRuleInput defines the expected JSON structure for creating or replacing a commission rule
+type CommissionRuleInput struct {
+	Name     string                `json:"name" binding:"required"`
+	UserID   *uuid.UUID            `json:"userId"`
+	Role     string                `json:"role" binding:"omitempty,oneof=owner manager employee"`
+	ItemType string                `json:"itemType" binding:"omitempty,oneof=service product package"`
+	Category string                `json:"category"`
+	Rate     float64               `json:"rate" binding:"min=0,max=100"`
+	IsActive *bool                 `json:"isActive"`
+	Tiers    []CommissionTierInput `json:"tiers" binding:"dive"`
+}
+
+// LockCommissionInput defines the period to close and pay out
+type LockCommissionInput struct {
+	From             string     `json:"from" binding:"required"` // YYYY-MM-DD
+	To               string     `json:"to" binding:"required"`   // YYYY-MM-DD, inclusive
+	UserID           *uuid.UUID `json:"userId"`                  // Optional: lock a single employee
+	PaymentReference string     `json:"paymentReference"`
+}
+
+// CommissionLine is one invoice line's contribution to an employee's commission
+type CommissionLine struct {
+	InvoiceID     uuid.UUID    `json:"invoiceId"`
+	InvoiceItemID uuid.UUID    `json:"invoiceItemId"`
+	InvoiceNumber string       `json:"invoiceNumber"`
+	InvoiceDate   time.Time    `json:"invoiceDate"`
+	ItemName      string       `json:"itemName"`
+	ItemType      string       `json:"itemType"`
+	Category      string       `json:"category"`
+	Quantity      int          `json:"quantity"`
+	Revenue       models.Money `json:"revenue"`
+	Rate          float64      `json:"rate"`
+	Commission    models.Money `json:"commission"`
+	RuleID        *uuid.UUID   `json:"ruleId"`
+	Locked        bool         `json:"locked"` // true when the line comes from a paid statement
+}
+
+// EmployeeCommission is an employee's commission statement for a period
+type EmployeeCommission struct {
+	UserID       uuid.UUID        `json:"userId"`
+	EmployeeName string           `json:"employeeName"`
+	Role         string           `json:"role"`
+	Revenue      models.Money     `json:"revenue"`
+	Commission   models.Money     `json:"commission"`
+	Lines        []CommissionLine `json:"lines"`
+}
+
+// commissionSourceRow is an invoice line as read for commission purposes
+type commissionSourceRow struct {
+	InvoiceID     uuid.UUID
+	InvoiceItemID uuid.UUID
+	InvoiceNumber string
+	InvoiceDate   time.Time
+	UserID        uuid.UUID
+	ItemName      string
+	ItemType      string
+	Category      string
+	Quantity      int
+	Revenue       models.Money
+}
+
+// GetCommissions returns per-employee commission statements for a period.
+// GET /api/commissions?from=YYYY-MM-DD&to=YYYY-MM-DD[&userId=<id>]
+// Employees only ever see their own statement.
+func GetCommissions(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Not allowed to view commissions", RoleOwner, RoleManager, RoleEmployee)
+	if !ok {
+		return
+	}
+
+	var userFilter *uuid.UUID
+	if currentUser.Role == string(RoleEmployee) {
+		userFilter = &currentUser.ID
+	} else if raw := c.Query("userId"); raw != "" {
+		userUUID, err := uuid.Parse(raw)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID format")
+			return
+		}
+		userFilter = &userUUID
+	}
+
+	statements, err := calculateCommissions(config.DB, salonUUID, from, to, userFilter)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate commissions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from.Format("2006-01-02"),
+		"to":         to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"statements": statements,
+	})
+}
+
+// GetCommissionRules lists the salon's commission rules
+func GetCommissionRules(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage commissions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var rules []models.CommissionRule
+	if err := config.DB.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_monthly_revenue")
+	}).Where("salon_id = ?", salonUUID).Order("created_at").Find(&rules).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve commission rules")
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateCommissionRule adds a commission rule
+func CreateCommissionRule(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage commissions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input CommissionRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	rule := models.CommissionRule{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+	}
+	if !applyCommissionRuleInput(c, salonUUID, &rule, input) {
+		return
+	}
+
+	if err := config.DB.Create(&rule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create commission rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateCommissionRule replaces a commission rule and its tiers
+func UpdateCommissionRule(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	ruleUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid rule ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage commissions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input CommissionRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var rule models.CommissionRule
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, ruleUUID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Commission rule not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applyCommissionRuleInput(c, salonUUID, &rule, input) {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.CommissionTier{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing tiers")
+		return
+	}
+
+	if err := tx.Save(&rule).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update commission rule")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteCommissionRule removes a commission rule. Paid statements keep their snapshot rates.
+func DeleteCommissionRule(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	ruleUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid rule ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage commissions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, ruleUUID).Delete(&models.CommissionRule{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete commission rule")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Commission rule not found")
+		return
+	}
+
+	if err := tx.Where("rule_id = ?", ruleUUID).Delete(&models.CommissionTier{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete commission tiers")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Commission rule deleted successfully"})
+}
+
+// LockCommissionStatements snapshots the commission of every employee (or one employee) for a
+// period and marks it paid. Locked periods are served from the snapshot from then on, and invoices
+// dated inside them can no longer be edited.
+func LockCommissionStatements(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can lock commission statements", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input LockCommissionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(input.From, input.To)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	periodEnd := to.AddDate(0, 0, -1)
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// A period can only be paid once per employee
+	overlap := tx.Model(&models.CommissionStatement{}).
+		Where("salon_id = ? AND period_start <= ? AND period_end >= ?", salonUUID, periodEnd, from)
+	if input.UserID != nil {
+		overlap = overlap.Where("user_id = ?", *input.UserID)
+	}
+	var overlapping int64
+	if err := overlap.Count(&overlapping).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if overlapping > 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Part of this period already has a locked commission statement")
+		return
+	}
+
+	calculated, err := calculateCommissions(tx, salonUUID, from, to, input.UserID)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate commissions")
+		return
+	}
+
+	var statements []models.CommissionStatement
+	for _, emp := range calculated {
+		if len(emp.Lines) == 0 {
+			continue
+		}
+
+		statement := models.CommissionStatement{
+			ID:               uuid.New(),
+			SalonID:          salonUUID,
+			UserID:           emp.UserID,
+			PeriodStart:      from,
+			PeriodEnd:        periodEnd,
+			Revenue:          emp.Revenue,
+			Commission:       emp.Commission,
+			PaymentReference: input.PaymentReference,
+			LockedByUserID:   currentUser.ID,
+		}
+		for _, line := range emp.Lines {
+			statement.Lines = append(statement.Lines, models.CommissionStatementLine{
+				ID:            uuid.New(),
+				InvoiceID:     line.InvoiceID,
+				InvoiceItemID: line.InvoiceItemID,
+				RuleID:        line.RuleID,
+				InvoiceNumber: line.InvoiceNumber,
+				InvoiceDate:   line.InvoiceDate,
+				ItemName:      line.ItemName,
+				ItemType:      line.ItemType,
+				Category:      line.Category,
+				Quantity:      line.Quantity,
+				Revenue:       line.Revenue,
+				Rate:          line.Rate,
+				Commission:    line.Commission,
+			})
+		}
+
+		if err := tx.Create(&statement).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save commission statement")
+			return
+		}
+		statements = append(statements, statement)
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, statements)
+}
+
+// GetCommissionStatements lists the salon's locked commission statements
+func GetCommissionStatements(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Not allowed to view commissions", RoleOwner, RoleManager, RoleEmployee)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if currentUser.Role == string(RoleEmployee) {
+		query = query.Where("user_id = ?", currentUser.ID)
+	}
+
+	var statements []models.CommissionStatement
+	if err := query.Order("period_start DESC").Find(&statements).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve commission statements")
+		return
+	}
+
+	c.JSON(http.StatusOK, statements)
+}
+
+// GetCommissionStatement retrieves a locked statement with its lines
+func GetCommissionStatement(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	statementUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid statement ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Not allowed to view commissions", RoleOwner, RoleManager, RoleEmployee)
+	if !ok {
+		return
+	}
+
+	var statement models.CommissionStatement
+	if err := config.DB.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("invoice_date")
+	}).Where("salon_id = ? AND id = ?", salonUUID, statementUUID).
+		First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Commission statement not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if currentUser.Role == string(RoleEmployee) && statement.UserID != currentUser.ID {
+		utils.RespondWithError(c, http.StatusNotFound, "Commission statement not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// applyCommissionRuleInput validates input and copies it onto rule. On failure it writes the error response.
+func applyCommissionRuleInput(c *gin.Context, salonID uuid.UUID, rule *models.CommissionRule, input CommissionRuleInput) bool {
+	if input.UserID != nil {
+		var user models.User
+		if err := config.DB.Where("salon_id = ? AND id = ?", salonID, *input.UserID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Employee not found")
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return false
+		}
+	}
+
+	itemType := input.ItemType
+	if itemType == "" {
+		itemType = "service"
+	}
+
+	rule.Name = input.Name
+	rule.UserID = input.UserID
+	rule.Role = input.Role
+	rule.ItemType = itemType
+	rule.Category = strings.TrimSpace(input.Category)
+	rule.Rate = input.Rate
+	rule.IsActive = input.IsActive == nil || *input.IsActive
+
+	rule.Tiers = nil
+	for _, t := range input.Tiers {
+		rule.Tiers = append(rule.Tiers, models.CommissionTier{
+			ID:                uuid.New(),
+			RuleID:            rule.ID,
+			MinMonthlyRevenue: t.MinMonthlyRevenue,
+			Rate:              t.Rate,
+		})
+	}
+	return true
+}
+
+// calculateCommissions builds commission statements for [from, to). Lines inside a period that
+// has already been locked are taken from the paid snapshot instead of the live invoices.
+// Tier thresholds are evaluated against the employee's revenue for the whole calendar month,
+// so a partial-month query uses the same rate a full-month one would.
+func calculateCommissions(db *gorm.DB, salonID uuid.UUID, from, to time.Time, userID *uuid.UUID) ([]EmployeeCommission, error) {
+	var users []models.User
+	userQuery := db.Where("salon_id = ?", salonID)
+	if userID != nil {
+		userQuery = userQuery.Where("id = ?", *userID)
+	}
+	if err := userQuery.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usersByID := make(map[uuid.UUID]models.User, len(users))
+	for _, u := range users {
+		usersByID[u.ID] = u
+	}
+
+	var rules []models.CommissionRule
+	if err := db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("min_monthly_revenue")
+	}).Where("salon_id = ? AND is_active = ?", salonID, true).
+		Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	// Locked statements overlapping the period
+	var locked []models.CommissionStatement
+	lockedQuery := db.Preload("Lines").
+		Where("salon_id = ? AND period_start < ? AND period_end >= ?", salonID, to, from)
+	if userID != nil {
+		lockedQuery = lockedQuery.Where("user_id = ?", *userID)
+	}
+	if err := lockedQuery.Find(&locked).Error; err != nil {
+		return nil, err
+	}
+
+	// Read live lines for the full months the period touches so tiers see the whole month
+	monthStart := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	last := to.Add(-time.Nanosecond)
+	monthEnd := time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, last.Location()).AddDate(0, 1, 0)
+
+	rows, err := commissionSourceRows(db, salonID, monthStart, monthEnd, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	monthlyRevenue := make(map[string]models.Money)
+	monthKey := func(userID uuid.UUID, itemType string, t time.Time) string {
+		return userID.String() + "|" + itemType + "|" + t.Format("2006-01")
+	}
+	for _, row := range rows {
+		monthlyRevenue[monthKey(row.UserID, row.ItemType, row.InvoiceDate)] += row.Revenue
+	}
+
+	statements := make(map[uuid.UUID]*EmployeeCommission)
+	statementFor := func(id uuid.UUID) *EmployeeCommission {
+		if s, ok := statements[id]; ok {
+			return s
+		}
+		user := usersByID[id]
+		s := &EmployeeCommission{UserID: id, EmployeeName: user.Name, Role: user.Role, Lines: []CommissionLine{}}
+		statements[id] = s
+		return s
+	}
+
+	isLocked := func(userID uuid.UUID, t time.Time) bool {
+		for _, st := range locked {
+			if st.UserID == userID && !t.Before(st.PeriodStart) && t.Before(st.PeriodEnd.AddDate(0, 0, 1)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, row := range rows {
+		if row.InvoiceDate.Before(from) || !row.InvoiceDate.Before(to) {
+			continue
+		}
+		user, ok := usersByID[row.UserID]
+		if !ok || isLocked(row.UserID, row.InvoiceDate) {
+			continue
+		}
+
+		line := CommissionLine{
+			InvoiceID:     row.InvoiceID,
+			InvoiceItemID: row.InvoiceItemID,
+			InvoiceNumber: row.InvoiceNumber,
+			InvoiceDate:   row.InvoiceDate,
+			ItemName:      row.ItemName,
+			ItemType:      row.ItemType,
+			Category:      row.Category,
+			Quantity:      row.Quantity,
+			Revenue:       row.Revenue,
+		}
+		if rule := matchCommissionRule(rules, user, row.ItemType, row.Category); rule != nil {
+			line.RuleID = &rule.ID
+			line.Rate = commissionRate(*rule, monthlyRevenue[monthKey(row.UserID, row.ItemType, row.InvoiceDate)])
+			line.Commission = row.Revenue.Percent(line.Rate)
+		}
+
+		s := statementFor(row.UserID)
+		s.Lines = append(s.Lines, line)
+	}
+
+	for _, st := range locked {
+		for _, l := range st.Lines {
+			if l.InvoiceDate.Before(from) || !l.InvoiceDate.Before(to) {
+				continue
+			}
+			s := statementFor(st.UserID)
+			s.Lines = append(s.Lines, CommissionLine{
+				InvoiceID:     l.InvoiceID,
+				InvoiceItemID: l.InvoiceItemID,
+				InvoiceNumber: l.InvoiceNumber,
+				InvoiceDate:   l.InvoiceDate,
+				ItemName:      l.ItemName,
+				ItemType:      l.ItemType,
+				Category:      l.Category,
+				Quantity:      l.Quantity,
+				Revenue:       l.Revenue,
+				Rate:          l.Rate,
+				Commission:    l.Commission,
+				RuleID:        l.RuleID,
+				Locked:        true,
+			})
+		}
+	}
+
+	result := make([]EmployeeCommission, 0, len(statements))
+	for _, s := range statements {
+		sort.Slice(s.Lines, func(i, j int) bool {
+			return s.Lines[i].InvoiceDate.Before(s.Lines[j].InvoiceDate)
+		})
+		for _, l := range s.Lines {
+			s.Revenue += l.Revenue
+			s.Commission += l.Commission
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].EmployeeName < result[j].EmployeeName
+	})
+
+	return result, nil
+}
+
+// commissionSourceRows reads the invoice lines in [start, end) credited to each employee. Revenue is
+// what the salon collected for the line: its taxable value after discounts, less refunded units.
+func commissionSourceRows(db *gorm.DB, salonID uuid.UUID, start, end time.Time, userID *uuid.UUID) ([]commissionSourceRow, error) {
+	var rows []commissionSourceRow
+
+	query := `
+		SELECT i.id as invoice_id,
+			   ii.id as invoice_item_id,
+			   i.invoice_number,
+			   i.invoice_date,
+			   COALESCE(ii.performed_by_user_id, i.created_by_user_id) as user_id,
+			   ii.service_name as item_name,
+			   ii.item_type,
+			   COALESCE(s.category, p.category, '') as category,
+			   ii.quantity - ii.refunded_quantity as quantity,
+			   ROUND(ii.taxable_value * (ii.quantity - ii.refunded_quantity) / NULLIF(ii.quantity, 0), 2) as revenue
+		FROM invoice_items ii
+		INNER JOIN invoices i ON i.id = ii.invoice_id
+		LEFT JOIN services s ON s.id = ii.service_id
+		LEFT JOIN products p ON p.id = ii.product_id
+		WHERE i.salon_id = ?
+		  AND i.status = 'finalized'
+		  AND ii.item_type <> 'gift_card'
+		  AND i.invoice_date >= ? AND i.invoice_date < ?
+	`
+	args := []interface{}{salonID, start, end}
+	if userID != nil {
+		query += " AND COALESCE(ii.performed_by_user_id, i.created_by_user_id) = ?"
+		args = append(args, *userID)
+	}
+
+	err := db.Raw(query, args...).Scan(&rows).Error
+	return rows, err
+}
+
+// matchCommissionRule picks the most specific active rule for a line:
+// employee beats role, and a category match beats a catch-all rule
+func matchCommissionRule(rules []models.CommissionRule, user models.User, itemType, category string) *models.CommissionRule {
+	var best *models.CommissionRule
+	bestScore := -1
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ItemType != "" && rule.ItemType != itemType {
+			continue
+		}
+
+		score := 0
+		if rule.UserID != nil {
+			if *rule.UserID != user.ID {
+				continue
+			}
+			score += 4
+		}
+		if rule.Role != "" {
+			if rule.Role != user.Role {
+				continue
+			}
+			score += 2
+		}
+		if rule.Category != "" {
+			if !strings.EqualFold(rule.Category, category) {
+				continue
+			}
+			score++
+		}
+
+		if score > bestScore {
+			best, bestScore = rule, score
+		}
+	}
+
+	return best
+}
+
+// commissionRate returns the rule's rate for the given monthly revenue, applying the highest tier reached
+func commissionRate(rule models.CommissionRule, monthlyRevenue models.Money) float64 {
+	rate := rule.Rate
+	for _, tier := range rule.Tiers {
+		if monthlyRevenue >= tier.MinMonthlyRevenue {
+			rate = tier.Rate
+		}
+	}
+	return rate
+}
+
+// invoiceInLockedCommissionPeriod reports whether an invoice date falls inside a paid commission statement
+func invoiceInLockedCommissionPeriod(db *gorm.DB, salonID uuid.UUID, invoiceDate time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&models.CommissionStatement{}).
+		Where("salon_id = ? AND period_start <= ? AND period_end + interval '1 day' > ?", salonID, invoiceDate, invoiceDate).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// checkInvoiceCommissionLock responds 409 and returns false when the invoice date is inside a paid commission period
+func checkInvoiceCommissionLock(c *gin.Context, db *gorm.DB, salonID uuid.UUID, invoiceDate time.Time) bool {
+	locked, err := invoiceInLockedCommissionPeriod(db, salonID, invoiceDate)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if locked {
+		utils.RespondWithError(c, http.StatusConflict, "Invoice falls in a paid commission period and can no longer be changed")
+		return false
+	}
+	return true
+}
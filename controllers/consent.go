@@ -0,0 +1,223 @@
+// controllers/consent.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ConsentInput grants or withdraws consent to messages on one channel for one purpose
+type ConsentInput struct {
+	Channel string `json:"channel" binding:"required,oneof=sms whatsapp email"`
+	Purpose string `json:"purpose" binding:"required,oneof=transactional reminders marketing"`
+	Granted bool   `json:"granted"`
+}
+
+// UpdateConsentsInput records a customer's consent choices and how they were captured
+type UpdateConsentsInput struct {
+	Consents []ConsentInput `json:"consents" binding:"required,min=1,dive"`
+	Source   string         `json:"source" binding:"omitempty,oneof=in_person form phone import"` // Default in_person
+	Evidence string         `json:"evidence"`                                                     // e.g. the form reference
+}
+
+// ConsentView is a customer's consent for one channel and purpose. Explicit is false when the customer
+// has not been asked and Granted is the default.
+type ConsentView struct {
+	Channel    string     `json:"channel"`
+	Purpose    string     `json:"purpose"`
+	Granted    bool       `json:"granted"`
+	Explicit   bool       `json:"explicit"`
+	Source     string     `json:"source,omitempty"`
+	RecordedAt *time.Time `json:"recordedAt,omitempty"`
+}
+
+// ConsentHistoryEntry is one grant or withdrawal in a customer's consent history
+type ConsentHistoryEntry struct {
+	ID             uuid.UUID `json:"id"`
+	Channel        string    `json:"channel"`
+	Purpose        string    `json:"purpose"`
+	Granted        bool      `json:"granted"`
+	Source         string    `json:"source"`
+	Evidence       string    `json:"evidence"`
+	RecordedByName string    `json:"recordedByName"`
+	RecordedAt     time.Time `json:"recordedAt"`
+}
+
+// GetCustomerConsents shows a customer's consent for every channel and purpose, and the history of
+// every grant and withdrawal, newest first.
+// GET /api/customers/:id/consents
+func GetCustomerConsents(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Preload("Consents").
+		Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	history := []ConsentHistoryEntry{}
+	if err := config.DB.Table("consent_events ce").
+		Select("ce.*, u.name AS recorded_by_name").
+		Joins("LEFT JOIN users u ON u.id = ce.recorded_by_user_id").
+		Where("ce.customer_id = ?", customer.ID).
+		Order("ce.recorded_at DESC").
+		Scan(&history).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve consent history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerId": customer.ID,
+		"consents":   consentViews(customer),
+		"history":    history,
+	})
+}
+
+// UpdateCustomerConsents records consent choices for a customer. Choices that change nothing are not
+// added to the history.
+// PUT /api/customers/:id/consents
+func UpdateCustomerConsents(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var input UpdateConsentsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	changed, err := services.RecordConsent(tx, customer, consentChanges(input.Consents), consentRecord(c, input.Source, input.Evidence))
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record consent")
+		return
+	}
+	if err := tx.Where("customer_id = ?", customer.ID).Find(&customer.Consents).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record consent")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Consent recorded successfully",
+		"changed":  changed,
+		"consents": consentViews(customer),
+	})
+}
+
+// consentViews lists the customer's consent for every channel and purpose; Consents must be loaded
+func consentViews(customer models.Customer) []ConsentView {
+	recorded := make(map[[2]string]models.CustomerConsent, len(customer.Consents))
+	for _, consent := range customer.Consents {
+		recorded[[2]string{consent.Channel, consent.Purpose}] = consent
+	}
+
+	views := make([]ConsentView, 0, len(models.ConsentChannels)*len(models.ConsentPurposes))
+	for _, channel := range models.ConsentChannels {
+		for _, purpose := range models.ConsentPurposes {
+			view := ConsentView{
+				Channel: channel,
+				Purpose: purpose,
+				Granted: customer.Consented(channel, purpose),
+			}
+			if consent, ok := recorded[[2]string{channel, purpose}]; ok {
+				recordedAt := consent.RecordedAt
+				view.Explicit = true
+				view.Source = consent.Source
+				view.RecordedAt = &recordedAt
+			}
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+func consentChanges(inputs []ConsentInput) []services.ConsentChange {
+	changes := make([]services.ConsentChange, len(inputs))
+	for i, input := range inputs {
+		changes[i] = services.ConsentChange{Channel: input.Channel, Purpose: input.Purpose, Granted: input.Granted}
+	}
+	return changes
+}
+
+// consentRecord attributes consent captured through the API to the logged-in user
+func consentRecord(c *gin.Context, source, evidence string) services.ConsentRecord {
+	if source == "" {
+		source = models.ConsentSourceInPerson
+	}
+	record := services.ConsentRecord{Source: source, Evidence: evidence, RecordedAt: time.Now()}
+	if userID, exists := c.Get("userId"); exists {
+		if userUUID, err := uuid.Parse(userID.(string)); err == nil {
+			record.RecordedBy = &userUUID
+		}
+	}
+	return record
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"salonpro-backend/config"
 	"salonpro-backend/models"
+	"salonpro-backend/services"
 	"salonpro-backend/utils"
 	"time"
 
@@ -22,6 +23,10 @@ type CreateCustomerInput struct {
 	Birthday    *time.Time `json:"birthday"`
 	Anniversary *time.Time `json:"anniversary"`
 	Notes       string     `json:"notes"`
+
+	// Consent the customer gave when signing up, and how it was captured (default in_person)
+	Consents      []ConsentInput `json:"consents" binding:"omitempty,dive"`
+	ConsentSource string         `json:"consentSource" binding:"omitempty,oneof=in_person form phone import"`
 }
 
 // UpdateCustomerInput defines the expected JSON structure for updating a customer
@@ -33,6 +38,10 @@ type UpdateCustomerInput struct {
 	Anniversary *time.Time `json:"anniversary"`
 	Notes       *string    `json:"notes"`
 	IsActive    *bool      `json:"isActive"`
+
+	// Changes to the customer's consent, and how they were captured (default in_person)
+	Consents      []ConsentInput `json:"consents" binding:"omitempty,dive"`
+	ConsentSource string         `json:"consentSource" binding:"omitempty,oneof=in_person form phone import"`
 }
 
 // CreateCustomer creates a new customer for the salon
@@ -98,12 +107,38 @@ func CreateCustomer(c *gin.Context) {
 		customer.Email = *input.Email
 	}
 
-	if err := config.DB.Create(&customer).Error; err != nil {
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&customer).Error; err != nil {
+		tx.Rollback()
 		fmt.Println(err.Error())
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create customer "+err.Error())
 		return
 	}
 
+	if len(input.Consents) > 0 {
+		if _, err := services.RecordConsent(tx, customer, consentChanges(input.Consents), consentRecord(c, input.ConsentSource, "")); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record consent")
+			return
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Find(&customer.Consents).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create customer")
+		return
+	}
+
 	c.JSON(http.StatusCreated, customer)
 }
 
@@ -152,7 +187,7 @@ func GetCustomer(c *gin.Context) {
 	}
 
 	var customer models.Customer
-	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+	if err := config.DB.Preload("Consents").Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
 		First(&customer).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
@@ -245,7 +280,33 @@ func UpdateCustomer(c *gin.Context) {
 		customer.IsActive = *input.IsActive
 	}
 
-	if err := config.DB.Save(&customer).Error; err != nil {
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Save(&customer).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer")
+		return
+	}
+
+	if len(input.Consents) > 0 {
+		if _, err := services.RecordConsent(tx, customer, consentChanges(input.Consents), consentRecord(c, input.ConsentSource, "")); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record consent")
+			return
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Find(&customer.Consents).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer")
 		return
 	}
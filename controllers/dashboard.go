@@ -15,11 +15,12 @@ import (
 
 type DashboardOverview struct {
 	TotalCustomers    int                `json:"totalCustomers"`
-	MonthlyRevenue    float64            `json:"monthlyRevenue"`
+	MonthlyRevenue    models.Money       `json:"monthlyRevenue"`
 	TotalInvoices     int                `json:"totalInvoices"`
 	UpcomingBirthdays []UpcomingEvent    `json:"upcomingBirthdays"`
 	RecentCustomers   []RecentCustomer   `json:"recentCustomers"`
 	UpcomingReminders []UpcomingReminder `json:"upcomingReminders"`
+	LowStock          []LowStockProduct  `json:"lowStock"`
 }
 
 type UpcomingEvent struct {
@@ -58,14 +59,14 @@ func GetDashboardOverview(c *gin.Context) {
 	// This Month's Revenue
 	now := time.Now()
 	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	var monthlyRevenue float64
+	var monthlyRevenue models.Money
 	config.DB.Model(&models.Invoice{}).
-		Where("salon_id = ? AND invoice_date >= ?", salonUUID, firstOfMonth).
-		Select("COALESCE(SUM(total), 0)").Scan(&monthlyRevenue)
+		Where("salon_id = ? AND status = ? AND invoice_date >= ?", salonUUID, "finalized", firstOfMonth).
+		Select("COALESCE(SUM(total - refunded_amount), 0)").Scan(&monthlyRevenue)
 
 	// Total Invoices
 	var totalInvoices int64
-	config.DB.Model(&models.Invoice{}).Where("salon_id = ?", salonUUID).Count(&totalInvoices)
+	config.DB.Model(&models.Invoice{}).Where("salon_id = ? AND status = ?", salonUUID, "finalized").Count(&totalInvoices)
 
 	// Upcoming Birthdays (till end of year, ignore year part)
 	var birthdayCount int64
@@ -97,7 +98,7 @@ func GetDashboardOverview(c *gin.Context) {
     SELECT c.name, i.invoice_date, i.id
     FROM invoices i
     JOIN customers c ON c.id = i.customer_id
-    WHERE i.salon_id = ?
+    WHERE i.salon_id = ? AND i.status = 'finalized'
     ORDER BY i.invoice_date DESC
 `, salonUUID).Rows()
 	if err == nil {
@@ -190,6 +191,9 @@ func GetDashboardOverview(c *gin.Context) {
 		}
 	}
 
+	// Products that need reordering
+	lowStock, _ := lowStockProducts(config.DB, salonUUID, 5)
+
 	// Compose response
 	response := gin.H{
 		"totalCustomers": totalCustomers,
@@ -201,6 +205,7 @@ func GetDashboardOverview(c *gin.Context) {
 		},
 		"recentCustomers":   recentCustomers,
 		"upcomingReminders": upcomingReminders,
+		"lowStock":          lowStock,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,752 @@
+// controllers/expense.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExpenseProductPurchases is stock bought for resale or back-bar use. The profit-and-loss report
+// counts that stock as product cost when it is sold or used, not when it is bought.
+const ExpenseProductPurchases = "product_purchases"
+
+// maxAttachmentBytes keeps scanned bills to a size that is reasonable to store with the expense
+const maxAttachmentBytes = 5 * 1024 * 1024
+
+// ExpenseInput defines the expected JSON structure for creating or replacing an expense
+type ExpenseInput struct {
+	Category        string       `json:"category" binding:"required,oneof=rent salaries utilities product_purchases marketing maintenance supplies professional_fees taxes other"`
+	Description     string       `json:"description" binding:"required"`
+	Amount          models.Money `json:"amount" binding:"gt=0"`
+	TaxAmount       models.Money `json:"taxAmount" binding:"min=0"` // GST included in Amount that can be claimed back
+	ExpenseDate     *time.Time   `json:"expenseDate"`               // Defaults to today
+	PaymentMethod   string       `json:"paymentMethod" binding:"omitempty,oneof=cash card upi bank_transfer cheque other"`
+	Vendor          string       `json:"vendor"`
+	Reference       string       `json:"reference"`
+	Notes           string       `json:"notes"`
+	SupplierID      *uuid.UUID   `json:"supplierId"`
+	PurchaseOrderID *uuid.UUID   `json:"purchaseOrderId"`
+}
+
+// RecurringExpenseInput defines the expected JSON structure for creating or replacing a recurring expense.
+// Frequency and start date cannot change once an occurrence has been posted.
+type RecurringExpenseInput struct {
+	Category      string       `json:"category" binding:"required,oneof=rent salaries utilities product_purchases marketing maintenance supplies professional_fees taxes other"`
+	Description   string       `json:"description" binding:"required"`
+	Amount        models.Money `json:"amount" binding:"gt=0"`
+	TaxAmount     models.Money `json:"taxAmount" binding:"min=0"`
+	PaymentMethod string       `json:"paymentMethod" binding:"omitempty,oneof=cash card upi bank_transfer cheque other"`
+	Vendor        string       `json:"vendor"`
+	SupplierID    *uuid.UUID   `json:"supplierId"`
+	Frequency     string       `json:"frequency" binding:"required,oneof=weekly monthly quarterly yearly"`
+	StartDate     time.Time    `json:"startDate" binding:"required"`
+	EndDate       *time.Time   `json:"endDate"`
+	IsActive      *bool        `json:"isActive"`
+}
+
+// GetExpenses lists expenses, newest first, with their total.
+// GET /api/expenses[?from=YYYY-MM-DD&to=YYYY-MM-DD][&category=rent]
+func GetExpenses(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	query := config.DB.Model(&models.Expense{}).Where("salon_id = ?", salonUUID)
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		query = query.Where("expense_date >= ? AND expense_date < ?", from, to)
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var total models.Money
+	if err := query.Session(&gorm.Session{}).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve expenses")
+		return
+	}
+
+	var expenses []models.Expense
+	if err := query.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("data")
+	}).Order("expense_date DESC, created_at DESC").Find(&expenses).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve expenses")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expenses": expenses,
+		"total":    total,
+	})
+}
+
+// GetExpense returns an expense with its attachments' details
+func GetExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	expenseUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var expense models.Expense
+	if err := config.DB.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Omit("data")
+	}).Where("salon_id = ? AND id = ?", salonUUID, expenseUUID).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Expense not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, expense)
+}
+
+// CreateExpense records an expense
+func CreateExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input ExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	expense := models.Expense{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: &currentUser.ID,
+	}
+	if !applyExpenseInput(c, &expense, input) {
+		return
+	}
+
+	if err := config.DB.Create(&expense).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create expense")
+		return
+	}
+
+	c.JSON(http.StatusCreated, expense)
+}
+
+// UpdateExpense replaces an expense's details. Attachments are kept.
+func UpdateExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	expenseUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input ExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var expense models.Expense
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, expenseUUID).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Expense not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applyExpenseInput(c, &expense, input) {
+		return
+	}
+
+	if err := config.DB.Save(&expense).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update expense")
+		return
+	}
+
+	c.JSON(http.StatusOK, expense)
+}
+
+// DeleteExpense removes an expense and its attachments
+func DeleteExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	expenseUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, expenseUUID).Delete(&models.Expense{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete expense")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Expense not found")
+		return
+	}
+
+	if err := tx.Where("expense_id = ?", expenseUUID).Delete(&models.ExpenseAttachment{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete expense attachments")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Expense deleted successfully"})
+}
+
+// UploadExpenseAttachment keeps a scanned bill or receipt with an expense. Expects a multipart "file"
+// (PDF or image, 5 MB at most).
+func UploadExpenseAttachment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	expenseUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var expense models.Expense
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonUUID, expenseUUID).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Expense not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if file.Size > maxAttachmentBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "Attachment must be 5 MB or smaller")
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Could not read attachment")
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxAttachmentBytes+1))
+	if err != nil || len(data) > maxAttachmentBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "Could not read attachment")
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if contentType != "application/pdf" && !strings.HasPrefix(contentType, "image/") {
+		utils.RespondWithError(c, http.StatusBadRequest, "Attachment must be a PDF or an image")
+		return
+	}
+
+	attachment := models.ExpenseAttachment{
+		ID:          uuid.New(),
+		ExpenseID:   expense.ID,
+		FileName:    filepath.Base(file.Filename),
+		ContentType: contentType,
+		Size:        len(data),
+		Data:        data,
+	}
+	if err := config.DB.Create(&attachment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save attachment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetExpenseAttachment returns an attachment's file
+func GetExpenseAttachment(c *gin.Context) {
+	attachment, ok := findExpenseAttachment(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.FileName))
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Data)
+}
+
+// DeleteExpenseAttachment removes an attachment from an expense
+func DeleteExpenseAttachment(c *gin.Context) {
+	attachment, ok := findExpenseAttachment(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&attachment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// GetRecurringExpenses lists the salon's recurring expenses
+func GetRecurringExpenses(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var schedules []models.RecurringExpense
+	if err := config.DB.Where("salon_id = ?", salonUUID).
+		Order("is_active DESC, next_due_date").Find(&schedules).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve recurring expenses")
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// CreateRecurringExpense sets up an expense posted on a schedule. Occurrences already due,
+// including any before today, are posted straight away.
+func CreateRecurringExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input RecurringExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	schedule := models.RecurringExpense{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: currentUser.ID,
+	}
+	if !applyRecurringExpenseInput(c, &schedule, input) {
+		return
+	}
+
+	if err := config.DB.Create(&schedule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create recurring expense")
+		return
+	}
+
+	if !postRecurringExpense(c, &schedule) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// UpdateRecurringExpense replaces a recurring expense. Expenses already posted are not changed.
+func UpdateRecurringExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	scheduleUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid recurring expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input RecurringExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var schedule models.RecurringExpense
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, scheduleUUID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Recurring expense not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applyRecurringExpenseInput(c, &schedule, input) {
+		return
+	}
+
+	if err := config.DB.Save(&schedule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update recurring expense")
+		return
+	}
+
+	if !postRecurringExpense(c, &schedule) {
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteRecurringExpense stops and removes a recurring expense. Expenses it already posted are kept.
+func DeleteRecurringExpense(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	scheduleUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid recurring expense ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, scheduleUUID).Delete(&models.RecurringExpense{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete recurring expense")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Recurring expense not found")
+		return
+	}
+
+	if err := tx.Model(&models.Expense{}).Where("recurring_expense_id = ?", scheduleUUID).
+		Update("recurring_expense_id", nil).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete recurring expense")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring expense deleted successfully"})
+}
+
+// applyExpenseInput validates input and copies it onto expense. On failure it writes the error response.
+func applyExpenseInput(c *gin.Context, expense *models.Expense, input ExpenseInput) bool {
+	if input.TaxAmount > input.Amount {
+		utils.RespondWithError(c, http.StatusBadRequest, "taxAmount cannot exceed amount")
+		return false
+	}
+	if input.SupplierID != nil && !checkExpenseSupplier(c, expense.SalonID, *input.SupplierID) {
+		return false
+	}
+	if input.PurchaseOrderID != nil {
+		var order models.PurchaseOrder
+		if err := config.DB.Select("id", "supplier_id").Where("salon_id = ? AND id = ?", expense.SalonID, *input.PurchaseOrderID).
+			First(&order).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Purchase order not found")
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return false
+		}
+		if input.SupplierID == nil {
+			input.SupplierID = &order.SupplierID
+		}
+	}
+
+	expenseDate := utils.BeginningOfDay(time.Now())
+	if input.ExpenseDate != nil {
+		expenseDate = utils.BeginningOfDay(input.ExpenseDate.In(time.Local))
+	}
+
+	expense.Category = input.Category
+	expense.Description = strings.TrimSpace(input.Description)
+	expense.Amount = input.Amount
+	expense.TaxAmount = input.TaxAmount
+	expense.ExpenseDate = expenseDate
+	expense.PaymentMethod = input.PaymentMethod
+	expense.Vendor = input.Vendor
+	expense.Reference = input.Reference
+	expense.Notes = input.Notes
+	expense.SupplierID = input.SupplierID
+	expense.PurchaseOrderID = input.PurchaseOrderID
+	return true
+}
+
+// applyRecurringExpenseInput validates input and copies it onto schedule. On failure it writes the error response.
+func applyRecurringExpenseInput(c *gin.Context, schedule *models.RecurringExpense, input RecurringExpenseInput) bool {
+	if input.TaxAmount > input.Amount {
+		utils.RespondWithError(c, http.StatusBadRequest, "taxAmount cannot exceed amount")
+		return false
+	}
+	if input.SupplierID != nil && !checkExpenseSupplier(c, schedule.SalonID, *input.SupplierID) {
+		return false
+	}
+
+	startDate := utils.BeginningOfDay(input.StartDate.In(time.Local))
+	var endDate *time.Time
+	if input.EndDate != nil {
+		end := utils.BeginningOfDay(input.EndDate.In(time.Local))
+		if end.Before(startDate) {
+			utils.RespondWithError(c, http.StatusBadRequest, "endDate must not be before startDate")
+			return false
+		}
+		endDate = &end
+	}
+
+	if schedule.PostedCount > 0 && (input.Frequency != schedule.Frequency || !startDate.Equal(schedule.StartDate)) {
+		utils.RespondWithError(c, http.StatusConflict, "Expenses have already been posted on this schedule; create a new recurring expense to change its frequency or start date")
+		return false
+	}
+
+	schedule.Category = input.Category
+	schedule.Description = strings.TrimSpace(input.Description)
+	schedule.Amount = input.Amount
+	schedule.TaxAmount = input.TaxAmount
+	schedule.PaymentMethod = input.PaymentMethod
+	schedule.Vendor = input.Vendor
+	schedule.SupplierID = input.SupplierID
+	schedule.Frequency = input.Frequency
+	schedule.StartDate = startDate
+	schedule.EndDate = endDate
+	schedule.NextDueDate = services.NextOccurrence(startDate, input.Frequency, schedule.PostedCount)
+	schedule.IsActive = input.IsActive == nil || *input.IsActive
+	return true
+}
+
+// checkExpenseSupplier verifies a supplier belongs to the salon. On failure it writes the error response.
+func checkExpenseSupplier(c *gin.Context, salonID, supplierID uuid.UUID) bool {
+	var supplier models.Supplier
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonID, supplierID).First(&supplier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Supplier not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return false
+	}
+	return true
+}
+
+// postRecurringExpense posts a schedule's occurrences that are already due and reloads it.
+// On failure it writes the error response.
+func postRecurringExpense(c *gin.Context, schedule *models.RecurringExpense) bool {
+	if _, err := services.PostRecurringExpense(config.DB, schedule.ID, time.Now()); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to post due expenses")
+		return false
+	}
+	if err := config.DB.First(schedule, "id = ?", schedule.ID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	return true
+}
+
+// findExpenseAttachment loads the attachment named in the route, checking its expense belongs to
+// the salon. On failure it writes the error response.
+func findExpenseAttachment(c *gin.Context) (models.ExpenseAttachment, bool) {
+	var attachment models.ExpenseAttachment
+
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return attachment, false
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return attachment, false
+	}
+
+	expenseUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid expense ID format")
+		return attachment, false
+	}
+
+	attachmentUUID, err := uuid.Parse(c.Param("attachmentId"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid attachment ID format")
+		return attachment, false
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage expenses", RoleOwner, RoleManager); !ok {
+		return attachment, false
+	}
+
+	expenses := config.DB.Model(&models.Expense{}).Select("id").Where("salon_id = ? AND id = ?", salonUUID, expenseUUID)
+	if err := config.DB.Where("expense_id IN (?) AND id = ?", expenses, attachmentUUID).
+		First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Attachment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return attachment, false
+	}
+
+	return attachment, true
+}
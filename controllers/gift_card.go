@@ -0,0 +1,411 @@
+// controllers/gift_card.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Gift card statuses
+const (
+	GiftCardActive    = "active"
+	GiftCardCancelled = "cancelled" // sale voided or refunded
+	GiftCardExpired   = "expired"
+)
+
+// Gift card ledger entry types
+const (
+	GiftCardIssue  = "issue"
+	GiftCardRedeem = "redeem"
+	GiftCardCancel = "cancel"
+	GiftCardExpire = "expire"
+)
+
+// GiftCardSettingsInput defines the expected JSON structure for gift card settings
+type GiftCardSettingsInput struct {
+	ValidityMonths int `json:"validityMonths" binding:"min=0,max=120"` // 0 never expires
+}
+
+// giftCardError is returned when a gift card cannot pay for an invoice
+type giftCardError struct {
+	message string
+}
+
+func (e giftCardError) Error() string {
+	return e.message
+}
+
+// errInvalidGiftCardValue is returned when an invoice line sells gift cards without a positive value
+var errInvalidGiftCardValue = errors.New("giftCardValue must be greater than zero")
+
+// errGiftCardUsed is returned when a refund would cancel gift cards that have already been spent from
+var errGiftCardUsed = errors.New("Gift cards sold on this invoice have been used and cannot be refunded")
+
+// GetGiftCardSettings returns how long gift cards sold by the salon stay valid
+func GetGiftCardSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.Select("id", "gift_card_validity_months").
+		First(&salon, "id = ?", salonID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve gift card settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"validityMonths": salon.GiftCardValidityMonths})
+}
+
+// UpdateGiftCardSettings changes the validity of gift cards sold from now on
+func UpdateGiftCardSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can change gift card settings", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input GiftCardSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if err := config.DB.Model(&models.Salon{}).Where("id = ?", salonID).
+		Update("gift_card_validity_months", input.ValidityMonths).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update gift card settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"validityMonths": input.ValidityMonths})
+}
+
+// GetGiftCards lists the salon's gift cards, newest first. ?status= filters by status.
+func GetGiftCards(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if err := expireGiftCards(config.DB, salonUUID, time.Now()); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update expired gift cards")
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var cards []models.GiftCard
+	if err := query.Order("issued_at DESC").Find(&cards).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve gift cards")
+		return
+	}
+
+	c.JSON(http.StatusOK, cards)
+}
+
+// GetGiftCard returns a gift card with its ledger
+func GetGiftCard(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	cardUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid gift card ID format")
+		return
+	}
+
+	respondGiftCard(c, salonUUID, config.DB.Where("salon_id = ? AND id = ?", salonUUID, cardUUID))
+}
+
+// LookupGiftCard checks the balance and expiry of a gift card by the code printed on it
+func LookupGiftCard(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	code := utils.NormalizeCode(c.Param("code"))
+	respondGiftCard(c, salonUUID, config.DB.Where("salon_id = ? AND code = ?", salonUUID, code))
+}
+
+// respondGiftCard loads the gift card matched by query, with its ledger, after expiring overdue cards
+func respondGiftCard(c *gin.Context, salonID uuid.UUID, query *gorm.DB) {
+	if err := expireGiftCards(config.DB, salonID, time.Now()); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update expired gift cards")
+		return
+	}
+
+	var card models.GiftCard
+	if err := query.Preload("Transactions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Gift card not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, card)
+}
+
+// addGiftCardEntry moves a gift card's balance and records the movement. The card must be
+// locked by the caller's transaction.
+func addGiftCardEntry(tx *gorm.DB, card *models.GiftCard, entry models.GiftCardTransaction) error {
+	card.Balance += entry.Amount
+	if err := tx.Model(card).Updates(map[string]interface{}{
+		"balance": card.Balance,
+		"status":  card.Status,
+	}).Error; err != nil {
+		return err
+	}
+
+	entry.ID = uuid.New()
+	entry.SalonID = card.SalonID
+	entry.GiftCardID = card.ID
+	entry.Balance = card.Balance
+	return tx.Create(&entry).Error
+}
+
+// expireGiftCards writes off the balance of active cards that are past their expiry date
+func expireGiftCards(db *gorm.DB, salonID uuid.UUID, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var cards []models.GiftCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("salon_id = ? AND status = ? AND expires_at <= ?", salonID, GiftCardActive, now).
+			Find(&cards).Error; err != nil {
+			return err
+		}
+
+		for i := range cards {
+			cards[i].Status = GiftCardExpired
+			if err := addGiftCardEntry(tx, &cards[i], models.GiftCardTransaction{
+				Type:        GiftCardExpire,
+				Amount:      -cards[i].Balance,
+				Description: "Expired unused",
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// issueGiftCards creates the gift cards sold on a finalized invoice, one per unit, each with a
+// fresh code and the line's unit value
+func issueGiftCards(tx *gorm.DB, invoice *models.Invoice) error {
+	items := invoice.Items
+	if len(items) == 0 {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+			return err
+		}
+	}
+
+	var salon models.Salon
+	loaded := false
+	now := time.Now()
+	for _, item := range items {
+		if item.ItemType != ItemTypeGiftCard {
+			continue
+		}
+
+		if !loaded {
+			if err := tx.Select("id", "gift_card_validity_months").First(&salon, "id = ?", invoice.SalonID).Error; err != nil {
+				return err
+			}
+			loaded = true
+		}
+		var expiresAt *time.Time
+		if salon.GiftCardValidityMonths > 0 {
+			expiry := now.AddDate(0, salon.GiftCardValidityMonths, 0)
+			expiresAt = &expiry
+		}
+
+		for n := 0; n < item.Quantity; n++ {
+			code, err := newGiftCardCode(tx, invoice.SalonID)
+			if err != nil {
+				return err
+			}
+
+			card := models.GiftCard{
+				ID:            uuid.New(),
+				SalonID:       invoice.SalonID,
+				Code:          code,
+				InitialValue:  item.UnitPrice,
+				Status:        GiftCardActive,
+				IssuedAt:      now,
+				ExpiresAt:     expiresAt,
+				InvoiceID:     invoice.ID,
+				InvoiceItemID: item.ID,
+				CustomerID:    invoice.CustomerID,
+			}
+			if err := tx.Create(&card).Error; err != nil {
+				return err
+			}
+			if err := addGiftCardEntry(tx, &card, models.GiftCardTransaction{
+				Type:        GiftCardIssue,
+				Amount:      item.UnitPrice,
+				InvoiceID:   &invoice.ID,
+				Description: "Sold on invoice " + invoice.InvoiceNumber,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newGiftCardCode picks a code not yet used in the salon
+func newGiftCardCode(tx *gorm.DB, salonID uuid.UUID) (string, error) {
+	for {
+		code, err := utils.RandomCode("GC", 3)
+		if err != nil {
+			return "", err
+		}
+		var count int64
+		if err := tx.Model(&models.GiftCard{}).Where("salon_id = ? AND code = ?", salonID, code).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+}
+
+// redeemGiftCard pays part of an invoice from the gift card whose code is the payment's reference
+func redeemGiftCard(tx *gorm.DB, invoice models.Invoice, payment models.Payment, now time.Time) error {
+	var card models.GiftCard
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND code = ?", invoice.SalonID, utils.NormalizeCode(payment.Reference)).
+		First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return giftCardError{message: "Gift card not found: " + payment.Reference}
+		}
+		return err
+	}
+
+	switch {
+	case card.Status != GiftCardActive:
+		return giftCardError{message: "Gift card " + card.Code + " is " + card.Status}
+	case card.ExpiresAt != nil && !card.ExpiresAt.After(now):
+		return giftCardError{message: "Gift card " + card.Code + " has expired"}
+	case card.Balance < payment.Amount:
+		return giftCardError{message: "Gift card " + card.Code + " has a balance of only " + card.Balance.String()}
+	}
+
+	return addGiftCardEntry(tx, &card, models.GiftCardTransaction{
+		Type:            GiftCardRedeem,
+		Amount:          -payment.Amount,
+		InvoiceID:       &invoice.ID,
+		PaymentID:       &payment.ID,
+		CreatedByUserID: &payment.TakenByUserID,
+		Description:     "Paid towards invoice " + invoice.InvoiceNumber,
+	})
+}
+
+// cancelGiftCards cancels gift cards sold on an invoice line, writing off their balance. With
+// count > 0 only that many unused cards are cancelled (a refund) and errGiftCardUsed is returned
+// if there are not enough; with count 0 every active card is cancelled (a void).
+func cancelGiftCards(tx *gorm.DB, invoiceItemID uuid.UUID, count int, reason string) error {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("invoice_item_id = ? AND status = ?", invoiceItemID, GiftCardActive)
+	if count > 0 {
+		query = query.Where("balance = initial_value").Limit(count)
+	}
+
+	var cards []models.GiftCard
+	if err := query.Order("issued_at").Find(&cards).Error; err != nil {
+		return err
+	}
+	if count > 0 && len(cards) < count {
+		return errGiftCardUsed
+	}
+
+	for i := range cards {
+		cards[i].Status = GiftCardCancelled
+		if err := addGiftCardEntry(tx, &cards[i], models.GiftCardTransaction{
+			Type:        GiftCardCancel,
+			Amount:      -cards[i].Balance,
+			Description: reason,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseInvoiceGiftCards cancels the gift cards sold on a voided invoice
+func releaseInvoiceGiftCards(tx *gorm.DB, invoice models.Invoice) error {
+	var items []models.InvoiceItem
+	if err := tx.Where("invoice_id = ? AND item_type = ?", invoice.ID, ItemTypeGiftCard).Find(&items).Error; err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := cancelGiftCards(tx, item.ID, 0, "Invoice "+invoice.InvoiceNumber+" voided"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refundGiftCardLines cancels the gift cards returned by a credit note. Only unused cards can be refunded.
+func refundGiftCardLines(tx *gorm.DB, invoice models.Invoice, creditNote models.CreditNote) error {
+	itemsByID := make(map[uuid.UUID]models.InvoiceItem, len(invoice.Items))
+	for _, item := range invoice.Items {
+		itemsByID[item.ID] = item
+	}
+
+	for _, credit := range creditNote.Items {
+		if itemsByID[credit.InvoiceItemID].ItemType != ItemTypeGiftCard {
+			continue
+		}
+		if err := cancelGiftCards(tx, credit.InvoiceItemID, credit.Quantity, "Refunded by credit note "+creditNote.CreditNoteNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -13,12 +14,34 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceStatus string
+
+const (
+	InvoiceDraft     InvoiceStatus = "draft"
+	InvoiceFinalized InvoiceStatus = "finalized"
+	InvoiceVoid      InvoiceStatus = "void"
+)
+
+// Invoice line types
+const (
+	ItemTypeService  = "service"
+	ItemTypePackage  = "package"
+	ItemTypeGiftCard = "gift_card"
+	ItemTypeProduct  = "product"
 )
 
 // InvoiceItemInput defines the structure for an invoice item
 type InvoiceItemInput struct {
-	ServiceID uuid.UUID `json:"serviceId" binding:"required"`
-	Quantity  int       `json:"quantity" binding:"min=1"`
+	ServiceID         *uuid.UUID           `json:"serviceId"`
+	PackageID         *uuid.UUID           `json:"packageId"`                              // Sells a package or membership instead of billing a service
+	GiftCardValue     *models.Money        `json:"giftCardValue" binding:"omitempty,gt=0"` // Sells gift cards of this value
+	ProductID         *uuid.UUID           `json:"productId"`                              // Sells a retail product from stock
+	Quantity          int                  `json:"quantity" binding:"min=1"`
+	PerformedByUserID *uuid.UUID           `json:"performedByUserId"` // Stylist who did the service
+	ProductUsage      *[]ProductUsageInput `json:"productUsage"`      // Back-bar product actually used on a service line; replaces the recipe
 }
 
 // CreateInvoiceInput defines the expected JSON structure for creating an invoice
@@ -26,11 +49,15 @@ type CreateInvoiceInput struct {
 	CustomerID    uuid.UUID          `json:"customerId" binding:"required"`
 	InvoiceDate   *time.Time         `json:"invoiceDate"`
 	Items         []InvoiceItemInput `json:"items" binding:"required,min=1"`
-	Discount      float64            `json:"discount" binding:"min=0"`
-	Tax           float64            `json:"tax" binding:"min=0"`
-	PaymentStatus string             `json:"paymentStatus" binding:"oneof=paid unpaid partial"`
-	PaidAmount    float64            `json:"paidAmount" binding:"min=0"`
-	PaymentMethod string             `json:"paymentMethod"`
+	Discount      models.Money       `json:"discount" binding:"min=0"`
+	RedeemPoints  int                `json:"redeemPoints" binding:"min=0"`                     // Loyalty points taken as a further discount
+	PromotionCode string             `json:"promotionCode"`                                    // Checked and applied to the lines it targets
+	Tax           float64            `json:"tax" binding:"min=0,max=100"`                      // Flat rate for services without a tax class
+	PlaceOfSupply string             `json:"placeOfSupply" binding:"omitempty,len=2,numeric"`  // GST state code; defaults to the salon's
+	Payments      []PaymentInput     `json:"payments" binding:"dive"`                          // Tenders taken at checkout; may be split
+	Status        string             `json:"status" binding:"omitempty,oneof=draft finalized"` // Defaults to finalized
+	SendReceipt   bool               `json:"sendReceipt"`                                      // Message the customer a receipt link
+	SkipPackages  bool               `json:"skipPackages"`                                     // Bill at full price even when a package covers the service
 	Notes         string             `json:"notes"`
 }
 
@@ -39,14 +66,19 @@ type UpdateInvoiceInput struct {
 	CustomerID    *uuid.UUID          `json:"customerId"`
 	InvoiceDate   *time.Time          `json:"invoiceDate"`
 	Items         *[]InvoiceItemInput `json:"items"`
-	Discount      *float64            `json:"discount"`
-	Tax           *float64            `json:"tax"`
-	PaymentStatus *string             `json:"paymentStatus" binding:"omitempty,oneof=paid unpaid partial"`
-	PaidAmount    *float64            `json:"paidAmount" binding:"omitempty,min=0"`
-	PaymentMethod *string             `json:"paymentMethod"`
+	Discount      *models.Money       `json:"discount" binding:"omitempty,min=0"`
+	PromotionCode *string             `json:"promotionCode"` // Empty removes the promotion
+	Tax           *float64            `json:"tax" binding:"omitempty,min=0,max=100"`
+	PlaceOfSupply *string             `json:"placeOfSupply" binding:"omitempty,len=2,numeric"`
+	SkipPackages  bool                `json:"skipPackages"` // Applies when items are replaced
 	Notes         *string             `json:"notes"`
 }
 
+// VoidInvoiceInput defines the expected JSON structure for voiding an invoice
+type VoidInvoiceInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
 // CreateInvoice creates a new invoice for the salon
 func CreateInvoice(c *gin.Context) {
 	salonID, exists := c.Get("salonId")
@@ -73,109 +105,174 @@ func CreateInvoice(c *gin.Context) {
 		return
 	}
 
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	invoice, ok := createInvoiceFromInput(c, tx, salonUUID, uuid.Must(uuid.Parse(userID.(string))), input)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	tx.Commit()
+
+	if input.SendReceipt && invoice.Status == string(InvoiceFinalized) {
+		queueInvoiceReceipt(salonUUID, invoice.ID)
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// createInvoiceFromInput prices, checks and saves a new invoice in tx, with its payments, package credits,
+// promotion and loyalty redemption. Every way of billing goes through it so that all of them apply the
+// same rules. When ok is false it has already responded with the error and the caller rolls back tx.
+func createInvoiceFromInput(c *gin.Context, tx *gorm.DB, salonID, userID uuid.UUID, input CreateInvoiceInput) (*models.Invoice, bool) {
 	// Validate customer exists in the same salon
 	var customer models.Customer
-	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+	if err := tx.Where("salon_id = ? AND id = ?", salonID, input.CustomerID).
 		First(&customer).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
 		} else {
 			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
 		}
-		return
+		return nil, false
 	}
 
 	// Validate and calculate invoice items
-	var subtotal float64 = 0
-	var invoiceItems []models.InvoiceItem
-
-	for _, item := range input.Items {
-		// Validate service exists and belongs to the same salon
-		var service models.Service
-		if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, item.ServiceID).
-			First(&service).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+item.ServiceID.String())
-			} else {
-				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
-			}
-			return
-		}
-
-		// Calculate item total
-		itemTotal := service.Price * float64(item.Quantity)
-		subtotal += itemTotal
-
-		invoiceItems = append(invoiceItems, models.InvoiceItem{
-			ID:          uuid.New(),
-			ServiceID:   service.ID,
-			ServiceName: service.Name,
-			Quantity:    item.Quantity,
-			UnitPrice:   service.Price,
-			TotalPrice:  itemTotal,
-		})
+	invoiceItems, subtotal, err := buildInvoiceItems(tx, salonID, input.Items)
+	if err != nil {
+		respondInvoiceItemsError(c, err)
+		return nil, false
 	}
 
-	// Calculate total
-	total := subtotal - input.Discount + (subtotal * input.Tax / 100)
-
 	// Set default invoice date to now if not provided
 	invoiceDate := time.Now()
 	if input.InvoiceDate != nil {
 		invoiceDate = *input.InvoiceDate
 	}
 
+	status := InvoiceFinalized
+	if input.Status != "" {
+		status = InvoiceStatus(input.Status)
+	}
+
 	// Create new invoice
 	invoice := models.Invoice{
 		ID:              uuid.New(),
-		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
-		SalonID:         salonUUID,
+		CreatedByUserID: userID,
+		SalonID:         salonID,
 		CustomerID:      input.CustomerID,
 		InvoiceDate:     invoiceDate,
 		Subtotal:        subtotal,
 		Discount:        input.Discount,
 		Tax:             input.Tax,
-		Total:           total,
-		PaymentStatus:   input.PaymentStatus,
-		PaidAmount:      input.PaidAmount,
-		PaymentMethod:   input.PaymentMethod,
+		Status:          string(status),
 		Notes:           input.Notes,
 		Items:           invoiceItems,
 	}
+	if status == InvoiceFinalized {
+		invoice.FinalizedAt = &invoiceDate
+	}
 
-	// Generate invoice number (you might want a better way)
-	invoice.InvoiceNumber = "INV-" + time.Now().Format("20060102") + "-" + utils.GenerateRandomString(6)
+	// A finalized invoice adds revenue to its period, which must not happen after commission was paid for it
+	if status == InvoiceFinalized && !checkInvoiceCommissionLock(c, tx, salonID, invoice.InvoiceDate) {
+		return nil, false
+	}
 
-	// Start transaction
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	// Services covered by the customer's packages are billed at zero against them
+	if !input.SkipPackages {
+		if err := applyPackageCredits(tx, &invoice, time.Now()); err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return nil, false
 		}
-	}()
+	}
 
-	// Save invoice
-	if err := tx.Create(&invoice).Error; err != nil {
-		tx.Rollback()
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create invoice")
-		return
+	// The promotion is checked now and its usage limits again when the invoice is saved
+	if err := applyPromotionCode(tx, &invoice, input.PromotionCode); err != nil {
+		respondInvoiceItemsError(c, err)
+		return nil, false
 	}
 
-	// Update customer stats
-	if err := tx.Model(&models.Customer{}).Where("id = ?", input.CustomerID).
-		Updates(map[string]interface{}{
-			"total_visits": gorm.Expr("total_visits + ?", 1),
-			"total_spent":  gorm.Expr("total_spent + ?", total),
-			"last_visit":   invoiceDate,
-		}).Error; err != nil {
-		tx.Rollback()
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
-		return
+	// Redeemed points become a discount line; the balance is checked when they are spent below
+	var loyalty models.LoyaltySettings
+	if input.RedeemPoints > 0 {
+		loyalty, err = loadLoyaltySettings(tx, salonID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return nil, false
+		}
+		if !loyalty.Enabled || loyalty.PointValue == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, errLoyaltyDisabled.Error())
+			return nil, false
+		}
+		invoice.LoyaltyPointsRedeemed = input.RedeemPoints
+		invoice.LoyaltyDiscount = loyalty.PointValue * models.Money(input.RedeemPoints)
 	}
 
-	tx.Commit()
+	// Tax per item, then the total
+	if err := priceInvoice(tx, &invoice, input.PlaceOfSupply); err != nil {
+		respondInvoiceItemsError(c, err)
+		return nil, false
+	}
 
-	c.JSON(http.StatusCreated, invoice)
+	// Payment status is derived from the tenders, never taken from the client
+	payments, err := buildPayments(&invoice, input.Payments, userID)
+	if err != nil {
+		respondPaymentError(c, err)
+		return nil, false
+	}
+	invoice.Payments = payments
+	applyPaymentSummary(&invoice)
+
+	if err := claimPromotion(tx, invoice); err != nil {
+		respondInvoiceItemsError(c, err)
+		return nil, false
+	}
+
+	// Save invoice and update customer stats
+	if err := createInvoiceRecord(tx, &invoice); err != nil {
+		var insufficient insufficientStockError
+		if errors.As(err, &insufficient) {
+			utils.RespondWithError(c, http.StatusBadRequest, insufficient.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create invoice")
+		}
+		return nil, false
+	}
+
+	if err := consumePackageCredits(tx, invoice.Items); err != nil {
+		if errors.Is(err, errPackageCreditsChanged) {
+			utils.RespondWithError(c, http.StatusConflict, err.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to use package credits")
+		}
+		return nil, false
+	}
+
+	if err := applyStoredValuePayments(tx, invoice, invoice.Payments); err != nil {
+		respondPaymentError(c, err)
+		return nil, false
+	}
+
+	if invoice.LoyaltyPointsRedeemed > 0 {
+		if err := redeemLoyaltyPoints(tx, loyalty, invoice); err != nil {
+			var insufficient insufficientPointsError
+			if errors.As(err, &insufficient) {
+				utils.RespondWithError(c, http.StatusBadRequest, insufficient.Error())
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Failed to redeem loyalty points")
+			}
+			return nil, false
+		}
+	}
+
+	return &invoice, true
 }
 
 // GetInvoices retrieves all invoices for the salon
@@ -192,10 +289,13 @@ func GetInvoices(c *gin.Context) {
 		return
 	}
 
+	query := config.DB.Preload("Items").Preload("Payments").Where("salon_id = ?", salonUUID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	var invoices []models.Invoice
-	if err := config.DB.Preload("Items").
-		Where("salon_id = ?", salonUUID).
-		Find(&invoices).Error; err != nil {
+	if err := query.Find(&invoices).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve invoices")
 		return
 	}
@@ -225,7 +325,7 @@ func GetInvoice(c *gin.Context) {
 	}
 
 	var invoice models.Invoice
-	if err := config.DB.Preload("Items").
+	if err := config.DB.Preload("Items").Preload("Payments").
 		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
 		First(&invoice).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -239,7 +339,7 @@ func GetInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, invoice)
 }
 
-// UpdateInvoice updates an existing invoice
+// UpdateInvoice updates a draft invoice. Finalized and void invoices are immutable.
 func UpdateInvoice(c *gin.Context) {
 	salonID, exists := c.Get("salonId")
 	if !exists {
@@ -276,7 +376,7 @@ func UpdateInvoice(c *gin.Context) {
 
 	// Retrieve existing invoice
 	var invoice models.Invoice
-	if err := tx.Preload("Items").
+	if err := tx.Preload("Items").Preload("Payments").
 		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
 		First(&invoice).Error; err != nil {
 		tx.Rollback()
@@ -288,6 +388,29 @@ func UpdateInvoice(c *gin.Context) {
 		return
 	}
 
+	if invoice.Status != string(InvoiceDraft) {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Only draft invoices can be edited")
+		return
+	}
+
+	// Package credits and loyalty points are held against the customer the draft was billed to
+	customerChanged := input.CustomerID != nil && *input.CustomerID != invoice.CustomerID
+	if customerChanged && invoice.LoyaltyPointsRedeemed > 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Loyalty points were redeemed on this invoice; delete it and bill the new customer instead")
+		return
+	}
+	if customerChanged && input.Items == nil {
+		for _, item := range invoice.Items {
+			if item.CustomerPackageID != nil {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusConflict, "Items are billed against a package; send the items again when changing the customer")
+				return
+			}
+		}
+	}
+
 	// Update fields if provided
 	if input.CustomerID != nil {
 		// Validate customer exists in the same salon
@@ -311,10 +434,19 @@ func UpdateInvoice(c *gin.Context) {
 
 	// If items are being updated, recalculate the invoice
 	if input.Items != nil {
-		var subtotal float64 = 0
-		var newInvoiceItems []models.InvoiceItem
+		// Credits held by the old items are given back before the new ones are billed
+		if err := releasePackageCredits(tx, invoice.Items); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to release package credits")
+			return
+		}
 
 		// Delete existing items
+		if err := deleteInvoiceConsumptions(tx, invoice.ID); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
+			return
+		}
 		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
 			tx.Rollback()
 			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
@@ -322,36 +454,35 @@ func UpdateInvoice(c *gin.Context) {
 		}
 
 		// Create new items
-		for _, item := range *input.Items {
-			// Validate service exists and belongs to the same salon
-			var service models.Service
-			if err := tx.Where("salon_id = ? AND id = ?", salonUUID, item.ServiceID).
-				First(&service).Error; err != nil {
-				tx.Rollback()
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+item.ServiceID.String())
-				} else {
-					utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
-				}
-				return
-			}
-
-			// Calculate item total
-			itemTotal := service.Price * float64(item.Quantity)
-			subtotal += itemTotal
-
-			newInvoiceItems = append(newInvoiceItems, models.InvoiceItem{
-				InvoiceID:   invoice.ID,
-				ServiceID:   service.ID,
-				ServiceName: service.Name,
-				Quantity:    item.Quantity,
-				UnitPrice:   service.Price,
-				TotalPrice:  itemTotal,
-			})
+		newInvoiceItems, subtotal, err := buildInvoiceItems(tx, salonUUID, *input.Items)
+		if err != nil {
+			tx.Rollback()
+			respondInvoiceItemsError(c, err)
+			return
+		}
+		for i := range newInvoiceItems {
+			newInvoiceItems[i].InvoiceID = invoice.ID
 		}
 
 		invoice.Items = newInvoiceItems
 		invoice.Subtotal = subtotal
+
+		if !input.SkipPackages {
+			if err := applyPackageCredits(tx, &invoice, time.Now()); err != nil {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+				return
+			}
+		}
+		if err := consumePackageCredits(tx, invoice.Items); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errPackageCreditsChanged) {
+				utils.RespondWithError(c, http.StatusConflict, err.Error())
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Failed to use package credits")
+			}
+			return
+		}
 	}
 
 	if input.Discount != nil {
@@ -362,29 +493,53 @@ func UpdateInvoice(c *gin.Context) {
 		invoice.Tax = *input.Tax
 	}
 
-	// Recalculate total if needed
-	if input.Items != nil || input.Discount != nil || input.Tax != nil {
-		invoice.Total = invoice.Subtotal - invoice.Discount + (invoice.Subtotal * invoice.Tax / 100)
-	}
-
-	if input.PaymentStatus != nil {
-		invoice.PaymentStatus = *input.PaymentStatus
+	// The promotion is checked again whenever the lines, customer or date it was granted for change
+	promotionChanged := input.PromotionCode != nil ||
+		(invoice.PromotionID != nil && (input.Items != nil || input.CustomerID != nil || input.InvoiceDate != nil))
+	if promotionChanged {
+		code := invoice.PromotionCode
+		if input.PromotionCode != nil {
+			code = *input.PromotionCode
+		}
+		if err := applyPromotionCode(tx, &invoice, code); err != nil {
+			tx.Rollback()
+			respondInvoiceItemsError(c, err)
+			return
+		}
+		if err := claimPromotion(tx, invoice); err != nil {
+			tx.Rollback()
+			respondInvoiceItemsError(c, err)
+			return
+		}
 	}
 
-	if input.PaidAmount != nil {
-		invoice.PaidAmount = *input.PaidAmount
+	// Re-price if needed
+	if input.Items != nil || input.Discount != nil || input.Tax != nil || input.PlaceOfSupply != nil || promotionChanged {
+		placeOfSupply := invoice.PlaceOfSupply
+		if input.PlaceOfSupply != nil {
+			placeOfSupply = *input.PlaceOfSupply
+		}
+		if err := priceInvoice(tx, &invoice, placeOfSupply); err != nil {
+			tx.Rollback()
+			respondInvoiceItemsError(c, err)
+			return
+		}
 	}
 
-	if input.PaymentMethod != nil {
-		invoice.PaymentMethod = *input.PaymentMethod
+	// The new total may change the derived payment status
+	if invoice.Total-invoice.RefundedAmount < invoice.PaidAmount {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, "Invoice total cannot be less than the amount already paid")
+		return
 	}
+	applyPaymentSummary(&invoice)
 
 	if input.Notes != nil {
 		invoice.Notes = *input.Notes
 	}
 
-	// Save updated invoice
-	if err := tx.Save(&invoice).Error; err != nil {
+	// Save updated invoice; re-pricing changes the tax on existing items too
+	if err := tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&invoice).Error; err != nil {
 		tx.Rollback()
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update invoice")
 		return
@@ -395,7 +550,7 @@ func UpdateInvoice(c *gin.Context) {
 	c.JSON(http.StatusOK, invoice)
 }
 
-// DeleteInvoice soft deletes an invoice
+// DeleteInvoice deletes a draft invoice. Finalized invoices are voided instead so they stay on record.
 func DeleteInvoice(c *gin.Context) {
 	salonID, exists := c.Get("salonId")
 	if !exists {
@@ -424,7 +579,6 @@ func DeleteInvoice(c *gin.Context) {
 		}
 	}()
 
-	// Retrieve invoice to get customer and total
 	var invoice models.Invoice
 	if err := tx.Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
 		First(&invoice).Error; err != nil {
@@ -437,7 +591,43 @@ func DeleteInvoice(c *gin.Context) {
 		return
 	}
 
+	if invoice.Status != string(InvoiceDraft) {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Only draft invoices can be deleted; void the invoice instead")
+		return
+	}
+
+	// Deposits taken against a draft must stay on record
+	var payments int64
+	if err := tx.Model(&models.Payment{}).Where("invoice_id = ?", invoice.ID).Count(&payments).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if payments > 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Invoice has payments and cannot be deleted")
+		return
+	}
+
+	// Points and package credits used on the draft go back to the customer
+	if err := releaseInvoiceLoyalty(tx, invoice, "Draft invoice deleted"); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to restore loyalty points")
+		return
+	}
+	if err := releaseInvoicePackages(tx, invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to release package credits")
+		return
+	}
+
 	// Delete invoice items
+	if err := deleteInvoiceConsumptions(tx, invoice.ID); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete invoice items")
+		return
+	}
 	if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
 		tx.Rollback()
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete invoice items")
@@ -451,18 +641,477 @@ func DeleteInvoice(c *gin.Context) {
 		return
 	}
 
-	// Update customer stats (decrement)
-	if err := tx.Model(&models.Customer{}).Where("id = ?", invoice.CustomerID).
-		Updates(map[string]interface{}{
-			"total_visits": gorm.Expr("total_visits - ?", 1),
-			"total_spent":  gorm.Expr("total_spent - ?", invoice.Total),
-		}).Error; err != nil {
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
+}
+
+// FinalizeInvoice locks a draft invoice. From then on only payments and refunds can be recorded against it.
+func FinalizeInvoice(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	invoice, ok := lockInvoiceForStatusChange(c, tx, salonUUID, invoiceUUID, InvoiceDraft)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	// Finalizing adds revenue to the period, which must not happen after commission was paid for it
+	if !checkInvoiceCommissionLock(c, tx, salonUUID, invoice.InvoiceDate) {
+		tx.Rollback()
+		return
+	}
+
+	// Drafts take their number from the series only now, so abandoned drafts leave no gaps
+	number, err := nextDocumentNumber(tx, salonUUID, SeriesInvoice, invoice.InvoiceDate)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to issue invoice number")
+		return
+	}
+
+	now := time.Now()
+	invoice.InvoiceNumber = number
+	invoice.Status = string(InvoiceFinalized)
+	invoice.FinalizedAt = &now
+	if err := tx.Model(&invoice).Updates(map[string]interface{}{
+		"invoice_number": invoice.InvoiceNumber,
+		"status":         invoice.Status,
+		"finalized_at":   invoice.FinalizedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to finalize invoice")
+		return
+	}
+
+	if err := updateCustomerInvoiceStats(tx, invoice, 1); err != nil {
 		tx.Rollback()
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
 		return
 	}
 
+	if err := awardLoyaltyPoints(tx, &invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to award loyalty points")
+		return
+	}
+
+	if err := issueCustomerPackages(tx, &invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to issue packages")
+		return
+	}
+
+	if err := issueGiftCards(tx, &invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to issue gift cards")
+		return
+	}
+
+	if err := deductInvoiceStock(tx, &invoice); err != nil {
+		tx.Rollback()
+		var insufficient insufficientStockError
+		if errors.As(err, &insufficient) {
+			utils.RespondWithError(c, http.StatusBadRequest, insufficient.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update stock")
+		}
+		return
+	}
+
 	tx.Commit()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
+	c.JSON(http.StatusOK, invoice)
+}
+
+// VoidInvoice cancels a finalized invoice. The invoice stays visible but no longer counts as revenue.
+// Only owners and managers may void, and a reason is required.
+func VoidInvoice(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can void invoices", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input VoidInvoiceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	invoice, ok := lockInvoiceForStatusChange(c, tx, salonUUID, invoiceUUID, InvoiceFinalized)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if invoice.PaidAmount > 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Invoice has payments; refund them before voiding")
+		return
+	}
+
+	if !checkInvoiceCommissionLock(c, tx, salonUUID, invoice.InvoiceDate) {
+		tx.Rollback()
+		return
+	}
+
+	now := time.Now()
+	invoice.Status = string(InvoiceVoid)
+	invoice.VoidedAt = &now
+	invoice.VoidedByUserID = &currentUser.ID
+	invoice.VoidReason = input.Reason
+	if err := tx.Model(&invoice).Updates(map[string]interface{}{
+		"status":            invoice.Status,
+		"voided_at":         invoice.VoidedAt,
+		"voided_by_user_id": invoice.VoidedByUserID,
+		"void_reason":       invoice.VoidReason,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to void invoice")
+		return
+	}
+
+	if err := updateCustomerInvoiceStats(tx, invoice, -1); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
+		return
+	}
+
+	if err := releaseInvoiceLoyalty(tx, invoice, "Invoice "+invoice.InvoiceNumber+" voided"); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to reverse loyalty points")
+		return
+	}
+
+	if err := releaseInvoicePackages(tx, invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel packages")
+		return
+	}
+
+	if err := releaseInvoiceGiftCards(tx, invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel gift cards")
+		return
+	}
+
+	if err := restoreInvoiceStock(tx, invoice, currentUser.ID); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to restore stock")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// serviceNotFoundError is returned when an invoice line references a service outside the salon
+type serviceNotFoundError struct {
+	ServiceID uuid.UUID
+}
+
+func (e serviceNotFoundError) Error() string {
+	return "Service not found: " + e.ServiceID.String()
+}
+
+// errInvalidInvoiceLine is returned when an invoice line does not say clearly what it bills
+var errInvalidInvoiceLine = errors.New("Each item needs exactly one of serviceId, packageId, productId or giftCardValue")
+
+// performerNotFoundError is returned when an invoice line is credited to someone who is not an active user of the salon
+type performerNotFoundError struct {
+	UserID uuid.UUID
+}
+
+func (e performerNotFoundError) Error() string {
+	return "Staff member not found: " + e.UserID.String()
+}
+
+// buildInvoiceItems validates the requested services and packages and prices each line at the current price.
+// It is the single pricing path for invoices, whether entered at the desk or produced from an appointment.
+func buildInvoiceItems(db *gorm.DB, salonID uuid.UUID, items []InvoiceItemInput) ([]models.InvoiceItem, models.Money, error) {
+	var subtotal models.Money
+	var invoiceItems []models.InvoiceItem
+	activeStaff := make(map[uuid.UUID]bool)
+	taxClasses := make(map[uuid.UUID]models.TaxClass)
+
+	for _, item := range items {
+		kinds := 0
+		for _, set := range []bool{item.ServiceID != nil, item.PackageID != nil, item.ProductID != nil, item.GiftCardValue != nil} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return nil, 0, errInvalidInvoiceLine
+		}
+
+		// Validate the performing staff member is an active user of the same salon
+		if item.PerformedByUserID != nil && !activeStaff[*item.PerformedByUserID] {
+			var staff models.User
+			if err := db.Where("salon_id = ? AND id = ? AND is_active = ?", salonID, *item.PerformedByUserID, true).
+				First(&staff).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, 0, performerNotFoundError{UserID: *item.PerformedByUserID}
+				}
+				return nil, 0, err
+			}
+			activeStaff[staff.ID] = true
+		}
+
+		invoiceItem := models.InvoiceItem{
+			ID:                uuid.New(),
+			Quantity:          item.Quantity,
+			PerformedByUserID: item.PerformedByUserID,
+		}
+
+		var taxClassID *uuid.UUID
+		switch {
+		case item.GiftCardValue != nil:
+			// Gift cards are stored value; tax is charged when they are spent
+			if *item.GiftCardValue <= 0 {
+				return nil, 0, errInvalidGiftCardValue
+			}
+			invoiceItem.ItemType = ItemTypeGiftCard
+			invoiceItem.ServiceName = "Gift card"
+			invoiceItem.UnitPrice = *item.GiftCardValue
+		case item.PackageID != nil:
+			// Validate the package is on sale in the same salon
+			var pkg models.ServicePackage
+			if err := db.Where("salon_id = ? AND id = ? AND is_active = ?", salonID, *item.PackageID, true).
+				First(&pkg).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, 0, packageNotFoundError{PackageID: *item.PackageID}
+				}
+				return nil, 0, err
+			}
+			invoiceItem.ItemType = ItemTypePackage
+			invoiceItem.PackageID = &pkg.ID
+			invoiceItem.ServiceName = pkg.Name
+			invoiceItem.UnitPrice = pkg.Price
+			taxClassID = pkg.TaxClassID
+		case item.ProductID != nil:
+			// Validate the product is on sale in the same salon; stock is taken when the invoice is finalized
+			var product models.Product
+			if err := db.Where("salon_id = ? AND id = ? AND is_active = ?", salonID, *item.ProductID, true).
+				First(&product).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, 0, productNotFoundError{ProductID: *item.ProductID}
+				}
+				return nil, 0, err
+			}
+			invoiceItem.ItemType = ItemTypeProduct
+			invoiceItem.ProductID = &product.ID
+			invoiceItem.ServiceName = product.Name
+			invoiceItem.UnitPrice = product.SalePrice
+			taxClassID = product.TaxClassID
+		default:
+			// Validate service exists and belongs to the same salon
+			var service models.Service
+			if err := db.Where("salon_id = ? AND id = ?", salonID, *item.ServiceID).
+				First(&service).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, 0, serviceNotFoundError{ServiceID: *item.ServiceID}
+				}
+				return nil, 0, err
+			}
+			invoiceItem.ItemType = ItemTypeService
+			invoiceItem.ServiceID = &service.ID
+			invoiceItem.ServiceName = service.Name
+			invoiceItem.UnitPrice = service.Price
+			taxClassID = service.TaxClassID
+
+			consumptions, err := buildLineConsumptions(db, salonID, service.ID, item.Quantity, item.ProductUsage)
+			if err != nil {
+				return nil, 0, err
+			}
+			invoiceItem.Consumptions = consumptions
+		}
+
+		// Calculate item total
+		invoiceItem.TotalPrice = invoiceItem.UnitPrice * models.Money(item.Quantity)
+		subtotal += invoiceItem.TotalPrice
+
+		// The rate is copied onto the line so later changes to the class do not alter the bill
+		if taxClassID != nil {
+			class, ok := taxClasses[*taxClassID]
+			if !ok {
+				if err := db.Where("salon_id = ? AND id = ?", salonID, *taxClassID).
+					First(&class).Error; err != nil {
+					return nil, 0, err
+				}
+				taxClasses[class.ID] = class
+			}
+			invoiceItem.TaxClassID = &class.ID
+			invoiceItem.TaxCode = class.Code
+			invoiceItem.TaxRate = class.Rate
+		}
+
+		invoiceItems = append(invoiceItems, invoiceItem)
+	}
+
+	return invoiceItems, subtotal, nil
+}
+
+// respondInvoiceItemsError maps an error from buildInvoiceItems, applyPromotionCode or priceInvoice to an HTTP response
+func respondInvoiceItemsError(c *gin.Context, err error) {
+	var notFound serviceNotFoundError
+	if errors.As(err, &notFound) {
+		utils.RespondWithError(c, http.StatusBadRequest, notFound.Error())
+		return
+	}
+	var performerNotFound performerNotFoundError
+	if errors.As(err, &performerNotFound) {
+		utils.RespondWithError(c, http.StatusBadRequest, performerNotFound.Error())
+		return
+	}
+	var packageNotFound packageNotFoundError
+	if errors.As(err, &packageNotFound) {
+		utils.RespondWithError(c, http.StatusBadRequest, packageNotFound.Error())
+		return
+	}
+	var productNotFound productNotFoundError
+	if errors.As(err, &productNotFound) {
+		utils.RespondWithError(c, http.StatusBadRequest, productNotFound.Error())
+		return
+	}
+	var promotion promotionError
+	if errors.As(err, &promotion) {
+		utils.RespondWithError(c, http.StatusBadRequest, promotion.Error())
+		return
+	}
+	if errors.Is(err, errDiscountExceedsSubtotal) || errors.Is(err, errInvalidPlaceOfSupply) || errors.Is(err, errInvalidInvoiceLine) || errors.Is(err, errInvalidGiftCardValue) || errors.Is(err, errInvalidProductUsage) {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+}
+
+// createInvoiceRecord numbers and saves a new invoice with its items and, once it is finalized,
+// updates the customer's visit stats and loyalty points, takes sold products out of stock and issues
+// what it sold: packages and gift cards.
+// It must run inside the caller's transaction.
+func createInvoiceRecord(tx *gorm.DB, invoice *models.Invoice) error {
+	if invoice.Status == string(InvoiceFinalized) {
+		number, err := nextDocumentNumber(tx, invoice.SalonID, SeriesInvoice, invoice.InvoiceDate)
+		if err != nil {
+			return fmt.Errorf("failed to issue invoice number: %w", err)
+		}
+		invoice.InvoiceNumber = number
+	} else {
+		invoice.InvoiceNumber = draftInvoiceNumber()
+	}
+
+	if err := tx.Create(invoice).Error; err != nil {
+		return fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	if invoice.Status == string(InvoiceFinalized) {
+		if err := updateCustomerInvoiceStats(tx, *invoice, 1); err != nil {
+			return err
+		}
+		if err := awardLoyaltyPoints(tx, invoice); err != nil {
+			return fmt.Errorf("failed to award loyalty points: %w", err)
+		}
+		if err := issueCustomerPackages(tx, invoice); err != nil {
+			return fmt.Errorf("failed to issue packages: %w", err)
+		}
+		if err := issueGiftCards(tx, invoice); err != nil {
+			return fmt.Errorf("failed to issue gift cards: %w", err)
+		}
+		if err := deductInvoiceStock(tx, invoice); err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// updateCustomerInvoiceStats adds (sign 1) or removes (sign -1) a finalized invoice from the customer's visit stats
+func updateCustomerInvoiceStats(tx *gorm.DB, invoice models.Invoice, sign int) error {
+	updates := map[string]interface{}{
+		"total_visits": gorm.Expr("total_visits + ?", sign),
+		"total_spent":  gorm.Expr("total_spent + ?", models.Money(sign)*(invoice.Total-invoice.RefundedAmount)),
+	}
+	if sign > 0 {
+		updates["last_visit"] = invoice.InvoiceDate
+	}
+
+	if err := tx.Model(&models.Customer{}).Where("id = ?", invoice.CustomerID).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update customer stats: %w", err)
+	}
+	return nil
+}
+
+// lockInvoiceForStatusChange loads an invoice FOR UPDATE and checks it is in the expected status.
+// On failure it writes the error response; the caller rolls back.
+func lockInvoiceForStatusChange(c *gin.Context, tx *gorm.DB, salonID, invoiceID uuid.UUID, expected InvoiceStatus) (models.Invoice, bool) {
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonID, invoiceID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return invoice, false
+	}
+
+	if invoice.Status != string(expected) {
+		utils.RespondWithError(c, http.StatusConflict, fmt.Sprintf("Invoice is %s, expected %s", invoice.Status, expected))
+		return invoice, false
+	}
+	return invoice, true
 }
@@ -0,0 +1,122 @@
+// controllers/invoice_print.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetInvoicePrint renders an invoice for printing.
+// GET /api/invoices/:id/pdf[?format=a4|a5|text|escpos][&paper=58|80]
+// a4 (default) and a5 return a PDF; text and escpos are receipts for 58mm or 80mm (default) thermal printers.
+func GetInvoicePrint(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	columns := services.Receipt80mmColumns
+	switch c.DefaultQuery("paper", "80") {
+	case "80":
+	case "58":
+		columns = services.Receipt58mmColumns
+	default:
+		utils.RespondWithError(c, http.StatusBadRequest, "paper must be 58 or 80")
+		return
+	}
+
+	doc, err := loadInvoiceDocument(config.DB, salonUUID, invoiceUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	filename := doc.Invoice.InvoiceNumber
+	switch c.DefaultQuery("format", "a4") {
+	case "a4":
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(doc, utils.PageA4))
+	case "a5":
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename+".pdf"))
+		c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(doc, utils.PageA5))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(services.RenderInvoiceText(doc, columns)))
+	case "escpos":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".bin"))
+		c.Data(http.StatusOK, "application/octet-stream", services.RenderInvoiceESCPOS(doc, columns))
+	default:
+		utils.RespondWithError(c, http.StatusBadRequest, "format must be one of a4, a5, text, escpos")
+	}
+}
+
+// loadInvoiceDocument gathers the invoice, its salon and logo, customer and staff names for rendering
+func loadInvoiceDocument(db *gorm.DB, salonID, invoiceID uuid.UUID) (services.InvoiceDocument, error) {
+	var doc services.InvoiceDocument
+
+	if err := db.Preload("Items").Preload("Payments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("paid_at")
+	}).Where("salon_id = ? AND id = ?", salonID, invoiceID).
+		First(&doc.Invoice).Error; err != nil {
+		return doc, err
+	}
+
+	if err := db.First(&doc.Salon, "id = ?", salonID).Error; err != nil {
+		return doc, err
+	}
+
+	var logo models.SalonLogo
+	if err := db.Where("salon_id = ?", salonID).Limit(1).Find(&logo).Error; err != nil {
+		return doc, err
+	}
+	doc.Logo = logo.Data
+
+	if err := db.First(&doc.Customer, "id = ?", doc.Invoice.CustomerID).Error; err != nil {
+		return doc, err
+	}
+
+	var staffIDs []uuid.UUID
+	for _, item := range doc.Invoice.Items {
+		if item.PerformedByUserID != nil {
+			staffIDs = append(staffIDs, *item.PerformedByUserID)
+		}
+	}
+	doc.StaffNames = make(map[uuid.UUID]string)
+	if len(staffIDs) > 0 {
+		var staff []models.User
+		if err := db.Select("id", "name").Where("id IN ?", staffIDs).Find(&staff).Error; err != nil {
+			return doc, err
+		}
+		for _, u := range staff {
+			doc.StaffNames[u.ID] = u.Name
+		}
+	}
+
+	return doc, nil
+}
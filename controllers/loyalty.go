@@ -0,0 +1,471 @@
+// controllers/loyalty.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Loyalty ledger entry types
+const (
+	LoyaltyEarn    = "earn"    // credited when an invoice is finalized
+	LoyaltyRedeem  = "redeem"  // spent as a discount on an invoice
+	LoyaltyReverse = "reverse" // earned points taken back by a refund or void
+	LoyaltyRestore = "restore" // redeemed points returned when the invoice is voided or deleted
+	LoyaltyExpire  = "expire"
+)
+
+// LoyaltyMultiplierInput sets the earning multiplier for one service category
+type LoyaltyMultiplierInput struct {
+	Category   string  `json:"category" binding:"required"`
+	Multiplier float64 `json:"multiplier" binding:"min=0,max=100"`
+}
+
+// LoyaltySettingsInput defines the expected JSON structure for configuring the loyalty program
+type LoyaltySettingsInput struct {
+	Enabled       bool                     `json:"enabled"`
+	PointsPerUnit float64                  `json:"pointsPerUnit" binding:"min=0"` // Points per unit of currency spent before tax
+	PointValue    models.Money             `json:"pointValue" binding:"min=0"`    // Discount given per point redeemed
+	ExpiryMonths  int                      `json:"expiryMonths" binding:"min=0,max=120"`
+	Multipliers   []LoyaltyMultiplierInput `json:"multipliers" binding:"dive"`
+}
+
+// errLoyaltyDisabled is returned when points are redeemed in a salon without a loyalty program
+var errLoyaltyDisabled = errors.New("Loyalty program is not enabled")
+
+// insufficientPointsError is returned when a customer redeems more points than they hold
+type insufficientPointsError struct {
+	Balance   int
+	Requested int
+}
+
+func (e insufficientPointsError) Error() string {
+	return fmt.Sprintf("Customer has %d loyalty points, cannot redeem %d", e.Balance, e.Requested)
+}
+
+// GetLoyaltySettings returns the salon's loyalty program configuration
+func GetLoyaltySettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	settings, err := loadLoyaltySettings(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateLoyaltySettings replaces the salon's loyalty program configuration and its category multipliers.
+// Points already earned keep their value; only future invoices use the new rates.
+func UpdateLoyaltySettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can configure the loyalty program", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input LoyaltySettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	settings := models.LoyaltySettings{
+		SalonID:       salonUUID,
+		Enabled:       input.Enabled,
+		PointsPerUnit: input.PointsPerUnit,
+		PointValue:    input.PointValue,
+		ExpiryMonths:  input.ExpiryMonths,
+	}
+	seen := make(map[string]bool)
+	for _, m := range input.Multipliers {
+		category := strings.TrimSpace(m.Category)
+		if seen[strings.ToLower(category)] {
+			utils.RespondWithError(c, http.StatusBadRequest, "Duplicate multiplier for category "+category)
+			return
+		}
+		seen[strings.ToLower(category)] = true
+		settings.Multipliers = append(settings.Multipliers, models.LoyaltyMultiplier{
+			ID:         uuid.New(),
+			SalonID:    salonUUID,
+			Category:   category,
+			Multiplier: m.Multiplier,
+		})
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("salon_id = ?", salonUUID).Delete(&models.LoyaltyMultiplier{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing multipliers")
+		return
+	}
+
+	if err := tx.Save(&settings).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update loyalty settings")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetCustomerLoyalty returns a customer's points balance and ledger, newest first.
+// Points past their expiry are written off before the balance is reported.
+func GetCustomerLoyalty(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	settings, err := loadLoyaltySettings(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty settings")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	balance, err := expireLoyaltyPoints(tx, settings, customer.ID, time.Now())
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update loyalty balance")
+		return
+	}
+
+	tx.Commit()
+
+	var history []models.LoyaltyTransaction
+	if err := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID).
+		Order("created_at DESC").Find(&history).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerId":   customer.ID,
+		"balance":      balance,
+		"balanceValue": settings.PointValue * models.Money(balance),
+		"enabled":      settings.Enabled,
+		"expiryMonths": settings.ExpiryMonths,
+		"history":      history,
+	})
+}
+
+// loadLoyaltySettings returns the salon's loyalty configuration; a salon that never set one up
+// gets a disabled program.
+func loadLoyaltySettings(db *gorm.DB, salonID uuid.UUID) (models.LoyaltySettings, error) {
+	var settings models.LoyaltySettings
+	err := db.Preload("Multipliers").Where("salon_id = ?", salonID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoyaltySettings{SalonID: salonID}, nil
+	}
+	return settings, err
+}
+
+// addLoyaltyEntry records a ledger entry and moves the customer's cached balance with it.
+// It returns the balance after the entry and must run inside the caller's transaction.
+func addLoyaltyEntry(tx *gorm.DB, entry models.LoyaltyTransaction) (int, error) {
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "loyalty_points").
+		First(&customer, "id = ?", entry.CustomerID).Error; err != nil {
+		return 0, fmt.Errorf("failed to lock customer: %w", err)
+	}
+
+	entry.ID = uuid.New()
+	entry.Balance = customer.LoyaltyPoints + entry.Points
+	if err := tx.Model(&customer).Update("loyalty_points", entry.Balance).Error; err != nil {
+		return 0, fmt.Errorf("failed to update loyalty balance: %w", err)
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return 0, fmt.Errorf("failed to record loyalty entry: %w", err)
+	}
+	return entry.Balance, nil
+}
+
+// expireLoyaltyPoints writes off points older than the salon's expiry period and returns the
+// customer's balance. Points are spent oldest first, so whatever was earned before the cutoff and
+// has not been covered by redemptions, reversals or earlier expiries has lapsed.
+func expireLoyaltyPoints(tx *gorm.DB, settings models.LoyaltySettings, customerID uuid.UUID, now time.Time) (int, error) {
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "loyalty_points").
+		First(&customer, "id = ?", customerID).Error; err != nil {
+		return 0, fmt.Errorf("failed to lock customer: %w", err)
+	}
+	if settings.ExpiryMonths == 0 {
+		return customer.LoyaltyPoints, nil
+	}
+
+	cutoff := now.AddDate(0, -settings.ExpiryMonths, 0)
+	var lapsed int
+	if err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("customer_id = ? AND ((points > 0 AND created_at < ?) OR points < 0)", customerID, cutoff).
+		Select("COALESCE(SUM(points), 0)").Scan(&lapsed).Error; err != nil {
+		return 0, err
+	}
+	if lapsed <= 0 {
+		return customer.LoyaltyPoints, nil
+	}
+
+	return addLoyaltyEntry(tx, models.LoyaltyTransaction{
+		SalonID:     settings.SalonID,
+		CustomerID:  customerID,
+		Type:        LoyaltyExpire,
+		Points:      -lapsed,
+		Description: "Points earned before " + cutoff.Format("2006-01-02") + " expired",
+	})
+}
+
+// redeemLoyaltyPoints spends the points redeemed on a new invoice. The discount itself was
+// priced onto the invoice by the caller.
+func redeemLoyaltyPoints(tx *gorm.DB, settings models.LoyaltySettings, invoice models.Invoice) error {
+	balance, err := expireLoyaltyPoints(tx, settings, invoice.CustomerID, time.Now())
+	if err != nil {
+		return err
+	}
+	if balance < invoice.LoyaltyPointsRedeemed {
+		return insufficientPointsError{Balance: balance, Requested: invoice.LoyaltyPointsRedeemed}
+	}
+
+	_, err = addLoyaltyEntry(tx, models.LoyaltyTransaction{
+		SalonID:     invoice.SalonID,
+		CustomerID:  invoice.CustomerID,
+		InvoiceID:   &invoice.ID,
+		Type:        LoyaltyRedeem,
+		Points:      -invoice.LoyaltyPointsRedeemed,
+		Description: fmt.Sprintf("Redeemed for a discount of %s", invoice.LoyaltyDiscount),
+	})
+	return err
+}
+
+// awardLoyaltyPoints credits the points a finalized invoice earns: its value before tax, after
+// all discounts, times the salon's rate and the multiplier of each line's service category.
+func awardLoyaltyPoints(tx *gorm.DB, invoice *models.Invoice) error {
+	settings, err := loadLoyaltySettings(tx, invoice.SalonID)
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled || settings.PointsPerUnit <= 0 {
+		return nil
+	}
+
+	items := invoice.Items
+	if len(items) == 0 {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+			return err
+		}
+	}
+
+	multipliers := make(map[string]float64, len(settings.Multipliers))
+	for _, m := range settings.Multipliers {
+		multipliers[strings.ToLower(m.Category)] = m.Multiplier
+	}
+
+	serviceIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		if item.ServiceID != nil {
+			serviceIDs = append(serviceIDs, *item.ServiceID)
+		}
+	}
+	var services []models.Service
+	if len(serviceIDs) > 0 {
+		if err := tx.Select("id", "category").Where("id IN ?", serviceIDs).Find(&services).Error; err != nil {
+			return err
+		}
+	}
+	categories := make(map[uuid.UUID]string, len(services))
+	for _, s := range services {
+		categories[s.ID] = strings.ToLower(s.Category)
+	}
+
+	var earned float64
+	for _, item := range items {
+		// Gift cards earn when they are spent, not when they are bought
+		if item.ItemType == ItemTypeGiftCard {
+			continue
+		}
+		value := item.TaxableValue
+		if item.LineTotal == 0 {
+			value = item.TotalPrice - item.DiscountAmount
+		}
+		multiplier := 1.0
+		if item.ServiceID != nil {
+			if m, ok := multipliers[categories[*item.ServiceID]]; ok {
+				multiplier = m
+			}
+		}
+		earned += value.Float64() * settings.PointsPerUnit * multiplier
+	}
+
+	// Whole points only; the small epsilon keeps 99.99999 from flooring to 99
+	points := int(math.Floor(earned + 1e-9))
+	if points <= 0 {
+		return nil
+	}
+
+	if _, err := addLoyaltyEntry(tx, models.LoyaltyTransaction{
+		SalonID:     invoice.SalonID,
+		CustomerID:  invoice.CustomerID,
+		InvoiceID:   &invoice.ID,
+		Type:        LoyaltyEarn,
+		Points:      points,
+		Description: "Earned on invoice " + invoice.InvoiceNumber,
+	}); err != nil {
+		return err
+	}
+
+	invoice.LoyaltyPointsEarned = points
+	return tx.Model(invoice).Update("loyalty_points_earned", points).Error
+}
+
+// reverseRefundLoyalty takes back the points earned on the refunded part of an invoice, in
+// proportion to the credit note. The refund that clears the invoice takes back the remainder.
+// invoice.RefundedAmount must already include the credit note.
+func reverseRefundLoyalty(tx *gorm.DB, invoice models.Invoice, creditNote models.CreditNote) error {
+	standing, err := standingLoyaltyPoints(tx, invoice)
+	if err != nil || standing <= 0 {
+		return err
+	}
+
+	points := standing
+	if invoice.RefundedAmount < invoice.Total {
+		points = int(models.Money(invoice.LoyaltyPointsEarned).Share(creditNote.Amount, invoice.Total))
+		if points > standing {
+			points = standing
+		}
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	_, err = addLoyaltyEntry(tx, models.LoyaltyTransaction{
+		SalonID:      invoice.SalonID,
+		CustomerID:   invoice.CustomerID,
+		InvoiceID:    &invoice.ID,
+		CreditNoteID: &creditNote.ID,
+		Type:         LoyaltyReverse,
+		Points:       -points,
+		Description:  "Reversed by credit note " + creditNote.CreditNoteNumber,
+	})
+	return err
+}
+
+// releaseInvoiceLoyalty undoes an invoice's effect on the ledger when it is voided or a draft is
+// deleted: earned points still standing are taken back and redeemed points are returned.
+// A customer who already spent the earned points can go negative until they earn more.
+func releaseInvoiceLoyalty(tx *gorm.DB, invoice models.Invoice, reason string) error {
+	standing, err := standingLoyaltyPoints(tx, invoice)
+	if err != nil {
+		return err
+	}
+	if standing > 0 {
+		if _, err := addLoyaltyEntry(tx, models.LoyaltyTransaction{
+			SalonID:     invoice.SalonID,
+			CustomerID:  invoice.CustomerID,
+			InvoiceID:   &invoice.ID,
+			Type:        LoyaltyReverse,
+			Points:      -standing,
+			Description: reason,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if invoice.LoyaltyPointsRedeemed > 0 {
+		if _, err := addLoyaltyEntry(tx, models.LoyaltyTransaction{
+			SalonID:     invoice.SalonID,
+			CustomerID:  invoice.CustomerID,
+			InvoiceID:   &invoice.ID,
+			Type:        LoyaltyRestore,
+			Points:      invoice.LoyaltyPointsRedeemed,
+			Description: reason,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// standingLoyaltyPoints returns the points an invoice earned that have not yet been reversed
+func standingLoyaltyPoints(tx *gorm.DB, invoice models.Invoice) (int, error) {
+	if invoice.LoyaltyPointsEarned == 0 {
+		return 0, nil
+	}
+	var standing int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("invoice_id = ? AND type IN ?", invoice.ID, []string{LoyaltyEarn, LoyaltyReverse}).
+		Select("COALESCE(SUM(points), 0)").Scan(&standing).Error
+	return standing, err
+}
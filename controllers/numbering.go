@@ -0,0 +1,91 @@
+// controllers/numbering.go
+package controllers
+
+import (
+	"time"
+
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Numbering series kept in document_sequences
+const (
+	SeriesInvoice    = "invoice"
+	SeriesCreditNote = "credit_note"
+	SeriesPurchase   = "purchase_order"
+)
+
+// Formats used when a salon has not configured its own
+const (
+	defaultInvoiceNumberFormat    = "{SALON}-{FY}-{SEQ:05}"
+	defaultCreditNoteNumberFormat = "{SALON}-CN-{FY}-{SEQ:05}"
+)
+
+// Purchase orders are internal documents and always use this format
+const purchaseOrderNumberFormat = "{SALON}-PO-{FY}-{SEQ:05}"
+
+// nextDocumentSequence issues the next number in a salon's series for a financial year. The upsert takes a
+// row lock that is held until tx commits, so concurrent callers queue up and a rolled-back transaction
+// gives its number back.
+func nextDocumentSequence(tx *gorm.DB, salonID uuid.UUID, series, fiscalYear string) (int64, error) {
+	var next int64
+	err := tx.Raw(`
+		INSERT INTO document_sequences (salon_id, series, fiscal_year, last_value)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT (salon_id, series, fiscal_year)
+		DO UPDATE SET last_value = document_sequences.last_value + 1
+		RETURNING last_value
+	`, salonID, series, fiscalYear).Scan(&next).Error
+	return next, err
+}
+
+// nextDocumentNumber issues and formats the salon's next number in a series, dated for the financial year of date
+func nextDocumentNumber(tx *gorm.DB, salonID uuid.UUID, series string, date time.Time) (string, error) {
+	var salon models.Salon
+	if err := tx.Select("name", "code", "invoice_number_format", "credit_note_number_format", "fiscal_year_start_month").
+		First(&salon, "id = ?", salonID).Error; err != nil {
+		return "", err
+	}
+
+	format := salon.InvoiceNumberFormat
+	if format == "" {
+		format = defaultInvoiceNumberFormat
+	}
+	if series == SeriesCreditNote {
+		format = salon.CreditNoteNumberFormat
+		if format == "" {
+			format = defaultCreditNoteNumberFormat
+		}
+	}
+	if series == SeriesPurchase {
+		format = purchaseOrderNumberFormat
+	}
+	// Formats saved before {FY} was required would repeat numbers in the next financial year
+	if utils.ValidateNumberFormat(format) != nil {
+		format = defaultInvoiceNumberFormat
+		if series == SeriesCreditNote {
+			format = defaultCreditNoteNumberFormat
+		}
+	}
+
+	code := salon.Code
+	if code == "" {
+		code = utils.DefaultSalonCode(salon.Name)
+	}
+
+	fiscalYear := utils.FiscalYearLabel(date, salon.FiscalYearStartMonth)
+	seq, err := nextDocumentSequence(tx, salonID, series, fiscalYear)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.FormatDocumentNumber(format, code, date, fiscalYear, seq), nil
+}
+
+// draftInvoiceNumber is a placeholder for drafts, which only take a number from the series when finalized
+func draftInvoiceNumber() string {
+	return "DRAFT-" + utils.GenerateRandomString(8)
+}
@@ -0,0 +1,732 @@
+// controllers/package.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Customer package statuses. Only active and cancelled are stored; expired and used are
+// derived when packages are listed.
+const (
+	PackageActive    = "active"
+	PackageCancelled = "cancelled"
+	PackageExpired   = "expired"
+	PackageUsed      = "used"
+)
+
+// PackageItemInput defines a service a package covers
+type PackageItemInput struct {
+	ServiceID uuid.UUID `json:"serviceId" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"min=0"` // 0 unlimited while the package is valid
+}
+
+// PackageInput defines the expected JSON structure for creating or replacing a package or membership
+type PackageInput struct {
+	Name         string             `json:"name" binding:"required"`
+	Description  string             `json:"description"`
+	Kind         string             `json:"kind" binding:"omitempty,oneof=package membership"` // Defaults to package
+	Price        models.Money       `json:"price" binding:"min=0"`
+	ValidityDays int                `json:"validityDays" binding:"min=0"` // 0 never expires
+	TaxClassID   *uuid.UUID         `json:"taxClassId"`
+	IsActive     *bool              `json:"isActive"`
+	Items        []PackageItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// CustomerPackageCreditView is a covered service with what is left of it
+type CustomerPackageCreditView struct {
+	ServiceID   uuid.UUID `json:"serviceId"`
+	ServiceName string    `json:"serviceName"`
+	Quantity    int       `json:"quantity"`
+	Used        int       `json:"used"`
+	Remaining   int       `json:"remaining"`
+	Unlimited   bool      `json:"unlimited"`
+}
+
+// CustomerPackageView is a package a customer bought, with its remaining balance and expiry
+type CustomerPackageView struct {
+	ID          uuid.UUID                   `json:"id"`
+	PackageID   uuid.UUID                   `json:"packageId"`
+	InvoiceID   uuid.UUID                   `json:"invoiceId"`
+	Name        string                      `json:"name"`
+	Kind        string                      `json:"kind"`
+	PurchasedAt time.Time                   `json:"purchasedAt"`
+	ExpiresAt   *time.Time                  `json:"expiresAt"`
+	DaysLeft    *int                        `json:"daysLeft"`
+	Status      string                      `json:"status"`
+	Credits     []CustomerPackageCreditView `json:"credits"`
+}
+
+// packageNotFoundError is returned when an invoice line sells a package the salon does not offer
+type packageNotFoundError struct {
+	PackageID uuid.UUID
+}
+
+func (e packageNotFoundError) Error() string {
+	return "Package not found: " + e.PackageID.String()
+}
+
+// errPackageCreditsChanged is returned when credits picked for an invoice were used up by
+// another invoice before they could be consumed
+var errPackageCreditsChanged = errors.New("Package credits changed while billing; please try again")
+
+// GetPackages lists the salon's packages and memberships. ?active=true hides retired ones.
+func GetPackages(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	query := config.DB.Preload("Items").Where("salon_id = ?", salonUUID)
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var packages []models.ServicePackage
+	if err := query.Order("name").Find(&packages).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve packages")
+		return
+	}
+
+	c.JSON(http.StatusOK, packages)
+}
+
+// GetPackage returns a single package with the services it covers
+func GetPackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	var pkg models.ServicePackage
+	if err := config.DB.Preload("Items").Where("salon_id = ? AND id = ?", salonUUID, packageUUID).
+		First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg)
+}
+
+// CreatePackage defines a new package or membership
+func CreatePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage packages", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input PackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	pkg := models.ServicePackage{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+	}
+	if !applyPackageInput(c, salonUUID, &pkg, input) {
+		return
+	}
+
+	if err := config.DB.Create(&pkg).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create package")
+		return
+	}
+
+	c.JSON(http.StatusCreated, pkg)
+}
+
+// UpdatePackage replaces a package and the services it covers. Packages already sold keep
+// the credits they were sold with.
+func UpdatePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage packages", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input PackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var pkg models.ServicePackage
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, packageUUID).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applyPackageInput(c, salonUUID, &pkg, input) {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("package_id = ?", pkg.ID).Delete(&models.ServicePackageItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
+		return
+	}
+
+	if err := tx.Save(&pkg).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update package")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, pkg)
+}
+
+// DeletePackage removes a package that was never sold. Sold packages are retired with isActive=false instead.
+func DeletePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage packages", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var sold int64
+	if err := config.DB.Model(&models.InvoiceItem{}).Where("package_id = ?", packageUUID).Count(&sold).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if sold > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Package has been sold; deactivate it instead")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, packageUUID).Delete(&models.ServicePackage{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete package")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Package not found")
+		return
+	}
+
+	if err := tx.Where("package_id = ?", packageUUID).Delete(&models.ServicePackageItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete package items")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Package deleted successfully"})
+}
+
+// GetCustomerPackages lists the packages a customer bought with their remaining credits and expiry.
+// ?status=active shows only packages that can still be used.
+func GetCustomerPackages(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var packages []models.CustomerPackage
+	if err := config.DB.Preload("Credits").
+		Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID).
+		Order("purchased_at DESC").Find(&packages).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve packages")
+		return
+	}
+
+	now := time.Now()
+	views := make([]CustomerPackageView, 0, len(packages))
+	for _, p := range packages {
+		view := customerPackageView(p, now)
+		if c.Query("status") == PackageActive && view.Status != PackageActive {
+			continue
+		}
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// applyPackageInput validates input and copies it onto pkg. On failure it writes the error response.
+func applyPackageInput(c *gin.Context, salonID uuid.UUID, pkg *models.ServicePackage, input PackageInput) bool {
+	if input.TaxClassID != nil {
+		if err := checkTaxClass(config.DB, salonID, *input.TaxClassID); err != nil {
+			respondTaxClassError(c, err)
+			return false
+		}
+	}
+
+	kind := input.Kind
+	if kind == "" {
+		kind = "package"
+	}
+
+	pkg.Name = strings.TrimSpace(input.Name)
+	pkg.Description = input.Description
+	pkg.Kind = kind
+	pkg.Price = input.Price
+	pkg.ValidityDays = input.ValidityDays
+	pkg.TaxClassID = input.TaxClassID
+	pkg.IsActive = input.IsActive == nil || *input.IsActive
+
+	// Each service appears once so a billed line maps to a single credit
+	pkg.Items = nil
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range input.Items {
+		if seen[item.ServiceID] {
+			utils.RespondWithError(c, http.StatusBadRequest, "Service listed twice: "+item.ServiceID.String())
+			return false
+		}
+		seen[item.ServiceID] = true
+
+		var service models.Service
+		if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonID, item.ServiceID).
+			First(&service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+item.ServiceID.String())
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return false
+		}
+
+		pkg.Items = append(pkg.Items, models.ServicePackageItem{
+			ID:        uuid.New(),
+			PackageID: pkg.ID,
+			ServiceID: item.ServiceID,
+			Quantity:  item.Quantity,
+		})
+	}
+	return true
+}
+
+// customerPackageView works out what is left of a package and whether it can still be used
+func customerPackageView(p models.CustomerPackage, now time.Time) CustomerPackageView {
+	view := CustomerPackageView{
+		ID:          p.ID,
+		PackageID:   p.PackageID,
+		InvoiceID:   p.InvoiceID,
+		Name:        p.Name,
+		Kind:        p.Kind,
+		PurchasedAt: p.PurchasedAt,
+		ExpiresAt:   p.ExpiresAt,
+		Status:      p.Status,
+	}
+
+	usable := false
+	for _, credit := range p.Credits {
+		cv := CustomerPackageCreditView{
+			ServiceID:   credit.ServiceID,
+			ServiceName: credit.ServiceName,
+			Quantity:    credit.Quantity,
+			Used:        credit.Used,
+			Unlimited:   credit.Quantity == 0,
+		}
+		if !cv.Unlimited {
+			cv.Remaining = credit.Quantity - credit.Used
+		}
+		if cv.Unlimited || cv.Remaining > 0 {
+			usable = true
+		}
+		view.Credits = append(view.Credits, cv)
+	}
+	sort.Slice(view.Credits, func(i, j int) bool { return view.Credits[i].ServiceName < view.Credits[j].ServiceName })
+
+	if p.ExpiresAt != nil {
+		daysLeft := int(p.ExpiresAt.Sub(now).Hours() / 24)
+		if daysLeft < 0 {
+			daysLeft = 0
+		}
+		view.DaysLeft = &daysLeft
+	}
+
+	if view.Status == PackageActive {
+		switch {
+		case p.ExpiresAt != nil && !p.ExpiresAt.After(now):
+			view.Status = PackageExpired
+		case !usable:
+			view.Status = PackageUsed
+		}
+	}
+	return view
+}
+
+// applyPackageCredits bills service lines against the customer's packages. Covered units are
+// split onto their own zero-priced line that records the package paying for them; packages
+// closest to expiry are used first. The credits are only reserved later by consumePackageCredits.
+func applyPackageCredits(db *gorm.DB, invoice *models.Invoice, now time.Time) error {
+	var credits []struct {
+		CustomerPackageID uuid.UUID
+		ServiceID         uuid.UUID
+		Quantity          int
+		Used              int
+	}
+	if err := db.Table("customer_package_credits").
+		Select("customer_package_credits.customer_package_id, customer_package_credits.service_id, customer_package_credits.quantity, customer_package_credits.used").
+		Joins("JOIN customer_packages cp ON cp.id = customer_package_credits.customer_package_id").
+		Where("cp.salon_id = ? AND cp.customer_id = ? AND cp.status = ?", invoice.SalonID, invoice.CustomerID, PackageActive).
+		Where("cp.expires_at IS NULL OR cp.expires_at > ?", now).
+		Where("customer_package_credits.quantity = 0 OR customer_package_credits.used < customer_package_credits.quantity").
+		Order("cp.expires_at NULLS LAST, cp.purchased_at").
+		Scan(&credits).Error; err != nil {
+		return err
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+
+	// Units still available per credit; -1 is unlimited
+	remaining := make([]int, len(credits))
+	for i, credit := range credits {
+		remaining[i] = -1
+		if credit.Quantity > 0 {
+			remaining[i] = credit.Quantity - credit.Used
+		}
+	}
+
+	var items []models.InvoiceItem
+	for _, item := range invoice.Items {
+		if item.ItemType == ItemTypePackage || item.ServiceID == nil {
+			items = append(items, item)
+			continue
+		}
+
+		for i, credit := range credits {
+			if item.Quantity == 0 {
+				break
+			}
+			if credit.ServiceID != *item.ServiceID || remaining[i] == 0 {
+				continue
+			}
+
+			covered := item.Quantity
+			if remaining[i] > 0 && remaining[i] < covered {
+				covered = remaining[i]
+			}
+			if remaining[i] > 0 {
+				remaining[i] -= covered
+			}
+
+			line := item
+			line.ID = uuid.New()
+			line.Quantity = covered
+			line.UnitPrice = 0
+			line.TotalPrice = 0
+			line.CustomerPackageID = &credits[i].CustomerPackageID
+			line.Consumptions, item.Consumptions = splitConsumptions(item.Consumptions, covered, item.Quantity)
+			items = append(items, line)
+
+			item.Quantity -= covered
+			item.TotalPrice = item.UnitPrice * models.Money(item.Quantity)
+		}
+
+		if item.Quantity > 0 {
+			items = append(items, item)
+		}
+	}
+
+	invoice.Items = items
+	invoice.Subtotal = 0
+	for _, item := range items {
+		invoice.Subtotal += item.TotalPrice
+	}
+	return nil
+}
+
+// consumePackageCredits reserves the credits used by an invoice's package-covered lines.
+// The conditional update makes a credit that was used up concurrently fail instead of overdraw.
+func consumePackageCredits(tx *gorm.DB, items []models.InvoiceItem) error {
+	for _, item := range items {
+		if item.CustomerPackageID == nil {
+			continue
+		}
+		result := tx.Model(&models.CustomerPackageCredit{}).
+			Where("customer_package_id = ? AND service_id = ?", *item.CustomerPackageID, *item.ServiceID).
+			Where("quantity = 0 OR used + ? <= quantity", item.Quantity).
+			Update("used", gorm.Expr("used + ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errPackageCreditsChanged
+		}
+	}
+	return nil
+}
+
+// releasePackageCredits gives back the credits still held by package-covered lines, for
+// units that were not already returned by a refund
+func releasePackageCredits(tx *gorm.DB, items []models.InvoiceItem) error {
+	for _, item := range items {
+		units := item.Quantity - item.RefundedQuantity
+		if item.CustomerPackageID == nil || units <= 0 {
+			continue
+		}
+		if err := tx.Model(&models.CustomerPackageCredit{}).
+			Where("customer_package_id = ? AND service_id = ?", *item.CustomerPackageID, *item.ServiceID).
+			Update("used", gorm.Expr("GREATEST(used - ?, 0)", units)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issueCustomerPackages gives the customer the packages sold on a finalized invoice, one per
+// unit sold. Validity runs from the moment of sale.
+func issueCustomerPackages(tx *gorm.DB, invoice *models.Invoice) error {
+	items := invoice.Items
+	if len(items) == 0 {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		if item.ItemType != ItemTypePackage || item.PackageID == nil {
+			continue
+		}
+
+		var pkg models.ServicePackage
+		if err := tx.Preload("Items").First(&pkg, "id = ?", *item.PackageID).Error; err != nil {
+			return err
+		}
+
+		serviceNames := make(map[uuid.UUID]string)
+		var serviceIDs []uuid.UUID
+		for _, pi := range pkg.Items {
+			serviceIDs = append(serviceIDs, pi.ServiceID)
+		}
+		var services []models.Service
+		if len(serviceIDs) > 0 {
+			if err := tx.Select("id", "name").Where("id IN ?", serviceIDs).Find(&services).Error; err != nil {
+				return err
+			}
+		}
+		for _, s := range services {
+			serviceNames[s.ID] = s.Name
+		}
+
+		var expiresAt *time.Time
+		if pkg.ValidityDays > 0 {
+			expiry := now.AddDate(0, 0, pkg.ValidityDays)
+			expiresAt = &expiry
+		}
+
+		for n := 0; n < item.Quantity; n++ {
+			customerPackage := models.CustomerPackage{
+				ID:            uuid.New(),
+				SalonID:       invoice.SalonID,
+				CustomerID:    invoice.CustomerID,
+				PackageID:     pkg.ID,
+				InvoiceID:     invoice.ID,
+				InvoiceItemID: item.ID,
+				Name:          pkg.Name,
+				Kind:          pkg.Kind,
+				PurchasedAt:   now,
+				ExpiresAt:     expiresAt,
+				Status:        PackageActive,
+			}
+			for _, pi := range pkg.Items {
+				customerPackage.Credits = append(customerPackage.Credits, models.CustomerPackageCredit{
+					ID:                uuid.New(),
+					CustomerPackageID: customerPackage.ID,
+					ServiceID:         pi.ServiceID,
+					ServiceName:       serviceNames[pi.ServiceID],
+					Quantity:          pi.Quantity,
+				})
+			}
+			if err := tx.Create(&customerPackage).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cancelCustomerPackages cancels up to count active packages sold on an invoice line; count 0
+// cancels all of them. Credits already used stay used.
+func cancelCustomerPackages(tx *gorm.DB, invoiceItemID uuid.UUID, count int) error {
+	query := tx.Model(&models.CustomerPackage{}).Select("id").
+		Where("invoice_item_id = ? AND status = ?", invoiceItemID, PackageActive).
+		Order("purchased_at DESC")
+	if count > 0 {
+		query = query.Limit(count)
+	}
+
+	var ids []uuid.UUID
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&models.CustomerPackage{}).Where("id IN ?", ids).Update("status", PackageCancelled).Error
+}
+
+// releaseInvoicePackages undoes an invoice's package activity when it is voided or a draft is
+// deleted: packages it sold are cancelled and credits it used are given back.
+func releaseInvoicePackages(tx *gorm.DB, invoice models.Invoice) error {
+	var items []models.InvoiceItem
+	if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.ItemType == ItemTypePackage {
+			if err := cancelCustomerPackages(tx, item.ID, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return releasePackageCredits(tx, items)
+}
+
+// refundPackageLines follows a credit note through to packages: returned package-covered units
+// give their credits back and refunded package sales cancel the packages sold.
+func refundPackageLines(tx *gorm.DB, invoice models.Invoice, creditItems []models.CreditNoteItem) error {
+	itemsByID := make(map[uuid.UUID]models.InvoiceItem, len(invoice.Items))
+	for _, item := range invoice.Items {
+		itemsByID[item.ID] = item
+	}
+
+	for _, credit := range creditItems {
+		item := itemsByID[credit.InvoiceItemID]
+		switch {
+		case item.ItemType == ItemTypePackage:
+			if err := cancelCustomerPackages(tx, item.ID, credit.Quantity); err != nil {
+				return err
+			}
+		case item.CustomerPackageID != nil:
+			item.Quantity, item.RefundedQuantity = credit.Quantity, 0
+			if err := releasePackageCredits(tx, []models.InvoiceItem{item}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+// controllers/payment.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Payment methods that draw on a balance held for the customer
+const (
+	PaymentGiftCard    = "gift_card"    // Reference is the gift card code
+	PaymentStoreCredit = "store_credit" // paid from the customer's wallet
+)
+
+// PaymentInput defines one tender taken against an invoice
+type PaymentInput struct {
+	Amount    models.Money `json:"amount" binding:"required,gt=0"`
+	Method    string       `json:"method" binding:"required,oneof=cash card upi wallet gift_card store_credit"`
+	Reference string       `json:"reference"` // Gift card code when method is gift_card
+	PaidAt    *time.Time   `json:"paidAt"`
+}
+
+// overpaymentError is returned when payments would exceed the invoice total
+type overpaymentError struct {
+	Balance models.Money
+}
+
+func (e overpaymentError) Error() string {
+	return fmt.Sprintf("Payment exceeds the outstanding balance of %s", e.Balance)
+}
+
+// AddInvoicePayment records a payment against an invoice and re-derives its payment status
+func AddInvoicePayment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var input PaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the invoice so concurrent payments cannot both pass the balance check
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if invoice.Status == string(InvoiceVoid) {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Cannot take payments on a void invoice")
+		return
+	}
+	if err := tx.Where("invoice_id = ?", invoice.ID).Order("paid_at").
+		Find(&invoice.Payments).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	takenBy := uuid.Must(uuid.Parse(userID.(string)))
+	payments, err := buildPayments(&invoice, []PaymentInput{input}, takenBy)
+	if err != nil {
+		tx.Rollback()
+		respondPaymentError(c, err)
+		return
+	}
+
+	if err := tx.Create(&payments).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment")
+		return
+	}
+	if err := applyStoredValuePayments(tx, invoice, payments); err != nil {
+		tx.Rollback()
+		respondPaymentError(c, err)
+		return
+	}
+	invoice.Payments = append(invoice.Payments, payments...)
+	applyPaymentSummary(&invoice)
+
+	if err := saveInvoicePaymentSummary(tx, &invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update invoice")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"payment": payments[0],
+		"invoice": invoice,
+	})
+}
+
+// GetInvoicePayments lists the payments taken against an invoice
+func GetInvoicePayments(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var payments []models.Payment
+	if err := config.DB.Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+		Order("paid_at").Find(&payments).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve payments")
+		return
+	}
+
+	c.JSON(http.StatusOK, payments)
+}
+
+// buildPayments validates new tenders against the invoice's outstanding balance and
+// returns them ready to insert. invoice.Payments must hold the payments already taken.
+func buildPayments(invoice *models.Invoice, inputs []PaymentInput, takenBy uuid.UUID) ([]models.Payment, error) {
+	balance := invoice.Total - invoice.RefundedAmount
+	for _, p := range invoice.Payments {
+		balance -= p.Amount
+	}
+
+	payments := make([]models.Payment, 0, len(inputs))
+	for _, input := range inputs {
+		amount := input.Amount
+		if amount > balance {
+			return nil, overpaymentError{Balance: balance}
+		}
+		if input.Method == PaymentGiftCard && strings.TrimSpace(input.Reference) == "" {
+			return nil, giftCardError{message: "reference must be the gift card code"}
+		}
+		balance -= amount
+
+		paidAt := time.Now()
+		if input.PaidAt != nil {
+			paidAt = *input.PaidAt
+		}
+
+		payments = append(payments, models.Payment{
+			ID:            uuid.New(),
+			SalonID:       invoice.SalonID,
+			InvoiceID:     invoice.ID,
+			Amount:        amount,
+			Method:        input.Method,
+			Reference:     input.Reference,
+			PaidAt:        paidAt,
+			TakenByUserID: takenBy,
+		})
+	}
+
+	return payments, nil
+}
+
+// applyStoredValuePayments draws gift card and wallet tenders from their balances once the
+// payments are saved. It must run inside the caller's transaction.
+func applyStoredValuePayments(tx *gorm.DB, invoice models.Invoice, payments []models.Payment) error {
+	now := time.Now()
+	for _, p := range payments {
+		switch p.Method {
+		case PaymentGiftCard:
+			if err := redeemGiftCard(tx, invoice, p, now); err != nil {
+				return err
+			}
+		case PaymentStoreCredit:
+			if err := payFromWallet(tx, invoice, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyPaymentSummary derives PaidAmount, PaymentStatus and PaymentMethod from invoice.Payments.
+// PaymentMethod is the single tender used, or "split" when there were several. Refund payouts
+// (negative entries) reduce PaidAmount but do not count as a tender.
+func applyPaymentSummary(invoice *models.Invoice) {
+	var paid models.Money
+	method := ""
+	for _, p := range invoice.Payments {
+		paid += p.Amount
+		if p.Amount < 0 {
+			continue
+		}
+		if method == "" {
+			method = p.Method
+		} else if method != p.Method {
+			method = "split"
+		}
+	}
+
+	invoice.PaidAmount = paid
+	invoice.PaymentMethod = method
+	if invoice.RefundedAmount > 0 && invoice.RefundedAmount >= invoice.Total {
+		invoice.PaymentStatus = "refunded"
+	} else {
+		invoice.PaymentStatus = derivePaymentStatus(paid, invoice.Total-invoice.RefundedAmount)
+	}
+}
+
+// derivePaymentStatus maps the amount paid against the total to unpaid, partial or paid
+func derivePaymentStatus(paid, total models.Money) string {
+	switch {
+	case paid <= 0:
+		return "unpaid"
+	case paid >= total:
+		return "paid"
+	default:
+		return "partial"
+	}
+}
+
+// saveInvoicePaymentSummary writes the derived payment and refund columns of an invoice
+func saveInvoicePaymentSummary(tx *gorm.DB, invoice *models.Invoice) error {
+	return tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).
+		Updates(map[string]interface{}{
+			"paid_amount":     invoice.PaidAmount,
+			"payment_status":  invoice.PaymentStatus,
+			"payment_method":  invoice.PaymentMethod,
+			"refunded_amount": invoice.RefundedAmount,
+		}).Error
+}
+
+// respondPaymentError maps payment validation errors to a response
+func respondPaymentError(c *gin.Context, err error) {
+	var overpaid overpaymentError
+	if errors.As(err, &overpaid) {
+		utils.RespondWithError(c, http.StatusBadRequest, overpaid.Error())
+		return
+	}
+	var cardErr giftCardError
+	if errors.As(err, &cardErr) {
+		utils.RespondWithError(c, http.StatusBadRequest, cardErr.Error())
+		return
+	}
+	var walletErr insufficientWalletError
+	if errors.As(err, &walletErr) {
+		utils.RespondWithError(c, http.StatusBadRequest, walletErr.Error())
+		return
+	}
+	utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+}
@@ -0,0 +1,714 @@
+// controllers/product.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stock movement types
+const (
+	StockOpening     = "opening"     // stock a product was created with
+	StockSale        = "sale"        // sold on a finalized invoice
+	StockConsumption = "consumption" // used at the back bar by a service on a finalized invoice
+	StockReturn      = "return"      // refunded through a credit note and put back on the shelf
+	StockVoid        = "void"        // put back when the invoice that sold it was voided
+	StockAdjustment  = "adjustment"  // counted, damaged or received by hand
+)
+
+// ProductInput defines the expected JSON structure for creating or replacing a product.
+// StockQuantity is only used when the product is created; later changes go through adjustments.
+type ProductInput struct {
+	Name              string       `json:"name" binding:"required"`
+	Description       string       `json:"description"`
+	Brand             string       `json:"brand"`
+	Category          string       `json:"category"`
+	SKU               string       `json:"sku" binding:"max=50"`
+	Barcode           string       `json:"barcode" binding:"max=50"`
+	CostPrice         models.Money `json:"costPrice" binding:"min=0"`
+	SalePrice         models.Money `json:"salePrice" binding:"min=0"`
+	TaxClassID        *uuid.UUID   `json:"taxClassId"`
+	StockQuantity     float64      `json:"stockQuantity" binding:"min=0"`
+	LowStockThreshold float64      `json:"lowStockThreshold" binding:"min=0"` // 0 no alert
+	UsageUnit         string       `json:"usageUnit" binding:"max=10"`        // Unit services use the product in, e.g. ml
+	UnitSize          float64      `json:"unitSize" binding:"min=0"`          // Usage units in one stock unit; 0 services count stock units
+	IsActive          *bool        `json:"isActive"`
+}
+
+// StockAdjustmentInput defines the expected JSON structure for correcting a product's stock
+type StockAdjustmentInput struct {
+	Quantity float64      `json:"quantity" binding:"required,ne=0"` // Positive adds stock, negative removes it
+	UnitCost models.Money `json:"unitCost" binding:"min=0"`         // Defaults to the product's cost price
+	Reason   string       `json:"reason" binding:"required"`
+}
+
+// LowStockProduct is a product at or below its low-stock threshold
+type LowStockProduct struct {
+	ID                uuid.UUID `json:"id"`
+	Name              string    `json:"name"`
+	SKU               string    `json:"sku"`
+	StockQuantity     float64   `json:"stockQuantity"`
+	LowStockThreshold float64   `json:"lowStockThreshold"`
+}
+
+// productNotFoundError is returned when an invoice line sells a product the salon does not stock
+type productNotFoundError struct {
+	ProductID uuid.UUID
+}
+
+func (e productNotFoundError) Error() string {
+	return "Product not found: " + e.ProductID.String()
+}
+
+// insufficientStockError is returned when a sale or adjustment would take stock below zero
+type insufficientStockError struct {
+	Name      string
+	Available float64
+}
+
+func (e insufficientStockError) Error() string {
+	return fmt.Sprintf("Only %g of %s in stock", e.Available, e.Name)
+}
+
+// GetProducts lists the salon's products. ?active=true hides retired ones and ?search= matches
+// the name, SKU or barcode.
+func GetProducts(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		like := "%" + search + "%"
+		query = query.Where("name ILIKE ? OR sku = ? OR barcode = ?", like, search, search)
+	}
+
+	var products []models.Product
+	if err := query.Order("name").Find(&products).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve products")
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// GetProduct returns a single product
+func GetProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// LookupProductBarcode finds an active product by its barcode or SKU, for scanning at the counter
+func LookupProductBarcode(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	code := strings.TrimSpace(c.Param("code"))
+
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND is_active = ? AND (barcode = ? OR sku = ?)", salonUUID, true, code, code).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// CreateProduct adds a product to the catalog with its opening stock
+func CreateProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage products", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input ProductInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	product := models.Product{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+	}
+	if !applyProductInput(c, salonUUID, &product, input) {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&product).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create product")
+		return
+	}
+
+	if input.StockQuantity > 0 {
+		userUUID := uuid.Must(uuid.Parse(userID.(string)))
+		if _, err := addStockMovement(tx, models.StockMovement{
+			SalonID:         salonUUID,
+			ProductID:       product.ID,
+			Type:            StockOpening,
+			Quantity:        input.StockQuantity,
+			Reason:          "Opening stock",
+			CreatedByUserID: &userUUID,
+		}); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record opening stock")
+			return
+		}
+		product.StockQuantity = input.StockQuantity
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// UpdateProduct replaces a product's details. Stock is changed through adjustments, not here.
+func UpdateProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage products", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input ProductInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applyProductInput(c, salonUUID, &product, input) {
+		return
+	}
+
+	// The stock column is left alone so a concurrent sale is not overwritten
+	if err := config.DB.Model(&product).Omit("stock_quantity").Select("*").Updates(&product).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update product")
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct removes a product that was never sold. Sold products are retired with isActive=false instead.
+func DeleteProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage products", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var sold int64
+	if err := config.DB.Model(&models.InvoiceItem{}).Where("product_id = ?", productUUID).Count(&sold).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if sold > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Product has been sold; deactivate it instead")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, productUUID).Delete(&models.Product{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete product")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	if err := tx.Where("product_id = ?", productUUID).Delete(&models.StockMovement{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete stock history")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
+
+// GetLowStockProducts lists active products at or below their low-stock threshold, emptiest first
+func GetLowStockProducts(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	products, err := lowStockProducts(config.DB, salonUUID, 0)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve low-stock products")
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// GetStockMovements returns a product's stock history, newest first
+func GetStockMovements(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var movements []models.StockMovement
+	if err := config.DB.Where("salon_id = ? AND product_id = ?", salonUUID, product.ID).
+		Order("created_at DESC").Find(&movements).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve stock history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"productId":     product.ID,
+		"stockQuantity": product.StockQuantity,
+		"movements":     movements,
+	})
+}
+
+// AdjustStock records a manual stock correction such as a stock count, damage or a delivery
+func AdjustStock(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can adjust stock", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input StockAdjustmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonUUID, productUUID).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	userUUID := uuid.Must(uuid.Parse(userID.(string)))
+	movement, err := addStockMovement(tx, models.StockMovement{
+		SalonID:         salonUUID,
+		ProductID:       product.ID,
+		Type:            StockAdjustment,
+		Quantity:        input.Quantity,
+		UnitCost:        input.UnitCost,
+		Reason:          input.Reason,
+		CreatedByUserID: &userUUID,
+	})
+	if err != nil {
+		tx.Rollback()
+		var insufficient insufficientStockError
+		if errors.As(err, &insufficient) {
+			utils.RespondWithError(c, http.StatusBadRequest, insufficient.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to adjust stock")
+		}
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// applyProductInput validates input and copies it onto product. On failure it writes the error response.
+func applyProductInput(c *gin.Context, salonID uuid.UUID, product *models.Product, input ProductInput) bool {
+	if input.TaxClassID != nil {
+		if err := checkTaxClass(config.DB, salonID, *input.TaxClassID); err != nil {
+			respondTaxClassError(c, err)
+			return false
+		}
+	}
+
+	// SKUs and barcodes identify a product when it is scanned, so they cannot be shared
+	sku := strings.TrimSpace(input.SKU)
+	barcode := strings.TrimSpace(input.Barcode)
+	for _, code := range []string{sku, barcode} {
+		if code == "" {
+			continue
+		}
+		var taken int64
+		if err := config.DB.Model(&models.Product{}).
+			Where("salon_id = ? AND id <> ? AND (sku = ? OR barcode = ?)", salonID, product.ID, code, code).
+			Count(&taken).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return false
+		}
+		if taken > 0 {
+			utils.RespondWithError(c, http.StatusConflict, "Another product already uses the code "+code)
+			return false
+		}
+	}
+
+	category := strings.TrimSpace(input.Category)
+	if category == "" {
+		category = "Retail"
+	}
+
+	product.Name = strings.TrimSpace(input.Name)
+	product.Description = input.Description
+	product.Brand = input.Brand
+	product.Category = category
+	product.SKU = sku
+	product.Barcode = barcode
+	product.CostPrice = input.CostPrice
+	product.SalePrice = input.SalePrice
+	product.TaxClassID = input.TaxClassID
+	product.LowStockThreshold = input.LowStockThreshold
+	product.UsageUnit = strings.TrimSpace(input.UsageUnit)
+	product.UnitSize = input.UnitSize
+	product.IsActive = input.IsActive == nil || *input.IsActive
+	return true
+}
+
+// lowStockProducts returns active products at or below their threshold, emptiest first; limit 0 returns all
+func lowStockProducts(db *gorm.DB, salonID uuid.UUID, limit int) ([]LowStockProduct, error) {
+	products := []LowStockProduct{}
+	query := db.Model(&models.Product{}).
+		Select("id", "name", "sku", "stock_quantity", "low_stock_threshold").
+		Where("salon_id = ? AND is_active = ? AND low_stock_threshold > 0 AND stock_quantity <= low_stock_threshold", salonID, true).
+		Order("stock_quantity, name")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&products).Error
+	return products, err
+}
+
+// addStockMovement records a stock change and moves the product's stock with it. The product row
+// is locked so concurrent sales cannot both take the last unit; taking stock below zero fails with
+// insufficientStockError. Back-bar consumption is the exception: it never blocks billing, and a
+// negative balance shows the stock needs counting. UnitCost defaults to the product's cost price.
+func addStockMovement(tx *gorm.DB, movement models.StockMovement) (models.StockMovement, error) {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "name", "stock_quantity", "cost_price").
+		First(&product, "id = ?", movement.ProductID).Error; err != nil {
+		return movement, err
+	}
+
+	movement.Quantity = roundStock(movement.Quantity)
+	balance := roundStock(product.StockQuantity + movement.Quantity)
+	if balance < 0 && movement.Quantity < 0 && movement.Type != StockConsumption {
+		return movement, insufficientStockError{Name: product.Name, Available: product.StockQuantity}
+	}
+
+	movement.ID = uuid.New()
+	movement.Balance = balance
+	if movement.UnitCost == 0 {
+		movement.UnitCost = product.CostPrice
+	}
+	if err := tx.Model(&product).Update("stock_quantity", movement.Balance).Error; err != nil {
+		return movement, err
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return movement, err
+	}
+	return movement, nil
+}
+
+// deductInvoiceStock takes the products sold on a finalized invoice, and those its services used
+// at the back bar, out of stock
+func deductInvoiceStock(tx *gorm.DB, invoice *models.Invoice) error {
+	items := invoice.Items
+	if len(items) == 0 {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		if item.ProductID == nil {
+			continue
+		}
+		if _, err := addStockMovement(tx, models.StockMovement{
+			SalonID:         invoice.SalonID,
+			ProductID:       *item.ProductID,
+			Type:            StockSale,
+			Quantity:        -float64(item.Quantity),
+			InvoiceID:       &invoice.ID,
+			InvoiceItemID:   &item.ID,
+			Reason:          "Invoice " + invoice.InvoiceNumber,
+			CreatedByUserID: &invoice.CreatedByUserID,
+		}); err != nil {
+			return err
+		}
+	}
+	return consumeInvoiceProducts(tx, invoice, items)
+}
+
+// restoreInvoiceStock puts back whatever a voided invoice still has out of stock, sold or used
+// at the back bar, after any units already returned through refunds
+func restoreInvoiceStock(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	var rows []struct {
+		ProductID     uuid.UUID
+		InvoiceItemID uuid.UUID
+		Quantity      float64
+	}
+	if err := tx.Model(&models.StockMovement{}).
+		Select("product_id, invoice_item_id, SUM(quantity) AS quantity").
+		Where("invoice_id = ?", invoice.ID).
+		Group("product_id, invoice_item_id").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if row.Quantity >= 0 {
+			continue
+		}
+		itemID := row.InvoiceItemID
+		if _, err := addStockMovement(tx, models.StockMovement{
+			SalonID:         invoice.SalonID,
+			ProductID:       row.ProductID,
+			Type:            StockVoid,
+			Quantity:        -row.Quantity,
+			InvoiceID:       &invoice.ID,
+			InvoiceItemID:   &itemID,
+			Reason:          "Invoice " + invoice.InvoiceNumber + " voided",
+			CreatedByUserID: &userID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restockRefundLines puts refunded products back in stock
+func restockRefundLines(tx *gorm.DB, invoice models.Invoice, creditNote models.CreditNote) error {
+	itemsByID := make(map[uuid.UUID]models.InvoiceItem, len(invoice.Items))
+	for _, item := range invoice.Items {
+		itemsByID[item.ID] = item
+	}
+
+	for _, credit := range creditNote.Items {
+		item := itemsByID[credit.InvoiceItemID]
+		if item.ProductID == nil {
+			continue
+		}
+		if _, err := addStockMovement(tx, models.StockMovement{
+			SalonID:         invoice.SalonID,
+			ProductID:       *item.ProductID,
+			Type:            StockReturn,
+			Quantity:        float64(credit.Quantity),
+			InvoiceID:       &invoice.ID,
+			InvoiceItemID:   &item.ID,
+			CreditNoteID:    &creditNote.ID,
+			Reason:          "Credit note " + creditNote.CreditNoteNumber,
+			CreatedByUserID: &creditNote.CreatedByUserID,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundStock keeps stock quantities to the three decimals they are stored with
+func roundStock(quantity float64) float64 {
+	return math.Round(quantity*1000) / 1000
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"salonpro-backend/config"
 	"salonpro-backend/models"
@@ -10,6 +12,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UpdateProfileInput struct {
@@ -47,6 +51,12 @@ func GetProfile(c *gin.Context) {
 		return
 	}
 
+	var logoCount int64
+	if err := config.DB.Model(&models.SalonLogo{}).Where("salon_id = ?", salon.ID).Count(&logoCount).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch salon logo")
+		return
+	}
+
 	// --- Fetch reminder templates ---
 	var reminderTemplates []models.ReminderTemplate
 	if err := config.DB.Where("salon_id = ?", salon.ID).Find(&reminderTemplates).Error; err != nil {
@@ -73,6 +83,7 @@ func GetProfile(c *gin.Context) {
 			"phone":        user.Phone,
 			"email":        user.Email,
 			"workingHours": salon.WorkingHours,
+			"hasLogo":      logoCount > 0,
 		},
 		"messageTemplates": gin.H{
 			"birthday":    birthdayMessage,
@@ -83,6 +94,24 @@ func GetProfile(c *gin.Context) {
 			"anniversaryReminders":  salon.AnniversaryReminders,
 			"whatsAppNotifications": salon.WhatsAppNotifications,
 			"smsNotifications":      salon.SMSNotifications,
+			"emailNotifications":    salon.EmailNotifications,
+			"receiptNotifications":  salon.ReceiptNotifications,
+			"channelOrder":          salon.ChannelOrder(),
+		},
+		"booking": gin.H{
+			"bufferMinutes":       salon.BookingBufferMinutes,
+			"slotIntervalMinutes": salon.SlotIntervalMinutes,
+		},
+		"numbering": gin.H{
+			"code":                   salon.Code,
+			"invoiceNumberFormat":    salon.InvoiceNumberFormat,
+			"creditNoteNumberFormat": salon.CreditNoteNumberFormat,
+			"fiscalYearStartMonth":   salon.FiscalYearStartMonth,
+		},
+		"tax": gin.H{
+			"gstin":            salon.GSTIN,
+			"stateCode":        salon.StateCode,
+			"pricesIncludeTax": salon.PricesIncludeTax,
 		},
 	})
 }
@@ -156,6 +185,98 @@ func UpdateSalonProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
 }
+
+// maxLogoBytes keeps logos small enough to embed in every printed invoice
+const maxLogoBytes = 512 * 1024
+
+// UpdateSalonLogo stores the logo printed on invoices. Expects a multipart "logo" file (JPEG, PNG or GIF).
+func UpdateSalonLogo(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	file, err := c.FormFile("logo")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "logo file is required")
+		return
+	}
+	if file.Size > maxLogoBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "Logo must be 512 KB or smaller")
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Could not read logo")
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxLogoBytes+1))
+	if err != nil || len(data) > maxLogoBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "Could not read logo")
+		return
+	}
+
+	// Make sure it can actually be printed
+	if _, err := utils.NewPDFImage(data); err != nil {
+		if errors.Is(err, utils.ErrImageTooLarge) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Logo "+err.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusBadRequest, "Logo must be a JPEG, PNG or GIF image")
+		}
+		return
+	}
+
+	logo := models.SalonLogo{
+		SalonID:     salonUUID,
+		Data:        data,
+		ContentType: http.DetectContentType(data),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "salon_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "content_type", "updated_at"}),
+	}).Create(&logo).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update logo")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logo updated successfully"})
+}
+
+// GetSalonLogo returns the salon's logo image
+func GetSalonLogo(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var logo models.SalonLogo
+	if err := config.DB.First(&logo, "salon_id = ?", salonUUID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "No logo uploaded")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch logo")
+		}
+		return
+	}
+
+	c.Data(http.StatusOK, logo.ContentType, logo.Data)
+}
+
 type UpdateWorkingHoursInput struct {
 	WorkingHours models.JSONB `json:"workingHours"`
 }
@@ -188,6 +309,151 @@ func UpdateWorkingHours(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Working hours updated successfully"})
 }
 
+type UpdateBookingSettingsInput struct {
+	BufferMinutes       int `json:"bufferMinutes" binding:"min=0"`
+	SlotIntervalMinutes int `json:"slotIntervalMinutes" binding:"required,min=5"`
+}
+
+// UpdateBookingSettings sets the buffer kept between bookings and the step used for offered slots
+func UpdateBookingSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var input UpdateBookingSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if err := config.DB.Model(&models.Salon{}).
+		Where("id = ?", salonUUID).
+		Updates(map[string]interface{}{
+			"booking_buffer_minutes": input.BufferMinutes,
+			"slot_interval_minutes":  input.SlotIntervalMinutes,
+		}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update booking settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Booking settings updated successfully"})
+}
+
+type UpdateNumberingSettingsInput struct {
+	Code                   string `json:"code" binding:"omitempty,alphanum,max=10"`
+	InvoiceNumberFormat    string `json:"invoiceNumberFormat" binding:"required"`
+	CreditNoteNumberFormat string `json:"creditNoteNumberFormat" binding:"required"`
+	FiscalYearStartMonth   int    `json:"fiscalYearStartMonth" binding:"required,min=1,max=12"`
+}
+
+// UpdateNumberingSettings changes how invoice and credit note numbers are formatted.
+// Numbers already issued are kept; the counters continue from where they are.
+func UpdateNumberingSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var input UpdateNumberingSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if err := utils.ValidateNumberFormat(input.InvoiceNumberFormat); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invoice "+err.Error())
+		return
+	}
+	if err := utils.ValidateNumberFormat(input.CreditNoteNumberFormat); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Credit note "+err.Error())
+		return
+	}
+
+	if err := config.DB.Model(&models.Salon{}).
+		Where("id = ?", salonUUID).
+		Updates(map[string]interface{}{
+			"code":                      strings.ToUpper(input.Code),
+			"invoice_number_format":     input.InvoiceNumberFormat,
+			"credit_note_number_format": input.CreditNoteNumberFormat,
+			"fiscal_year_start_month":   input.FiscalYearStartMonth,
+		}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update numbering settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Numbering settings updated successfully"})
+}
+
+type UpdateTaxSettingsInput struct {
+	GSTIN            string `json:"gstin"`
+	StateCode        string `json:"stateCode" binding:"omitempty,len=2,numeric"` // Taken from the GSTIN when registered
+	PricesIncludeTax bool   `json:"pricesIncludeTax"`
+}
+
+// UpdateTaxSettings sets the salon's GST registration and whether service prices include tax.
+// Invoices already issued keep the settings they were priced with.
+func UpdateTaxSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var input UpdateTaxSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	gstin := utils.NormalizeGSTIN(input.GSTIN)
+	stateCode := input.StateCode
+	if gstin != "" {
+		if err := utils.ValidateGSTIN(gstin); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if stateCode != "" && stateCode != gstin[:2] {
+			utils.RespondWithError(c, http.StatusBadRequest, "stateCode does not match the GSTIN")
+			return
+		}
+		stateCode = gstin[:2]
+	} else if stateCode != "" && !utils.ValidGSTStateCode(stateCode) {
+		utils.RespondWithError(c, http.StatusBadRequest, "stateCode must be a two-digit GST state code")
+		return
+	}
+
+	if err := config.DB.Model(&models.Salon{}).
+		Where("id = ?", salonUUID).
+		Updates(map[string]interface{}{
+			"gstin":              gstin,
+			"state_code":         stateCode,
+			"prices_include_tax": input.PricesIncludeTax,
+		}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update tax settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax settings updated successfully"})
+}
+
 type UpdateTemplatesInput struct {
 	BirthdayMessage    string `json:"birthday" form:"birthday" binding:"omitempty"`
 	AnniversaryMessage string `json:"anniversary" form:"anniversary" binding:"omitempty"`
@@ -236,6 +502,11 @@ type UpdateNotificationsInput struct {
 	AnniversaryReminders  bool `json:"anniversaryReminders"`
 	WhatsAppNotifications bool `json:"whatsAppNotifications"`
 	SMSNotifications      bool `json:"smsNotifications"`
+	// Optional so clients that predate receipts do not switch them off
+	ReceiptNotifications *bool `json:"receiptNotifications"`
+	EmailNotifications   *bool `json:"emailNotifications"`
+	// Channels to try in turn, e.g. ["whatsapp", "sms", "email"]; left unchanged when omitted
+	ChannelOrder []string `json:"channelOrder" binding:"omitempty,dive,oneof=whatsapp sms email"`
 }
 
 func UpdateNotifications(c *gin.Context) {
@@ -256,14 +527,33 @@ func UpdateNotifications(c *gin.Context) {
 		return
 	}
 
+	updates := map[string]interface{}{
+		"birthday_reminders":      input.BirthdayReminders,
+		"anniversary_reminders":   input.AnniversaryReminders,
+		"whats_app_notifications": input.WhatsAppNotifications,
+		"sms_notifications":       input.SMSNotifications,
+	}
+	if input.ReceiptNotifications != nil {
+		updates["receipt_notifications"] = *input.ReceiptNotifications
+	}
+	if input.EmailNotifications != nil {
+		updates["email_notifications"] = *input.EmailNotifications
+	}
+	if len(input.ChannelOrder) > 0 {
+		seen := map[string]bool{}
+		for _, channel := range input.ChannelOrder {
+			if seen[channel] {
+				utils.RespondWithError(c, http.StatusBadRequest, "channelOrder lists "+channel+" more than once")
+				return
+			}
+			seen[channel] = true
+		}
+		updates["notification_channel_order"] = strings.Join(input.ChannelOrder, ",")
+	}
+
 	if err := config.DB.Model(&models.Salon{}).
 		Where("id = ?", salonUUID).
-		Updates(map[string]interface{}{
-			"birthday_reminders":      input.BirthdayReminders,
-			"anniversary_reminders":   input.AnniversaryReminders,
-			"whats_app_notifications": input.WhatsAppNotifications,
-			"sms_notifications":       input.SMSNotifications,
-		}).Error; err != nil {
+		Updates(updates).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update notifications")
 		return
 	}
@@ -271,45 +561,60 @@ func UpdateNotifications(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Notification settings updated successfully"})
 }
 
-// TestNotificationInput is the body for sending a test SMS or WhatsApp message.
+// TestNotificationInput is the body for sending a test SMS, WhatsApp or email message.
 type TestNotificationInput struct {
-	Phone   string `json:"phone" binding:"required"`   // E.164 format, e.g. +919799570493
+	Phone   string `json:"phone"`                      // E.164 format, e.g. +919799570493; for sms and whatsapp
+	Email   string `json:"email"`                      // for email
 	Message string `json:"message"`                    // Optional: if empty, uses salon's reminder template body (with [CustomerName] → "Test Customer")
-	Channel string `json:"channel" binding:"required"` // "sms" or "whatsapp"
+	Channel string `json:"channel" binding:"required"` // "sms", "whatsapp" or "email"
 }
 
-// SendTestNotification sends a single test SMS or WhatsApp message (for testing Twilio).
+// SendTestNotification sends a single test SMS, WhatsApp or email message through the configured provider (Twilio or SMTP, or the fake in development).
 // If "message" is omitted or empty, uses the current implementation body from the salon's reminder template (same as real reminders).
-// POST /auth/profile/test-notification with body: { "phone": "+919799570493", "channel": "sms" } or include "message" to override.
+// POST /auth/profile/test-notification with body: { "phone": "+919799570493", "channel": "sms" } or
+// { "email": "me@example.com", "channel": "email" }, or include "message" to override.
 func SendTestNotification(c *gin.Context) {
 	var input TestNotificationInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: phone and channel are required")
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: channel is required")
 		return
 	}
 	channel := strings.ToLower(strings.TrimSpace(input.Channel))
-	if channel != "sms" && channel != "whatsapp" {
-		utils.RespondWithError(c, http.StatusBadRequest, "channel must be 'sms' or 'whatsapp'")
+	var to string
+	switch channel {
+	case "sms", "whatsapp":
+		if to = strings.TrimSpace(input.Phone); to == "" {
+			utils.RespondWithError(c, http.StatusBadRequest, "phone is required (E.164 format, e.g. +919799570493)")
+			return
+		}
+	case "email":
+		if to = strings.TrimSpace(input.Email); to == "" {
+			utils.RespondWithError(c, http.StatusBadRequest, "email is required")
+			return
+		}
+	default:
+		utils.RespondWithError(c, http.StatusBadRequest, "channel must be 'sms', 'whatsapp' or 'email'")
+		return
+	}
+
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
 		return
 	}
-	phone := strings.TrimSpace(input.Phone)
-	if phone == "" {
-		utils.RespondWithError(c, http.StatusBadRequest, "phone is required (E.164 format, e.g. +919799570493)")
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Salon not found")
 		return
 	}
 
 	body := strings.TrimSpace(input.Message)
 	if body == "" {
-		salonID, exists := c.Get("salonId")
-		if !exists {
-			utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
-			return
-		}
-		salonUUID, err := uuid.Parse(salonID.(string))
-		if err != nil {
-			utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
-			return
-		}
 		var templates []models.ReminderTemplate
 		if err := config.DB.Where("salon_id = ? AND is_active = true", salonUUID).Find(&templates).Error; err != nil {
 			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch reminder templates")
@@ -336,14 +641,15 @@ func SendTestNotification(c *gin.Context) {
 	}
 
 	svc := services.NewReminderService(config.DB)
-	if err := svc.SendTestMessage(phone, body, channel); err != nil {
+	if err := svc.SendTestMessage(salon, to, body, channel); err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to send test notification: "+err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Test " + channel + " sent successfully",
-		"channel": channel,
-		"phone":   phone,
-		"body":    body,
+		"message":  "Test " + channel + " sent successfully",
+		"channel":  channel,
+		"provider": svc.Provider(),
+		"to":       to,
+		"body":     body,
 	})
 }
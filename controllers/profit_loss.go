@@ -0,0 +1,210 @@
+// controllers/profit_loss.go
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxProfitAndLossMonths bounds the month-by-month breakdown
+const maxProfitAndLossMonths = 24
+
+// ExpenseCategoryTotal is what one expense category cost over a period, net of claimable GST
+type ExpenseCategoryTotal struct {
+	Category string       `json:"category"`
+	Amount   models.Money `json:"amount"`
+}
+
+// ProfitAndLoss is the salon's income statement for a period. All figures exclude GST.
+type ProfitAndLoss struct {
+	Sales             models.Money           `json:"sales"`   // finalized invoices, less discounts; gift card sales are prepayments and excluded
+	Refunds           models.Money           `json:"refunds"` // credit notes issued in the period
+	NetRevenue        models.Money           `json:"netRevenue"`
+	ProductCost       models.Money           `json:"productCost"` // retail stock sold and back-bar product used, at cost
+	GrossProfit       models.Money           `json:"grossProfit"`
+	Commissions       models.Money           `json:"commissions"`
+	Expenses          []ExpenseCategoryTotal `json:"expenses"` // operating expenses by category
+	OperatingExpenses models.Money           `json:"operatingExpenses"`
+	NetProfit         models.Money           `json:"netProfit"`
+	NetMarginPercent  float64                `json:"netMarginPercent"`
+	ProductPurchases  models.Money           `json:"productPurchases"` // stock bought; already counted in ProductCost as it is sold or used
+}
+
+// ProfitAndLossMonth is one calendar month of the breakdown, with growth over the month before
+type ProfitAndLossMonth struct {
+	Month             string       `json:"month"` // YYYY-MM
+	NetRevenue        models.Money `json:"netRevenue"`
+	GrossProfit       models.Money `json:"grossProfit"`
+	OperatingExpenses models.Money `json:"operatingExpenses"`
+	NetProfit         models.Money `json:"netProfit"`
+	RevenueGrowth     float64      `json:"revenueGrowth"`
+	ExpenseGrowth     float64      `json:"expenseGrowth"`
+	NetProfitGrowth   float64      `json:"netProfitGrowth"`
+}
+
+// GetProfitAndLoss combines invoice revenue, refunds, product cost, commissions and expenses into
+// a profit-and-loss statement for the period, compares it with the period of the same length just
+// before, and breaks it down by calendar month with month-over-month growth.
+// GET /api/reports/profit-loss?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (rc *ReportController) GetProfitAndLoss(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can view profit and loss", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	firstMonth := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	lastDay := to.AddDate(0, 0, -1)
+	months := (lastDay.Year()-firstMonth.Year())*12 + int(lastDay.Month()-firstMonth.Month()) + 1
+	if months > maxProfitAndLossMonths {
+		utils.RespondWithError(c, http.StatusBadRequest, "Period must not span more than 24 months")
+		return
+	}
+
+	current, err := rc.profitAndLoss(config.DB, salonUUID, from, to)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate profit and loss")
+		return
+	}
+
+	previousFrom := from.AddDate(0, 0, -utils.DaysBetween(from, to))
+	previous, err := rc.profitAndLoss(config.DB, salonUUID, previousFrom, from)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate profit and loss")
+		return
+	}
+
+	// The month before the first one is only there to work out its growth
+	breakdown := []ProfitAndLossMonth{}
+	var before ProfitAndLoss
+	for i := -1; i < months; i++ {
+		start := firstMonth.AddDate(0, i, 0)
+		month, err := rc.profitAndLoss(config.DB, salonUUID, start, start.AddDate(0, 1, 0))
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate profit and loss")
+			return
+		}
+		if i >= 0 {
+			breakdown = append(breakdown, ProfitAndLossMonth{
+				Month:             start.Format("2006-01"),
+				NetRevenue:        month.NetRevenue,
+				GrossProfit:       month.GrossProfit,
+				OperatingExpenses: month.OperatingExpenses,
+				NetProfit:         month.NetProfit,
+				RevenueGrowth:     rc.calculateGrowthPercentage(month.NetRevenue, before.NetRevenue),
+				ExpenseGrowth:     rc.calculateGrowthPercentage(month.OperatingExpenses, before.OperatingExpenses),
+				NetProfitGrowth:   rc.calculateGrowthPercentage(month.NetProfit, before.NetProfit),
+			})
+		}
+		before = month
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":              from.Format("2006-01-02"),
+		"to":                lastDay.Format("2006-01-02"),
+		"current":           current,
+		"previousFrom":      previousFrom.Format("2006-01-02"),
+		"previousTo":        from.AddDate(0, 0, -1).Format("2006-01-02"),
+		"previous":          previous,
+		"revenueGrowth":     rc.calculateGrowthPercentage(current.NetRevenue, previous.NetRevenue),
+		"grossProfitGrowth": rc.calculateGrowthPercentage(current.GrossProfit, previous.GrossProfit),
+		"expenseGrowth":     rc.calculateGrowthPercentage(current.OperatingExpenses, previous.OperatingExpenses),
+		"netProfitGrowth":   rc.calculateGrowthPercentage(current.NetProfit, previous.NetProfit),
+		"months":            breakdown,
+	})
+}
+
+// profitAndLoss builds the statement for the half-open range [from, to)
+func (rc *ReportController) profitAndLoss(db *gorm.DB, salonID uuid.UUID, from, to time.Time) (ProfitAndLoss, error) {
+	var pl ProfitAndLoss
+
+	if err := db.Raw(`
+		SELECT COALESCE(SUM(ii.taxable_value), 0)
+		FROM invoice_items ii
+		JOIN invoices i ON i.id = ii.invoice_id
+		WHERE i.salon_id = ? AND i.status = 'finalized' AND ii.item_type <> ?
+			AND i.invoice_date >= ? AND i.invoice_date < ?`,
+		salonID, ItemTypeGiftCard, from, to).Scan(&pl.Sales).Error; err != nil {
+		return pl, err
+	}
+
+	// Refunded amounts include GST; scale each line back to its taxable value
+	if err := db.Raw(`
+		SELECT COALESCE(ROUND(SUM(cni.amount * ii.taxable_value / NULLIF(ii.line_total, 0)), 2), 0)
+		FROM credit_note_items cni
+		JOIN credit_notes cn ON cn.id = cni.credit_note_id
+		JOIN invoice_items ii ON ii.id = cni.invoice_item_id
+		WHERE cn.salon_id = ? AND ii.item_type <> ?
+			AND cn.credit_note_date >= ? AND cn.credit_note_date < ?`,
+		salonID, ItemTypeGiftCard, from, to).Scan(&pl.Refunds).Error; err != nil {
+		return pl, err
+	}
+
+	// Stock that left the shelf, less what came back from returns and voids
+	if err := db.Raw(`
+		SELECT COALESCE(ROUND(-SUM(quantity * unit_cost), 2), 0)
+		FROM stock_movements
+		WHERE salon_id = ? AND type IN ? AND created_at >= ? AND created_at < ?`,
+		salonID, []string{StockSale, StockConsumption, StockReturn, StockVoid}, from, to).Scan(&pl.ProductCost).Error; err != nil {
+		return pl, err
+	}
+
+	statements, err := calculateCommissions(db, salonID, from, to, nil)
+	if err != nil {
+		return pl, err
+	}
+	for _, statement := range statements {
+		pl.Commissions += statement.Commission
+	}
+
+	var categories []ExpenseCategoryTotal
+	if err := db.Model(&models.Expense{}).
+		Select("category, SUM(amount - tax_amount) AS amount").
+		Where("salon_id = ? AND expense_date >= ? AND expense_date < ?", salonID, from, to).
+		Group("category").Order("amount DESC").Scan(&categories).Error; err != nil {
+		return pl, err
+	}
+
+	pl.Expenses = []ExpenseCategoryTotal{}
+	for _, category := range categories {
+		if category.Category == ExpenseProductPurchases {
+			pl.ProductPurchases = category.Amount
+			continue
+		}
+		pl.Expenses = append(pl.Expenses, category)
+		pl.OperatingExpenses += category.Amount
+	}
+
+	pl.NetRevenue = pl.Sales - pl.Refunds
+	pl.GrossProfit = pl.NetRevenue - pl.ProductCost
+	pl.NetProfit = pl.GrossProfit - pl.Commissions - pl.OperatingExpenses
+	if pl.NetRevenue > 0 {
+		pl.NetMarginPercent = math.Round(float64(pl.NetProfit)/float64(pl.NetRevenue)*10000) / 100
+	}
+	return pl, nil
+}
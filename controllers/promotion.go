@@ -0,0 +1,684 @@
+// controllers/promotion.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Promotion discount types
+const (
+	PromotionPercent = "percent"
+	PromotionFixed   = "fixed"
+)
+
+// PromotionTargetInput limits a promotion to a service or a service category; set one of them
+type PromotionTargetInput struct {
+	ServiceID *uuid.UUID `json:"serviceId"`
+	Category  string     `json:"category"`
+}
+
+// PromotionInput defines the expected JSON structure for creating or replacing a promotion
+type PromotionInput struct {
+	Code                  string                 `json:"code" binding:"required,max=30"`
+	Name                  string                 `json:"name" binding:"required"`
+	Description           string                 `json:"description"`
+	DiscountType          string                 `json:"discountType" binding:"required,oneof=percent fixed"`
+	Percent               float64                `json:"percent" binding:"min=0,max=100"`       // For percent promotions
+	Amount                models.Money           `json:"amount" binding:"min=0"`                // For fixed promotions
+	MaxDiscount           models.Money           `json:"maxDiscount" binding:"min=0"`           // Caps a percent discount; 0 no cap
+	MinSpend              models.Money           `json:"minSpend" binding:"min=0"`              // Invoice subtotal required
+	ValidFrom             *time.Time             `json:"validFrom"`                             // First day the code works
+	ValidTo               *time.Time             `json:"validTo"`                               // Last day the code works
+	UsageLimit            int                    `json:"usageLimit" binding:"min=0"`            // Invoices in total; 0 unlimited
+	UsageLimitPerCustomer int                    `json:"usageLimitPerCustomer" binding:"min=0"` // 0 unlimited
+	BirthdayMonthOnly     bool                   `json:"birthdayMonthOnly"`
+	IsActive              *bool                  `json:"isActive"`
+	Targets               []PromotionTargetInput `json:"targets" binding:"dive"` // Empty applies to every line but gift cards
+}
+
+// PromotionReportRow measures one promotion over a period
+type PromotionReportRow struct {
+	PromotionID       uuid.UUID    `json:"promotionId"`
+	Code              string       `json:"code"`
+	Name              string       `json:"name"`
+	Uses              int          `json:"uses"`
+	Customers         int          `json:"customers"`
+	NewCustomers      int          `json:"newCustomers"` // First finalized invoice at the salon used the code
+	DiscountGiven     models.Money `json:"discountGiven"`
+	Revenue           models.Money `json:"revenue"` // Invoice totals less refunds
+	AverageOrderValue models.Money `json:"averageOrderValue"`
+}
+
+// promotionError is returned when a promotion code cannot be used on an invoice
+type promotionError struct {
+	message string
+}
+
+func (e promotionError) Error() string {
+	return e.message
+}
+
+// GetPromotions lists the salon's promotions. ?active=true hides retired ones.
+func GetPromotions(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	query := config.DB.Preload("Targets").Where("salon_id = ?", salonUUID)
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var promotions []models.Promotion
+	if err := query.Order("created_at DESC").Find(&promotions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve promotions")
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetPromotion returns a single promotion with its targets
+func GetPromotion(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	promotionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid promotion ID format")
+		return
+	}
+
+	var promotion models.Promotion
+	if err := config.DB.Preload("Targets").Where("salon_id = ? AND id = ?", salonUUID, promotionUUID).
+		First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Promotion not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// CreatePromotion defines a new promotion code
+func CreatePromotion(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage promotions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	promotion := models.Promotion{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+	}
+	if !applyPromotionInput(c, salonUUID, &promotion, input) {
+		return
+	}
+
+	if err := config.DB.Create(&promotion).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create promotion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// UpdatePromotion replaces a promotion and its targets. Invoices that already used it keep
+// the discount they were given.
+func UpdatePromotion(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	promotionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid promotion ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage promotions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var promotion models.Promotion
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, promotionUUID).First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Promotion not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Invoices record the code they used, so it cannot change once the promotion has been used
+	if code := utils.NormalizeCode(input.Code); code != promotion.Code {
+		var used int64
+		if err := config.DB.Model(&models.Invoice{}).Where("promotion_id = ?", promotion.ID).Count(&used).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if used > 0 {
+			utils.RespondWithError(c, http.StatusConflict, "Promotion has been used; its code cannot change")
+			return
+		}
+	}
+
+	if !applyPromotionInput(c, salonUUID, &promotion, input) {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("promotion_id = ?", promotion.ID).Delete(&models.PromotionTarget{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing targets")
+		return
+	}
+
+	if err := tx.Save(&promotion).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update promotion")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion removes a promotion that was never used. Used promotions are retired with isActive=false instead.
+func DeletePromotion(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	promotionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid promotion ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage promotions", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var used int64
+	if err := config.DB.Model(&models.Invoice{}).Where("promotion_id = ?", promotionUUID).Count(&used).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if used > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Promotion has been used; deactivate it instead")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result := tx.Where("salon_id = ? AND id = ?", salonUUID, promotionUUID).Delete(&models.Promotion{})
+	if result.Error != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete promotion")
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusNotFound, "Promotion not found")
+		return
+	}
+
+	if err := tx.Where("promotion_id = ?", promotionUUID).Delete(&models.PromotionTarget{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete promotion targets")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+}
+
+// GetPromotionReport measures each promotion used on finalized invoices in the period: how often,
+// by how many customers, the discount given and the revenue it brought in.
+// GET /api/reports/promotions?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (rc *ReportController) GetPromotionReport(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can view promotion reports", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows := []PromotionReportRow{}
+	if err := config.DB.Raw(`
+		SELECT p.id AS promotion_id, p.code, p.name,
+			COUNT(i.id) AS uses,
+			COUNT(DISTINCT i.customer_id) AS customers,
+			COUNT(DISTINCT i.customer_id) FILTER (WHERE NOT EXISTS (
+				SELECT 1 FROM invoices e
+				WHERE e.customer_id = i.customer_id AND e.status = 'finalized'
+					AND e.invoice_date < i.invoice_date)) AS new_customers,
+			COALESCE(SUM(i.promotion_discount), 0) AS discount_given,
+			COALESCE(SUM(i.total - i.refunded_amount), 0) AS revenue
+		FROM invoices i
+		JOIN promotions p ON p.id = i.promotion_id
+		WHERE i.salon_id = ? AND i.status = 'finalized'
+			AND i.invoice_date >= ? AND i.invoice_date < ?
+		GROUP BY p.id, p.code, p.name
+		ORDER BY discount_given DESC`, salonUUID, from, to).Scan(&rows).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate promotion report")
+		return
+	}
+
+	var totalDiscount, totalRevenue models.Money
+	for i := range rows {
+		if rows[i].Uses > 0 {
+			rows[i].AverageOrderValue = rows[i].Revenue.MulDiv(1, int64(rows[i].Uses))
+		}
+		totalDiscount += rows[i].DiscountGiven
+		totalRevenue += rows[i].Revenue
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format("2006-01-02"),
+		"to":            to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"promotions":    rows,
+		"totalDiscount": totalDiscount,
+		"totalRevenue":  totalRevenue,
+	})
+}
+
+// applyPromotionInput validates input and copies it onto promotion. On failure it writes the error response.
+func applyPromotionInput(c *gin.Context, salonID uuid.UUID, promotion *models.Promotion, input PromotionInput) bool {
+	code := utils.NormalizeCode(input.Code)
+	if code == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "code is required")
+		return false
+	}
+	if input.DiscountType == PromotionPercent && input.Percent <= 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "percent is required for percent promotions")
+		return false
+	}
+	if input.DiscountType == PromotionFixed && input.Amount <= 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "amount is required for fixed promotions")
+		return false
+	}
+	if input.ValidFrom != nil && input.ValidTo != nil && input.ValidTo.Before(*input.ValidFrom) {
+		utils.RespondWithError(c, http.StatusBadRequest, "validTo cannot be before validFrom")
+		return false
+	}
+
+	var taken int64
+	if err := config.DB.Model(&models.Promotion{}).
+		Where("salon_id = ? AND code = ? AND id <> ?", salonID, code, promotion.ID).
+		Count(&taken).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return false
+	}
+	if taken > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Promotion code already exists: "+code)
+		return false
+	}
+
+	promotion.Code = code
+	promotion.Name = strings.TrimSpace(input.Name)
+	promotion.Description = input.Description
+	promotion.DiscountType = input.DiscountType
+	promotion.Percent, promotion.Amount, promotion.MaxDiscount = 0, 0, 0
+	if input.DiscountType == PromotionPercent {
+		promotion.Percent = input.Percent
+		promotion.MaxDiscount = input.MaxDiscount
+	} else {
+		promotion.Amount = input.Amount
+	}
+	promotion.MinSpend = input.MinSpend
+	promotion.ValidFrom = input.ValidFrom
+	promotion.ValidTo = input.ValidTo
+	promotion.UsageLimit = input.UsageLimit
+	promotion.UsageLimitPerCustomer = input.UsageLimitPerCustomer
+	promotion.BirthdayMonthOnly = input.BirthdayMonthOnly
+	promotion.IsActive = input.IsActive == nil || *input.IsActive
+
+	promotion.Targets = nil
+	for _, target := range input.Targets {
+		category := strings.TrimSpace(target.Category)
+		if (target.ServiceID == nil) == (category == "") {
+			utils.RespondWithError(c, http.StatusBadRequest, "Each target needs either a serviceId or a category")
+			return false
+		}
+
+		if target.ServiceID != nil {
+			var service models.Service
+			if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonID, *target.ServiceID).
+				First(&service).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+target.ServiceID.String())
+				} else {
+					utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+				}
+				return false
+			}
+		}
+
+		promotion.Targets = append(promotion.Targets, models.PromotionTarget{
+			ID:          uuid.New(),
+			PromotionID: promotion.ID,
+			ServiceID:   target.ServiceID,
+			Category:    category,
+		})
+	}
+	return true
+}
+
+// applyPromotionCode checks a promotion code against the invoice's customer, date and lines and
+// spreads the discount over the lines it targets. An empty code removes any promotion. It must run
+// after package credits are applied and before the invoice is priced; claimPromotion then checks
+// the usage limits again when the invoice is saved.
+func applyPromotionCode(db *gorm.DB, invoice *models.Invoice, code string) error {
+	clearPromotion(invoice)
+	code = utils.NormalizeCode(code)
+	if code == "" {
+		return nil
+	}
+
+	var promotion models.Promotion
+	if err := db.Preload("Targets").Where("salon_id = ? AND code = ?", invoice.SalonID, code).
+		First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return promotionError{"Promotion code not found: " + code}
+		}
+		return err
+	}
+
+	var customer models.Customer
+	if err := db.Select("id", "birthday").First(&customer, "id = ?", invoice.CustomerID).Error; err != nil {
+		return err
+	}
+
+	day := invoice.InvoiceDate
+	if !promotion.IsActive {
+		return promotionError{"Promotion " + code + " is no longer active"}
+	}
+	if promotion.ValidFrom != nil && day.Before(*promotion.ValidFrom) {
+		return promotionError{"Promotion " + code + " starts on " + promotion.ValidFrom.Format("2006-01-02")}
+	}
+	if promotion.ValidTo != nil && !day.Before(promotion.ValidTo.AddDate(0, 0, 1)) {
+		return promotionError{"Promotion " + code + " ended on " + promotion.ValidTo.Format("2006-01-02")}
+	}
+	if promotion.BirthdayMonthOnly && (customer.Birthday == nil || customer.Birthday.Month() != day.Month()) {
+		return promotionError{"Promotion " + code + " is only valid in the customer's birthday month"}
+	}
+
+	var subtotal models.Money
+	for _, item := range invoice.Items {
+		subtotal += item.TotalPrice
+	}
+	if subtotal < promotion.MinSpend {
+		return promotionError{"Promotion " + code + " needs a minimum spend of " + promotion.MinSpend.String()}
+	}
+
+	if err := checkPromotionUsage(db, promotion, invoice.CustomerID, invoice.ID); err != nil {
+		return err
+	}
+
+	lines, err := promotionLines(db, promotion, invoice.Items)
+	if err != nil {
+		return err
+	}
+	var base models.Money
+	for _, i := range lines {
+		base += invoice.Items[i].TotalPrice
+	}
+	if base == 0 {
+		return promotionError{"Promotion " + code + " does not apply to any item on this invoice"}
+	}
+
+	discount := promotion.Amount
+	if promotion.DiscountType == PromotionPercent {
+		discount = base.Percent(promotion.Percent)
+		if promotion.MaxDiscount > 0 && discount > promotion.MaxDiscount {
+			discount = promotion.MaxDiscount
+		}
+	}
+	if discount > base {
+		discount = base
+	}
+
+	// Shared over the targeted lines by value; the last takes the rounding
+	left := discount
+	for n, i := range lines {
+		item := &invoice.Items[i]
+		item.PromotionDiscount = left
+		if n < len(lines)-1 {
+			item.PromotionDiscount = discount.Share(item.TotalPrice, base)
+		}
+		left -= item.PromotionDiscount
+	}
+
+	invoice.PromotionID = &promotion.ID
+	invoice.PromotionCode = promotion.Code
+	invoice.PromotionDiscount = discount
+	return nil
+}
+
+// clearPromotion removes a promotion and its line discounts from an invoice
+func clearPromotion(invoice *models.Invoice) {
+	invoice.PromotionID = nil
+	invoice.PromotionCode = ""
+	invoice.PromotionDiscount = 0
+	for i := range invoice.Items {
+		invoice.Items[i].PromotionDiscount = 0
+	}
+}
+
+// promotionLines returns the indexes of the lines a promotion discounts: its target services and
+// categories, or every service, package and product line when it has none. Gift cards and lines already
+// paid from a package are never discounted.
+func promotionLines(db *gorm.DB, promotion models.Promotion, items []models.InvoiceItem) ([]int, error) {
+	services := make(map[uuid.UUID]bool)
+	categories := make(map[string]bool)
+	for _, t := range promotion.Targets {
+		if t.ServiceID != nil {
+			services[*t.ServiceID] = true
+		} else {
+			categories[strings.ToLower(t.Category)] = true
+		}
+	}
+
+	// Category targets match service and product categories alike
+	categoryOf := make(map[uuid.UUID]string)
+	if len(categories) > 0 {
+		var serviceIDs, productIDs []uuid.UUID
+		for _, item := range items {
+			if item.ServiceID != nil {
+				serviceIDs = append(serviceIDs, *item.ServiceID)
+			}
+			if item.ProductID != nil {
+				productIDs = append(productIDs, *item.ProductID)
+			}
+		}
+		var services []models.Service
+		if len(serviceIDs) > 0 {
+			if err := db.Select("id", "category").Where("id IN ?", serviceIDs).Find(&services).Error; err != nil {
+				return nil, err
+			}
+		}
+		for _, s := range services {
+			categoryOf[s.ID] = strings.ToLower(s.Category)
+		}
+		var products []models.Product
+		if len(productIDs) > 0 {
+			if err := db.Select("id", "category").Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+				return nil, err
+			}
+		}
+		for _, p := range products {
+			categoryOf[p.ID] = strings.ToLower(p.Category)
+		}
+	}
+
+	var lines []int
+	for i, item := range items {
+		if item.ItemType == ItemTypeGiftCard || item.TotalPrice == 0 {
+			continue
+		}
+		if len(promotion.Targets) == 0 {
+			lines = append(lines, i)
+			continue
+		}
+		switch {
+		case item.ServiceID != nil:
+			if services[*item.ServiceID] || categories[categoryOf[*item.ServiceID]] {
+				lines = append(lines, i)
+			}
+		case item.ProductID != nil:
+			if categories[categoryOf[*item.ProductID]] {
+				lines = append(lines, i)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// checkPromotionUsage counts the invoices that used a promotion, other than the one being billed,
+// against its limits. Drafts count; voided invoices give their use back.
+func checkPromotionUsage(db *gorm.DB, promotion models.Promotion, customerID, invoiceID uuid.UUID) error {
+	if promotion.UsageLimit > 0 {
+		var used int64
+		if err := db.Model(&models.Invoice{}).
+			Where("promotion_id = ? AND status <> ? AND id <> ?", promotion.ID, InvoiceVoid, invoiceID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(promotion.UsageLimit) {
+			return promotionError{"Promotion " + promotion.Code + " has been fully used"}
+		}
+	}
+
+	if promotion.UsageLimitPerCustomer > 0 {
+		var used int64
+		if err := db.Model(&models.Invoice{}).
+			Where("promotion_id = ? AND customer_id = ? AND status <> ? AND id <> ?", promotion.ID, customerID, InvoiceVoid, invoiceID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(promotion.UsageLimitPerCustomer) {
+			return promotionError{fmt.Sprintf("Promotion %s can be used %d time(s) per customer", promotion.Code, promotion.UsageLimitPerCustomer)}
+		}
+	}
+	return nil
+}
+
+// claimPromotion locks the promotion an invoice uses and checks its limits again, so two invoices
+// saved at the same time cannot both take the last use. It must run inside the caller's
+// transaction before the invoice is saved.
+func claimPromotion(tx *gorm.DB, invoice models.Invoice) error {
+	if invoice.PromotionID == nil {
+		return nil
+	}
+
+	var promotion models.Promotion
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&promotion, "id = ?", *invoice.PromotionID).Error; err != nil {
+		return err
+	}
+	return checkPromotionUsage(tx, promotion, invoice.CustomerID, invoice.ID)
+}
@@ -0,0 +1,835 @@
+// controllers/purchase_order.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Purchase order statuses
+const (
+	PurchaseDraft     = "draft"
+	PurchaseOrdered   = "ordered"
+	PurchasePartial   = "partial"   // some lines or units received
+	PurchaseReceived  = "received"  // every line received in full
+	PurchaseClosed    = "closed"    // part received and the rest will not come
+	PurchaseCancelled = "cancelled" // closed before anything was received
+)
+
+// StockPurchase is the stock movement type for units received against a purchase order
+const StockPurchase = "purchase"
+
+// PurchaseOrderItemInput is a product to order, in stock units
+type PurchaseOrderItemInput struct {
+	ProductID uuid.UUID     `json:"productId" binding:"required"`
+	Quantity  float64       `json:"quantity" binding:"gt=0"`
+	UnitCost  *models.Money `json:"unitCost" binding:"omitempty,min=0"` // Defaults to the product's cost price
+}
+
+// PurchaseOrderInput defines the expected JSON structure for creating or replacing a draft purchase order
+type PurchaseOrderInput struct {
+	SupplierID uuid.UUID                `json:"supplierId" binding:"required"`
+	ExpectedAt *time.Time               `json:"expectedAt"`
+	Notes      string                   `json:"notes"`
+	Items      []PurchaseOrderItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReceiveItemInput is a delivery of one purchase order line
+type ReceiveItemInput struct {
+	PurchaseOrderItemID uuid.UUID     `json:"purchaseOrderItemId" binding:"required"`
+	Quantity            float64       `json:"quantity" binding:"gt=0"`
+	UnitCost            *models.Money `json:"unitCost" binding:"omitempty,min=0"` // Billed cost when it differs from the order
+}
+
+// ReceivePurchaseOrderInput defines the expected JSON structure for receiving stock.
+// Leaving Items empty receives everything still outstanding.
+type ReceivePurchaseOrderInput struct {
+	Items []ReceiveItemInput `json:"items" binding:"dive"`
+}
+
+// ReorderSuggestion is a product that should be ordered, with how much to cover the period asked for
+type ReorderSuggestion struct {
+	ProductID         uuid.UUID    `json:"productId"`
+	Name              string       `json:"name"`
+	SKU               string       `json:"sku"`
+	StockQuantity     float64      `json:"stockQuantity"`
+	LowStockThreshold float64      `json:"lowStockThreshold"`
+	OnOrder           float64      `json:"onOrder"`    // Ordered and not yet received
+	DailyUsage        float64      `json:"dailyUsage"` // Sold and used per day over the look-back period
+	DaysLeft          *float64     `json:"daysLeft"`   // At that rate; null when nothing was used
+	SuggestedQuantity float64      `json:"suggestedQuantity"`
+	LastSupplierID    *uuid.UUID   `json:"lastSupplierId"`
+	LastUnitCost      models.Money `json:"lastUnitCost"`
+}
+
+// GetPurchaseOrders lists the salon's purchase orders, newest first. ?status= and ?supplierId= filter them.
+func GetPurchaseOrders(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if supplierID := c.Query("supplierId"); supplierID != "" {
+		supplierUUID, err := uuid.Parse(supplierID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid supplier ID format")
+			return
+		}
+		query = query.Where("supplier_id = ?", supplierUUID)
+	}
+
+	var orders []models.PurchaseOrder
+	if err := query.Order("created_at DESC").Find(&orders).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve purchase orders")
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetPurchaseOrder returns a purchase order with its lines
+func GetPurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var order models.PurchaseOrder
+	if err := config.DB.Preload("Items").Where("salon_id = ? AND id = ?", salonUUID, orderUUID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Purchase order not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// CreatePurchaseOrder drafts a purchase order
+func CreatePurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input PurchaseOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	order := models.PurchaseOrder{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		Status:          PurchaseDraft,
+		CreatedByUserID: currentUser.ID,
+	}
+	if !applyPurchaseOrderInput(c, &order, input) {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	number, err := nextDocumentNumber(tx, salonUUID, SeriesPurchase, time.Now())
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to issue purchase order number")
+		return
+	}
+	order.OrderNumber = number
+
+	if err := tx.Create(&order).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// UpdatePurchaseOrder replaces a draft purchase order and its lines
+func UpdatePurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input PurchaseOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, ok := lockPurchaseOrder(c, tx, salonUUID, orderUUID, PurchaseDraft)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if !applyPurchaseOrderInput(c, &order, input) {
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
+		return
+	}
+
+	if err := tx.Save(&order).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, order)
+}
+
+// DeletePurchaseOrder removes a draft purchase order. Orders already placed are cancelled instead.
+func DeletePurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, ok := lockPurchaseOrder(c, tx, salonUUID, orderUUID, PurchaseDraft)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete purchase order items")
+		return
+	}
+
+	if err := tx.Delete(&order).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order deleted successfully"})
+}
+
+// PlacePurchaseOrder marks a draft as sent to the supplier. From then on it can only be received or cancelled.
+func PlacePurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, ok := lockPurchaseOrder(c, tx, salonUUID, orderUUID, PurchaseDraft)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	now := time.Now()
+	order.Status = PurchaseOrdered
+	order.OrderedAt = &now
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"status":     order.Status,
+		"ordered_at": order.OrderedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to place purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, order)
+}
+
+// ReceivePurchaseOrder books a delivery into stock. Each line can arrive over several deliveries;
+// received units are added to stock and the product's cost price moves to the weighted average
+// of the stock on hand and the units received.
+func ReceivePurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	currentUser, ok := requireRole(c, "Only owners and managers can receive stock", RoleOwner, RoleManager)
+	if !ok {
+		return
+	}
+
+	var input ReceivePurchaseOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, ok := lockPurchaseOrder(c, tx, salonUUID, orderUUID, PurchaseOrdered, PurchasePartial)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+	if err := tx.Where("purchase_order_id = ?", order.ID).Find(&order.Items).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	receipts := input.Items
+	if len(receipts) == 0 {
+		for _, item := range order.Items {
+			if outstanding := roundStock(item.Quantity - item.ReceivedQuantity); outstanding > 0 {
+				receipts = append(receipts, ReceiveItemInput{PurchaseOrderItemID: item.ID, Quantity: outstanding})
+			}
+		}
+	}
+	if len(receipts) == 0 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, "Nothing left to receive on this purchase order")
+		return
+	}
+
+	itemIndex := make(map[uuid.UUID]int, len(order.Items))
+	for i, item := range order.Items {
+		itemIndex[item.ID] = i
+	}
+
+	for _, receipt := range receipts {
+		i, found := itemIndex[receipt.PurchaseOrderItemID]
+		if !found {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, "Purchase order item not found: "+receipt.PurchaseOrderItemID.String())
+			return
+		}
+		item := &order.Items[i]
+
+		quantity := roundStock(receipt.Quantity)
+		if outstanding := roundStock(item.Quantity - item.ReceivedQuantity); quantity > outstanding {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, fmt.Sprintf("Only %g of %s is still to be received", outstanding, item.ProductName))
+			return
+		}
+
+		unitCost := item.UnitCost
+		if receipt.UnitCost != nil {
+			unitCost = *receipt.UnitCost
+		}
+
+		if err := receiveStock(tx, order, *item, quantity, unitCost, currentUser.ID); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to receive stock")
+			return
+		}
+
+		item.ReceivedQuantity = roundStock(item.ReceivedQuantity + quantity)
+		if err := tx.Model(item).Update("received_quantity", item.ReceivedQuantity).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update purchase order items")
+			return
+		}
+	}
+
+	order.Status = PurchaseReceived
+	for _, item := range order.Items {
+		if item.ReceivedQuantity < item.Quantity {
+			order.Status = PurchasePartial
+			break
+		}
+	}
+	if order.Status == PurchaseReceived {
+		now := time.Now()
+		order.ReceivedAt = &now
+	}
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"status":      order.Status,
+		"received_at": order.ReceivedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, order)
+}
+
+// CancelPurchaseOrder stops waiting for a purchase order. An order with nothing received is
+// cancelled; one that was partly received is closed with what arrived.
+func CancelPurchaseOrder(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid purchase order ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	order, ok := lockPurchaseOrder(c, tx, salonUUID, orderUUID, PurchaseDraft, PurchaseOrdered, PurchasePartial)
+	if !ok {
+		tx.Rollback()
+		return
+	}
+
+	if order.Status == PurchasePartial {
+		order.Status = PurchaseClosed
+	} else {
+		order.Status = PurchaseCancelled
+	}
+
+	if err := tx.Model(&order).Update("status", order.Status).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel purchase order")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, order)
+}
+
+// GetReorderSuggestions lists products to reorder: those at or below their low-stock threshold,
+// or that will run out within ?coverDays= (default 30) at the rate they were sold and used over
+// the last ?days= (default 30). Stock already on order counts towards what is needed.
+func GetReorderSuggestions(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage purchase orders", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	days, coverDays := 30, 30
+	for param, target := range map[string]*int{"days": &days, "coverDays": &coverDays} {
+		if value := c.Query(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 365 {
+				utils.RespondWithError(c, http.StatusBadRequest, param+" must be between 1 and 365")
+				return
+			}
+			*target = n
+		}
+	}
+
+	var products []models.Product
+	if err := config.DB.Where("salon_id = ? AND is_active = ?", salonUUID, true).
+		Order("name").Find(&products).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve products")
+		return
+	}
+
+	type productQuantity struct {
+		ProductID uuid.UUID
+		Quantity  float64
+	}
+
+	// Units that left the shelf: sales and back-bar use, less returns and voids
+	var used []productQuantity
+	since := time.Now().AddDate(0, 0, -days)
+	if err := config.DB.Model(&models.StockMovement{}).
+		Select("product_id, -SUM(quantity) AS quantity").
+		Where("salon_id = ? AND type IN ? AND created_at >= ?", salonUUID,
+			[]string{StockSale, StockConsumption, StockReturn, StockVoid}, since).
+		Group("product_id").Scan(&used).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate usage")
+		return
+	}
+
+	var onOrder []productQuantity
+	if err := config.DB.Table("purchase_order_items poi").
+		Select("poi.product_id, SUM(poi.quantity - poi.received_quantity) AS quantity").
+		Joins("JOIN purchase_orders po ON po.id = poi.purchase_order_id").
+		Where("po.salon_id = ? AND po.status IN ?", salonUUID, []string{PurchaseDraft, PurchaseOrdered, PurchasePartial}).
+		Group("poi.product_id").Scan(&onOrder).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate stock on order")
+		return
+	}
+
+	var lastPurchases []struct {
+		ProductID  uuid.UUID
+		SupplierID uuid.UUID
+		UnitCost   models.Money
+	}
+	if err := config.DB.Raw(`
+		SELECT DISTINCT ON (poi.product_id) poi.product_id, po.supplier_id, poi.unit_cost
+		FROM purchase_order_items poi
+		JOIN purchase_orders po ON po.id = poi.purchase_order_id
+		WHERE po.salon_id = ? AND po.status <> ?
+		ORDER BY poi.product_id, po.created_at DESC`, salonUUID, PurchaseCancelled).Scan(&lastPurchases).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to look up suppliers")
+		return
+	}
+
+	usedBy := make(map[uuid.UUID]float64, len(used))
+	for _, u := range used {
+		usedBy[u.ProductID] = u.Quantity
+	}
+	onOrderBy := make(map[uuid.UUID]float64, len(onOrder))
+	for _, o := range onOrder {
+		onOrderBy[o.ProductID] = o.Quantity
+	}
+
+	suggestions := []ReorderSuggestion{}
+	for _, product := range products {
+		daily := math.Max(usedBy[product.ID], 0) / float64(days)
+		available := product.StockQuantity + onOrderBy[product.ID]
+
+		lowStock := product.LowStockThreshold > 0 && available <= product.LowStockThreshold
+		runningOut := daily > 0 && available < daily*float64(coverDays)
+		if !lowStock && !runningOut {
+			continue
+		}
+
+		// Enough for the cover period on top of the threshold, in whole units
+		needed := math.Ceil(roundStock(daily*float64(coverDays) + product.LowStockThreshold - available))
+		if needed <= 0 {
+			continue
+		}
+
+		suggestion := ReorderSuggestion{
+			ProductID:         product.ID,
+			Name:              product.Name,
+			SKU:               product.SKU,
+			StockQuantity:     product.StockQuantity,
+			LowStockThreshold: product.LowStockThreshold,
+			OnOrder:           onOrderBy[product.ID],
+			DailyUsage:        roundStock(daily),
+			SuggestedQuantity: needed,
+			LastUnitCost:      product.CostPrice,
+		}
+		if daily > 0 {
+			daysLeft := math.Round(math.Max(product.StockQuantity, 0)/daily*10) / 10
+			suggestion.DaysLeft = &daysLeft
+		}
+		for _, p := range lastPurchases {
+			if p.ProductID == product.ID {
+				supplierID := p.SupplierID
+				suggestion.LastSupplierID = &supplierID
+				suggestion.LastUnitCost = p.UnitCost
+				break
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	// Soonest to run out first; products with no recent usage follow by name
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i].DaysLeft, suggestions[j].DaysLeft
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":        days,
+		"coverDays":   coverDays,
+		"suggestions": suggestions,
+	})
+}
+
+// applyPurchaseOrderInput validates input and copies it onto order. On failure it writes the error response.
+func applyPurchaseOrderInput(c *gin.Context, order *models.PurchaseOrder, input PurchaseOrderInput) bool {
+	var supplier models.Supplier
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ? AND is_active = ?", order.SalonID, input.SupplierID, true).
+		First(&supplier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Supplier not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return false
+	}
+
+	order.SupplierID = supplier.ID
+	order.ExpectedAt = input.ExpectedAt
+	order.Notes = input.Notes
+	order.Total = 0
+	order.Items = nil
+	for _, item := range input.Items {
+		var product models.Product
+		if err := config.DB.Select("id", "name", "cost_price").Where("salon_id = ? AND id = ?", order.SalonID, item.ProductID).
+			First(&product).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, productNotFoundError{ProductID: item.ProductID}.Error())
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return false
+		}
+
+		unitCost := product.CostPrice
+		if item.UnitCost != nil {
+			unitCost = *item.UnitCost
+		}
+		quantity := roundStock(item.Quantity)
+		totalCost := unitCost.MulDiv(int64(math.Round(quantity*1000)), 1000)
+
+		order.Items = append(order.Items, models.PurchaseOrderItem{
+			ID:              uuid.New(),
+			PurchaseOrderID: order.ID,
+			ProductID:       product.ID,
+			ProductName:     product.Name,
+			Quantity:        quantity,
+			UnitCost:        unitCost,
+			TotalCost:       totalCost,
+		})
+		order.Total += totalCost
+	}
+	return true
+}
+
+// lockPurchaseOrder loads a purchase order FOR UPDATE and checks it is in one of the expected statuses.
+// On failure it writes the error response; the caller rolls back.
+func lockPurchaseOrder(c *gin.Context, tx *gorm.DB, salonID, orderID uuid.UUID, expected ...string) (models.PurchaseOrder, bool) {
+	var order models.PurchaseOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonID, orderID).
+		First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Purchase order not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return order, false
+	}
+
+	for _, status := range expected {
+		if order.Status == status {
+			return order, true
+		}
+	}
+	utils.RespondWithError(c, http.StatusConflict, "Purchase order is "+order.Status)
+	return order, false
+}
+
+// receiveStock adds units received against a purchase order line to stock and moves the product's
+// cost price to the weighted average of the stock on hand and the units received
+func receiveStock(tx *gorm.DB, order models.PurchaseOrder, item models.PurchaseOrderItem, quantity float64, unitCost models.Money, userID uuid.UUID) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "stock_quantity", "cost_price").
+		First(&product, "id = ?", item.ProductID).Error; err != nil {
+		return err
+	}
+
+	// Stock below zero (back-bar use not yet counted) carries no value into the average
+	onHand := int64(math.Round(math.Max(product.StockQuantity, 0) * 1000))
+	received := int64(math.Round(quantity * 1000))
+	cost := (product.CostPrice*models.Money(onHand) + unitCost*models.Money(received)).MulDiv(1, onHand+received)
+
+	if err := tx.Model(&product).Update("cost_price", cost).Error; err != nil {
+		return err
+	}
+
+	_, err := addStockMovement(tx, models.StockMovement{
+		SalonID:         order.SalonID,
+		ProductID:       item.ProductID,
+		Type:            StockPurchase,
+		Quantity:        quantity,
+		UnitCost:        unitCost,
+		PurchaseOrderID: &order.ID,
+		Reason:          "Purchase order " + order.OrderNumber,
+		CreatedByUserID: &userID,
+	})
+	return err
+}
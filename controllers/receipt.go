@@ -0,0 +1,190 @@
+// controllers/receipt.go
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SendReceiptInput optionally forces the channel used for a receipt
+type SendReceiptInput struct {
+	Channel string `json:"channel" binding:"omitempty,oneof=sms whatsapp email"`
+}
+
+// SendInvoiceReceipt messages the customer a short summary of the invoice with a signed link to view it.
+// POST /api/invoices/:id/send-receipt
+func SendInvoiceReceipt(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	// The body is optional
+	var input SendReceiptInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	doc, err := loadInvoiceDocument(config.DB, salonUUID, invoiceUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !doc.Salon.ReceiptNotifications {
+		utils.RespondWithError(c, http.StatusConflict, "Receipt messages are turned off in notification settings")
+		return
+	}
+	if doc.Invoice.Status == string(InvoiceDraft) {
+		utils.RespondWithError(c, http.StatusConflict, "Finalize the invoice before sending a receipt")
+		return
+	}
+
+	link, err := invoiceViewURL(doc.Invoice.ID)
+	if err != nil {
+		if errors.Is(err, utils.ErrPublicBaseURLNotSet) {
+			utils.RespondWithError(c, http.StatusServiceUnavailable, "Receipts need PUBLIC_BASE_URL to be configured")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create receipt link")
+		}
+		return
+	}
+
+	svc := services.NewReminderService(config.DB)
+	channel, err := svc.SendReceipt(doc, link, input.Channel)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to send receipt: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Receipt sent successfully",
+		"channel": channel,
+		"phone":   doc.Customer.Phone,
+		"email":   doc.Customer.Email,
+		"link":    link,
+	})
+}
+
+// GetPublicInvoice shows an invoice to the holder of a signed receipt link, without login.
+// GET /public/invoices/:token[?format=pdf]
+func GetPublicInvoice(c *gin.Context) {
+	invoiceUUID, err := utils.VerifyLinkToken(utils.LinkPurposeInvoice, c.Param("token"))
+	if err != nil {
+		if errors.Is(err, utils.ErrLinkExpired) {
+			utils.RespondWithError(c, http.StatusGone, "This link has expired; ask the salon for a new one")
+		} else {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		}
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Select("id", "salon_id").First(&invoice, "id = ?", invoiceUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	doc, err := loadInvoiceDocument(config.DB, invoice.SalonID, invoice.ID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Robots-Tag", "noindex")
+
+	if c.Query("format") == "pdf" {
+		c.Header("Content-Disposition", "inline; filename=\""+doc.Invoice.InvoiceNumber+".pdf\"")
+		c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(doc, utils.PageA4))
+		return
+	}
+
+	page, err := services.RenderInvoiceHTML(doc, c.Request.URL.Path+"?format=pdf")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to render invoice")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
+
+// queueInvoiceReceipt sends a receipt in the background after an invoice is saved. Failures are only logged
+// so they never fail the checkout itself.
+func queueInvoiceReceipt(salonID, invoiceID uuid.UUID) {
+	link, err := invoiceViewURL(invoiceID)
+	if err != nil {
+		log.Printf("Receipt for invoice %s not sent: %v", invoiceID, err)
+		return
+	}
+
+	go func() {
+		doc, err := loadInvoiceDocument(config.DB, salonID, invoiceID)
+		if err != nil {
+			log.Printf("Receipt for invoice %s not sent: %v", invoiceID, err)
+			return
+		}
+		if !doc.Salon.ReceiptNotifications {
+			return
+		}
+
+		if _, err := services.NewReminderService(config.DB).SendReceipt(doc, link, ""); err != nil {
+			log.Printf("Receipt for invoice %s not sent: %v", invoiceID, err)
+		}
+	}()
+}
+
+// invoiceViewURL builds the public, signed and expiring link to an invoice. PUBLIC_BASE_URL sets the host
+// customers are sent to and must be configured. RECEIPT_LINK_TTL_HOURS (default 168, one week) controls
+// how long links stay valid.
+func invoiceViewURL(invoiceID uuid.UUID) (string, error) {
+	base, err := utils.PublicBaseURL()
+	if err != nil {
+		return "", err
+	}
+
+	ttlHours := 168
+	if env := os.Getenv("RECEIPT_LINK_TTL_HOURS"); env != "" {
+		if h, err := strconv.Atoi(env); err == nil && h > 0 {
+			ttlHours = h
+		}
+	}
+
+	token, err := utils.SignLinkToken(utils.LinkPurposeInvoice, invoiceID, time.Now().Add(time.Duration(ttlHours)*time.Hour))
+	if err != nil {
+		return "", err
+	}
+
+	return base + "/public/invoices/" + token, nil
+}
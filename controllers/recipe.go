@@ -0,0 +1,374 @@
+// controllers/recipe.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductUsageInput is product used, in the product's usage unit
+type ProductUsageInput struct {
+	ProductID uuid.UUID `json:"productId" binding:"required"`
+	Quantity  float64   `json:"quantity" binding:"gt=0"`
+}
+
+// ServiceRecipeInput defines the expected JSON structure for replacing a service's recipe.
+// Quantities are per service performed; an empty list clears the recipe.
+type ServiceRecipeInput struct {
+	Items []ProductUsageInput `json:"items" binding:"dive"`
+}
+
+// ServiceProfitabilityRow is what a service brought in over a period against the product it used
+type ServiceProfitabilityRow struct {
+	ServiceID     uuid.UUID    `json:"serviceId"`
+	ServiceName   string       `json:"serviceName"`
+	Quantity      int          `json:"quantity"`    // Performed, less refunds
+	Revenue       models.Money `json:"revenue"`     // Before tax, less refunds
+	ProductCost   models.Money `json:"productCost"` // Back-bar product used at cost
+	GrossProfit   models.Money `json:"grossProfit"`
+	MarginPercent float64      `json:"marginPercent"`
+}
+
+// errInvalidProductUsage is returned when usage reported for an invoice line is not a positive amount
+var errInvalidProductUsage = errors.New("Product usage quantities must be greater than zero")
+
+// GetServiceRecipe returns the products a service uses each time it is performed
+func GetServiceRecipe(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	serviceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid service ID format")
+		return
+	}
+
+	var service models.Service
+	if err := config.DB.Preload("Recipe").Where("salon_id = ? AND id = ?", salonUUID, serviceUUID).
+		First(&service).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Service not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"serviceId": service.ID,
+		"items":     service.Recipe,
+	})
+}
+
+// UpdateServiceRecipe replaces the products a service uses. Invoices already billed keep what they recorded.
+func UpdateServiceRecipe(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	serviceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid service ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can change service recipes", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input ServiceRecipeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var service models.Service
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonUUID, serviceUUID).
+		First(&service).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Service not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Each product appears once so a line's usage can be overridden product by product
+	recipe := []models.ServiceRecipeItem{}
+	seen := make(map[uuid.UUID]bool)
+	for _, item := range input.Items {
+		if seen[item.ProductID] {
+			utils.RespondWithError(c, http.StatusBadRequest, "Product listed twice: "+item.ProductID.String())
+			return
+		}
+		seen[item.ProductID] = true
+
+		if err := checkUsageProduct(config.DB, salonUUID, item.ProductID); err != nil {
+			respondInvoiceItemsError(c, err)
+			return
+		}
+
+		recipe = append(recipe, models.ServiceRecipeItem{
+			ID:        uuid.New(),
+			ServiceID: service.ID,
+			ProductID: item.ProductID,
+			Quantity:  roundStock(item.Quantity),
+		})
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Where("service_id = ?", service.ID).Delete(&models.ServiceRecipeItem{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing recipe")
+		return
+	}
+
+	if len(recipe) > 0 {
+		if err := tx.Create(&recipe).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save recipe")
+			return
+		}
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"serviceId": service.ID,
+		"items":     recipe,
+	})
+}
+
+// GetServiceProfitability reports each service's revenue against the cost of the back-bar
+// product it used, over finalized invoices in the period.
+// GET /api/reports/service-profitability?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (rc *ReportController) GetServiceProfitability(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can view profitability", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Revenue is the taxable value of the units kept; product used for refunded services is still a cost
+	rows := []ServiceProfitabilityRow{}
+	if err := config.DB.Raw(`
+		SELECT ii.service_id, MAX(ii.service_name) AS service_name,
+			SUM(ii.quantity - ii.refunded_quantity) AS quantity,
+			COALESCE(ROUND(SUM(ii.taxable_value * (ii.quantity - ii.refunded_quantity) / ii.quantity), 2), 0) AS revenue,
+			COALESCE(SUM(used.cost), 0) AS product_cost
+		FROM invoice_items ii
+		JOIN invoices i ON i.id = ii.invoice_id
+		LEFT JOIN (
+			SELECT invoice_item_id, SUM(cost) AS cost
+			FROM invoice_item_consumptions
+			GROUP BY invoice_item_id
+		) used ON used.invoice_item_id = ii.id
+		WHERE i.salon_id = ? AND i.status = 'finalized' AND ii.item_type = 'service'
+			AND i.invoice_date >= ? AND i.invoice_date < ?
+		GROUP BY ii.service_id
+		ORDER BY revenue DESC`, salonUUID, from, to).Scan(&rows).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate service profitability")
+		return
+	}
+
+	var totalRevenue, totalCost models.Money
+	for i := range rows {
+		row := &rows[i]
+		row.GrossProfit = row.Revenue - row.ProductCost
+		if row.Revenue > 0 {
+			row.MarginPercent = math.Round(float64(row.GrossProfit)/float64(row.Revenue)*10000) / 100
+		}
+		totalRevenue += row.Revenue
+		totalCost += row.ProductCost
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":             from.Format("2006-01-02"),
+		"to":               to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"services":         rows,
+		"totalRevenue":     totalRevenue,
+		"totalProductCost": totalCost,
+		"totalGrossProfit": totalRevenue - totalCost,
+	})
+}
+
+// checkUsageProduct verifies a product belongs to the salon
+func checkUsageProduct(db *gorm.DB, salonID, productID uuid.UUID) error {
+	var product models.Product
+	if err := db.Select("id").Where("salon_id = ? AND id = ?", salonID, productID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return productNotFoundError{ProductID: productID}
+		}
+		return err
+	}
+	return nil
+}
+
+// buildLineConsumptions works out the product a service line uses: the recipe times the units
+// billed, or usage reported for the line, which replaces the recipe entirely
+func buildLineConsumptions(db *gorm.DB, salonID uuid.UUID, serviceID uuid.UUID, quantity int, usage *[]ProductUsageInput) ([]models.InvoiceItemConsumption, error) {
+	var consumptions []models.InvoiceItemConsumption
+	if usage != nil {
+		for _, u := range *usage {
+			if u.Quantity <= 0 {
+				return nil, errInvalidProductUsage
+			}
+			if err := checkUsageProduct(db, salonID, u.ProductID); err != nil {
+				return nil, err
+			}
+			consumptions = append(consumptions, models.InvoiceItemConsumption{
+				ID:        uuid.New(),
+				ProductID: u.ProductID,
+				Quantity:  roundStock(u.Quantity),
+			})
+		}
+		return consumptions, nil
+	}
+
+	var recipe []models.ServiceRecipeItem
+	if err := db.Where("service_id = ?", serviceID).Find(&recipe).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recipe {
+		consumptions = append(consumptions, models.InvoiceItemConsumption{
+			ID:        uuid.New(),
+			ProductID: r.ProductID,
+			Quantity:  roundStock(r.Quantity * float64(quantity)),
+		})
+	}
+	return consumptions, nil
+}
+
+// splitConsumptions divides a line's product usage when units of it move to a line of their own,
+// e.g. when a package pays for some of them. part is the share of units out of total; rest keeps
+// the remainder so nothing is lost to rounding.
+func splitConsumptions(consumptions []models.InvoiceItemConsumption, units, total int) (part, rest []models.InvoiceItemConsumption) {
+	for _, consumption := range consumptions {
+		share := roundStock(consumption.Quantity * float64(units) / float64(total))
+		if share > 0 {
+			part = append(part, models.InvoiceItemConsumption{
+				ID:        uuid.New(),
+				ProductID: consumption.ProductID,
+				Quantity:  share,
+			})
+		}
+		if left := roundStock(consumption.Quantity - share); left > 0 {
+			consumption.Quantity = left
+			rest = append(rest, consumption)
+		}
+	}
+	return part, rest
+}
+
+// consumeInvoiceProducts takes the back-bar product a finalized invoice's lines used out of
+// stock and records what it cost
+func consumeInvoiceProducts(tx *gorm.DB, invoice *models.Invoice, items []models.InvoiceItem) error {
+	itemIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	var consumptions []models.InvoiceItemConsumption
+	if err := tx.Where("invoice_item_id IN ?", itemIDs).Find(&consumptions).Error; err != nil {
+		return err
+	}
+
+	unitSizes := make(map[uuid.UUID]float64)
+	for _, consumption := range consumptions {
+		size, ok := unitSizes[consumption.ProductID]
+		if !ok {
+			var product models.Product
+			if err := tx.Select("id", "unit_size").First(&product, "id = ?", consumption.ProductID).Error; err != nil {
+				return err
+			}
+			size = product.UnitSize
+			unitSizes[product.ID] = size
+		}
+
+		// Usage is measured in the product's usage unit; stock is counted in whole units sold
+		stock := consumption.Quantity
+		if size > 0 {
+			stock = consumption.Quantity / size
+		}
+
+		itemID := consumption.InvoiceItemID
+		movement, err := addStockMovement(tx, models.StockMovement{
+			SalonID:         invoice.SalonID,
+			ProductID:       consumption.ProductID,
+			Type:            StockConsumption,
+			Quantity:        -stock,
+			InvoiceID:       &invoice.ID,
+			InvoiceItemID:   &itemID,
+			Reason:          "Invoice " + invoice.InvoiceNumber,
+			CreatedByUserID: &invoice.CreatedByUserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		cost := movement.UnitCost.MulDiv(int64(math.Round(-movement.Quantity*1000)), 1000)
+		if err := tx.Model(&consumption).Update("cost", cost).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteInvoiceConsumptions removes the product usage recorded against an invoice's lines,
+// before the lines themselves are deleted
+func deleteInvoiceConsumptions(tx *gorm.DB, invoiceID uuid.UUID) error {
+	return tx.Where("invoice_item_id IN (?)", tx.Model(&models.InvoiceItem{}).Select("id").Where("invoice_id = ?", invoiceID)).
+		Delete(&models.InvoiceItemConsumption{}).Error
+}
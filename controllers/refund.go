@@ -0,0 +1,356 @@
+// controllers/refund.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundItemInput selects how many units of an invoice line to refund
+type RefundItemInput struct {
+	InvoiceItemID uuid.UUID `json:"invoiceItemId" binding:"required"`
+	Quantity      int       `json:"quantity" binding:"required,min=1"`
+}
+
+// RefundInput defines the expected JSON structure for refunding an invoice.
+// Leaving Items empty refunds everything not yet refunded.
+type RefundInput struct {
+	Items       []RefundItemInput `json:"items" binding:"dive"`
+	Reason      string            `json:"reason" binding:"required"`
+	Method      string            `json:"method" binding:"omitempty,oneof=cash card upi wallet store_credit"` // How money is returned; store_credit keeps it in the customer's wallet
+	Reference   string            `json:"reference"`
+	SkipRestock bool              `json:"skipRestock"` // Returned products are damaged or used and do not go back in stock
+}
+
+// CreateRefund issues a credit note against an invoice. Money is paid back (as a negative payment)
+// only for the part the customer had already paid beyond the reduced total.
+func CreateRefund(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var input RefundInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	userUUID := uuid.Must(uuid.Parse(userID.(string)))
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the invoice so two refunds cannot both pass the remaining-quantity check
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if invoice.Status != string(InvoiceFinalized) {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusConflict, "Only finalized invoices can be refunded")
+		return
+	}
+	if err := tx.Where("invoice_id = ?", invoice.ID).Find(&invoice.Items).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if err := tx.Where("invoice_id = ?", invoice.ID).Order("paid_at").Find(&invoice.Payments).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	creditItems, amount, err := buildCreditNoteItems(invoice, input.Items)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Cash goes back only for what was paid beyond the new, reduced total
+	newNetTotal := invoice.Total - invoice.RefundedAmount - amount
+	payout := invoice.PaidAmount - newNetTotal
+	if payout > amount {
+		payout = amount
+	}
+	if payout > 0 && input.Method == "" {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, "method is required when money is returned to the customer")
+		return
+	}
+
+	now := time.Now()
+	number, err := nextDocumentNumber(tx, salonUUID, SeriesCreditNote, now)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to issue credit note number")
+		return
+	}
+
+	creditNote := models.CreditNote{
+		ID:               uuid.New(),
+		SalonID:          salonUUID,
+		InvoiceID:        invoice.ID,
+		CustomerID:       invoice.CustomerID,
+		CreatedByUserID:  userUUID,
+		CreditNoteNumber: number,
+		CreditNoteDate:   now,
+		Amount:           amount,
+		RefundMethod:     input.Method,
+		Reason:           input.Reason,
+		Items:            creditItems,
+	}
+	if err := tx.Create(&creditNote).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create credit note")
+		return
+	}
+
+	for _, item := range creditItems {
+		if err := tx.Model(&models.InvoiceItem{}).Where("id = ?", item.InvoiceItemID).
+			Update("refunded_quantity", gorm.Expr("refunded_quantity + ?", item.Quantity)).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update invoice items")
+			return
+		}
+	}
+
+	if payout > 0 {
+		payment := models.Payment{
+			ID:            uuid.New(),
+			SalonID:       salonUUID,
+			InvoiceID:     invoice.ID,
+			Amount:        -payout,
+			Method:        input.Method,
+			Reference:     strings.TrimSpace(number + " " + input.Reference),
+			PaidAt:        creditNote.CreditNoteDate,
+			TakenByUserID: userUUID,
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record refund payment")
+			return
+		}
+		invoice.Payments = append(invoice.Payments, payment)
+
+		if payment.Method == PaymentStoreCredit {
+			if _, err := addWalletEntry(tx, models.WalletTransaction{
+				SalonID:         salonUUID,
+				CustomerID:      invoice.CustomerID,
+				Type:            WalletRefund,
+				Amount:          payout,
+				InvoiceID:       &invoice.ID,
+				PaymentID:       &payment.ID,
+				CreditNoteID:    &creditNote.ID,
+				Description:     "Credit note " + number,
+				CreatedByUserID: userUUID,
+			}); err != nil {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusInternalServerError, "Failed to credit wallet")
+				return
+			}
+		}
+	}
+
+	invoice.RefundedAmount += amount
+	applyPaymentSummary(&invoice)
+	if err := saveInvoicePaymentSummary(tx, &invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update invoice")
+		return
+	}
+
+	// Update customer stats
+	if err := tx.Model(&models.Customer{}).Where("id = ?", invoice.CustomerID).
+		Update("total_spent", gorm.Expr("total_spent - ?", amount)).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
+		return
+	}
+
+	if err := reverseRefundLoyalty(tx, invoice, creditNote); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to reverse loyalty points")
+		return
+	}
+
+	if err := refundPackageLines(tx, invoice, creditItems); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update packages")
+		return
+	}
+
+	if err := refundGiftCardLines(tx, invoice, creditNote); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errGiftCardUsed) {
+			utils.RespondWithError(c, http.StatusConflict, err.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel gift cards")
+		}
+		return
+	}
+
+	if !input.SkipRestock {
+		if err := restockRefundLines(tx, invoice, creditNote); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to restock products")
+			return
+		}
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"creditNote": creditNote,
+		"invoice":    invoice,
+	})
+}
+
+// GetInvoiceRefunds lists the credit notes issued against an invoice
+func GetInvoiceRefunds(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var creditNotes []models.CreditNote
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+		Order("credit_note_date").Find(&creditNotes).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve credit notes")
+		return
+	}
+
+	c.JSON(http.StatusOK, creditNotes)
+}
+
+// buildCreditNoteItems resolves the requested lines (all remaining units when empty) and prices them.
+// Each line is refunded at its own amount after discount and tax, in proportion to the units returned.
+func buildCreditNoteItems(invoice models.Invoice, requested []RefundItemInput) ([]models.CreditNoteItem, models.Money, error) {
+	quantities := make(map[uuid.UUID]int)
+	if len(requested) == 0 {
+		for _, item := range invoice.Items {
+			if remaining := item.Quantity - item.RefundedQuantity; remaining > 0 {
+				quantities[item.ID] = remaining
+			}
+		}
+	} else {
+		for _, r := range requested {
+			quantities[r.InvoiceItemID] += r.Quantity
+		}
+	}
+
+	itemsByID := make(map[uuid.UUID]models.InvoiceItem, len(invoice.Items))
+	for _, item := range invoice.Items {
+		itemsByID[item.ID] = item
+	}
+
+	var creditItems []models.CreditNoteItem
+	var amount models.Money
+	fullyRefunded := true
+	for _, item := range invoice.Items {
+		qty := quantities[item.ID]
+		if item.RefundedQuantity+qty < item.Quantity {
+			fullyRefunded = false
+		}
+		if qty == 0 {
+			continue
+		}
+		if remaining := item.Quantity - item.RefundedQuantity; qty > remaining {
+			return nil, 0, fmt.Errorf("Only %d of %s can still be refunded", remaining, item.ServiceName)
+		}
+
+		// Lines carry their own amount after discount and tax; older invoices are shared out by value
+		var lineAmount models.Money
+		if item.LineTotal > 0 {
+			lineAmount = item.LineTotal.MulDiv(int64(qty), int64(item.Quantity))
+		} else {
+			lineAmount = invoice.Total.Share(item.UnitPrice*models.Money(qty), invoice.Subtotal)
+		}
+		creditItems = append(creditItems, models.CreditNoteItem{
+			ID:            uuid.New(),
+			InvoiceItemID: item.ID,
+			ServiceName:   item.ServiceName,
+			Quantity:      qty,
+			Amount:        lineAmount,
+		})
+		amount += lineAmount
+	}
+
+	for id := range quantities {
+		if _, ok := itemsByID[id]; !ok {
+			return nil, 0, fmt.Errorf("Invoice item not found: %s", id)
+		}
+	}
+	if len(creditItems) == 0 {
+		return nil, 0, errors.New("Nothing left to refund on this invoice")
+	}
+
+	// The last refund of an invoice absorbs rounding so the credit notes add up to the total exactly
+	if fullyRefunded {
+		remainder := invoice.Total - invoice.RefundedAmount
+		creditItems[len(creditItems)-1].Amount += remainder - amount
+		amount = remainder
+	}
+
+	return creditItems, amount, nil
+}
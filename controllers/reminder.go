@@ -0,0 +1,93 @@
+// controllers/reminder.go
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxReminderLogEntries bounds one page of the reminder log
+const maxReminderLogEntries = 500
+
+// ReminderLogEntry is a reminder as shown in the salon's delivery log
+type ReminderLogEntry struct {
+	ID             uuid.UUID  `json:"id"`
+	CustomerID     uuid.UUID  `json:"customerId"`
+	CustomerName   string     `json:"customerName"`
+	EventType      string     `json:"eventType"`
+	EventDate      time.Time  `json:"eventDate"`
+	OccurrenceYear int        `json:"occurrenceYear"`
+	Channel        string     `json:"channel"`
+	Recipient      string     `json:"recipient"`
+	Message        string     `json:"message"`
+	Status         string     `json:"status"`
+	TwilioSID      string     `json:"twilioSid"`
+	Error          string     `json:"error"`
+	Attempts       int        `json:"attempts"`
+	SentAt         *time.Time `json:"sentAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// GetReminderLog lists the birthday and anniversary reminders sent to customers, newest first.
+// GET /api/reminders/log[?from=YYYY-MM-DD&to=YYYY-MM-DD][&status=failed][&eventType=birthday][&customerId=<id>]
+// Without a range it shows the last 30 days.
+func GetReminderLog(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	to := utils.BeginningOfDay(time.Now()).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -30)
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, to, err = utils.ParseDateRange(c.Query("from"), c.Query("to"))
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	query := config.DB.Table("reminder_logs rl").
+		Select("rl.*, cu.name AS customer_name").
+		Joins("LEFT JOIN customers cu ON cu.id = rl.customer_id").
+		Where("rl.salon_id = ? AND rl.created_at >= ? AND rl.created_at < ?", salonUUID, from, to)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("rl.status = ?", status)
+	}
+	if eventType := c.Query("eventType"); eventType != "" {
+		query = query.Where("rl.event_type = ?", eventType)
+	}
+	if customerID := c.Query("customerId"); customerID != "" {
+		customerUUID, err := uuid.Parse(customerID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+			return
+		}
+		query = query.Where("rl.customer_id = ?", customerUUID)
+	}
+
+	entries := []ReminderLogEntry{}
+	if err := query.Order("rl.created_at DESC").Limit(maxReminderLogEntries).Scan(&entries).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve reminder log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Format("2006-01-02"),
+		"to":      to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"entries": entries,
+	})
+}
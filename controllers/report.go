@@ -3,8 +3,10 @@ package controllers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"salonpro-backend/config"
+	"salonpro-backend/models"
 	"salonpro-backend/utils"
 	"sync"
 	"time"
@@ -18,11 +20,11 @@ type ReportController struct{}
 
 // AnalyticsSummary represents the Analytics data
 type AnalyticsSummary struct {
-	CurrentMonthRevenue    float64                `json:"currentMonthRevenue"`
+	CurrentMonthRevenue    models.Money           `json:"currentMonthRevenue"`
 	MonthGrowth            float64                `json:"monthGrowth"`
-	CurrentQuarterRevenue  float64                `json:"currentQuarterRevenue"`
+	CurrentQuarterRevenue  models.Money           `json:"currentQuarterRevenue"`
 	QuarterGrowth          float64                `json:"quarterGrowth"`
-	CurrentYearRevenue     float64                `json:"currentYearRevenue"`
+	CurrentYearRevenue     models.Money           `json:"currentYearRevenue"`
 	YearGrowth             float64                `json:"yearGrowth"`
 	TopServices            []ServiceSummary       `json:"topServices"`
 	TopCustomers           []CustomerSummary      `json:"topCustomers"`
@@ -32,45 +34,45 @@ type AnalyticsSummary struct {
 }
 
 type ServiceSummary struct {
-	Name    string  `json:"name"`
-	Count   int     `json:"count"`
-	Revenue float64 `json:"revenue"`
+	Name    string       `json:"name"`
+	Count   int          `json:"count"`
+	Revenue models.Money `json:"revenue"`
 }
 
 type CustomerSummary struct {
-	Name   string  `json:"name"`
-	Visits int     `json:"visits"`
-	Spent  float64 `json:"spent"`
+	Name   string       `json:"name"`
+	Visits int          `json:"visits"`
+	Spent  models.Money `json:"spent"`
 }
 
 type QuickStatistics struct {
-	TotalCustomers   int     `json:"totalCustomers"`
-	TotalInvoices    int     `json:"totalInvoices"`
-	AvgMonthlyVisits float64 `json:"avgMonthlyVisits"`
-	AvgOrderValue    float64 `json:"avgOrderValue"`
+	TotalCustomers   int          `json:"totalCustomers"`
+	TotalInvoices    int          `json:"totalInvoices"`
+	AvgMonthlyVisits float64      `json:"avgMonthlyVisits"`
+	AvgOrderValue    models.Money `json:"avgOrderValue"`
 }
 
 type EmployeeSummary struct {
-	Name            string  `json:"name"`
-	Revenue         float64 `json:"revenue"`
-	ServicesHandled int     `json:"servicesHandled"`
+	Name            string       `json:"name"`
+	Revenue         models.Money `json:"revenue"`
+	ServicesHandled int          `json:"servicesHandled"`
 }
 
 type EmployeeServiceStats struct {
-	EmployeeName string  `json:"employeeName"`
-	ServiceName  string  `json:"serviceName"`
-	Count        int     `json:"count"`
-	Revenue      float64 `json:"revenue"`
+	EmployeeName string       `json:"employeeName"`
+	ServiceName  string       `json:"serviceName"`
+	Count        int          `json:"count"`
+	Revenue      models.Money `json:"revenue"`
 }
 
 // RevenueData holds consolidated revenue information
 type RevenueData struct {
-	CurrentMonth   float64
-	LastMonth      float64
-	CurrentQuarter float64
-	LastQuarter    float64
-	CurrentYear    float64
-	LastYear       float64
+	CurrentMonth   models.Money
+	LastMonth      models.Money
+	CurrentQuarter models.Money
+	LastQuarter    models.Money
+	CurrentYear    models.Money
+	LastYear       models.Money
 }
 
 // GetReportAnalytics returns the complete dashboard summary with optimizations
@@ -240,26 +242,26 @@ func (rc *ReportController) getConsolidatedRevenueData(salonID uuid.UUID, now ti
 	lastYearStart := time.Date(currentYear-1, 1, 1, 0, 0, 0, 0, currentLocation)
 	lastYearEnd := time.Date(currentYear-1, 12, 31, 23, 59, 59, 0, currentLocation)
 
-	// Single query to get all revenue data
+	// Single query to get all revenue data, net of refunds
 	query := `
 		SELECT 
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as current_month,
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as last_month,
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as current_quarter,
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as last_quarter,
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as current_year,
-			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total ELSE 0 END), 0) as last_year
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as current_month,
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as last_month,
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as current_quarter,
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as last_quarter,
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as current_year,
+			COALESCE(SUM(CASE WHEN invoice_date BETWEEN ? AND ? THEN total - refunded_amount ELSE 0 END), 0) as last_year
 		FROM invoices 
-		WHERE salon_id = ?
+		WHERE salon_id = ? AND status = 'finalized'
 `
 
 	var result struct {
-		CurrentMonth   float64 `db:"current_month"`
-		LastMonth      float64 `db:"last_month"`
-		CurrentQuarter float64 `db:"current_quarter"`
-		LastQuarter    float64 `db:"last_quarter"`
-		CurrentYear    float64 `db:"current_year"`
-		LastYear       float64 `db:"last_year"`
+		CurrentMonth   models.Money `db:"current_month"`
+		LastMonth      models.Money `db:"last_month"`
+		CurrentQuarter models.Money `db:"current_quarter"`
+		LastQuarter    models.Money `db:"last_quarter"`
+		CurrentYear    models.Money `db:"current_year"`
+		LastYear       models.Money `db:"last_year"`
 	}
 
 	err := config.DB.Raw(query,
@@ -294,21 +296,21 @@ func (rc *ReportController) getQuickStatistics(salonID uuid.UUID) (QuickStatisti
 	query := `
 		SELECT 
 			(SELECT COUNT(*) FROM customers WHERE salon_id = ?) as total_customers,
-			(SELECT COUNT(*) FROM invoices WHERE salon_id = ?) as total_invoices,
-			(SELECT COALESCE(SUM(total), 0) FROM invoices WHERE salon_id = ?) as total_revenue,
+			(SELECT COUNT(*) FROM invoices WHERE salon_id = ? AND status = 'finalized') as total_invoices,
+			(SELECT COALESCE(SUM(total - refunded_amount), 0) FROM invoices WHERE salon_id = ? AND status = 'finalized') as total_revenue,
 			(SELECT COALESCE(AVG(visits), 0) FROM (
 				SELECT COUNT(*) as visits
 				FROM invoices
-				WHERE salon_id = ?
+				WHERE salon_id = ? AND status = 'finalized'
 				GROUP BY DATE_TRUNC('month', invoice_date)
 			) monthly_visits) as avg_monthly_visits
 	`
 
 	var result struct {
-		TotalCustomers   int     `db:"total_customers"`
-		TotalInvoices    int     `db:"total_invoices"`
-		TotalRevenue     float64 `db:"total_revenue"`
-		AvgMonthlyVisits float64 `db:"avg_monthly_visits"`
+		TotalCustomers   int          `db:"total_customers"`
+		TotalInvoices    int          `db:"total_invoices"`
+		TotalRevenue     models.Money `db:"total_revenue"`
+		AvgMonthlyVisits float64      `db:"avg_monthly_visits"`
 	}
 
 	err := config.DB.Raw(query, salonID, salonID, salonID, salonID).Scan(&result).Error
@@ -322,7 +324,7 @@ func (rc *ReportController) getQuickStatistics(salonID uuid.UUID) (QuickStatisti
 
 	// Calculate average order value
 	if result.TotalInvoices > 0 {
-		stats.AvgOrderValue = result.TotalRevenue / float64(result.TotalInvoices)
+		stats.AvgOrderValue = result.TotalRevenue.MulDiv(1, int64(result.TotalInvoices))
 	}
 
 	return stats, nil
@@ -336,12 +338,13 @@ func (rc *ReportController) getTopServices(salonID uuid.UUID, start, end time.Ti
 	// Optimized query with proper joins and indexing
 	query := `
 		SELECT s.name, 
-			   SUM(ii.quantity) as count, 
-			   SUM(ii.total_price) as revenue
+			   SUM(ii.quantity - ii.refunded_quantity) as count, 
+			   SUM(ii.unit_price * (ii.quantity - ii.refunded_quantity)) as revenue
 		FROM invoice_items ii
 		INNER JOIN invoices i ON i.id = ii.invoice_id 
 		INNER JOIN services s ON s.id = ii.service_id
 		WHERE i.salon_id = ? 
+		  AND i.status = 'finalized'
 		  AND i.invoice_date BETWEEN ? AND ?
 		GROUP BY s.id, s.name
 		ORDER BY revenue DESC
@@ -358,10 +361,11 @@ func (rc *ReportController) getTopCustomers(salonID uuid.UUID, start, end time.T
 	query := `
 		SELECT c.name, 
 			   COUNT(i.id) as visits, 
-			   SUM(i.total) as spent
+			   SUM(i.total - i.refunded_amount) as spent
 		FROM invoices i
 		INNER JOIN customers c ON c.id = i.customer_id
 		WHERE i.salon_id = ? 
+		  AND i.status = 'finalized'
 		  AND i.invoice_date BETWEEN ? AND ?
 		GROUP BY c.id, c.name
 		ORDER BY spent DESC
@@ -372,17 +376,22 @@ func (rc *ReportController) getTopCustomers(salonID uuid.UUID, start, end time.T
 	return customers, err
 }
 
+// getTopEmployees ranks staff by the revenue of the service lines they performed, counted after
+// discounts and refunds the way commission is. Lines billed before per-line attribution existed
+// fall back to the invoice's creator.
 func (rc *ReportController) getTopEmployees(salonID uuid.UUID, start, end time.Time, limit int) ([]EmployeeSummary, error) {
 	var employees []EmployeeSummary
 
 	query := `
 		SELECT u.name, 
-			   SUM(i.total) as revenue, 
-			   COUNT(ii.id) as services_handled
-		FROM invoices i
-		INNER JOIN users u ON u.id = i.created_by_user_id
-		LEFT JOIN invoice_items ii ON ii.invoice_id = i.id
+			   SUM(ROUND(ii.taxable_value * (ii.quantity - ii.refunded_quantity) / NULLIF(ii.quantity, 0), 2)) as revenue, 
+			   SUM(ii.quantity - ii.refunded_quantity) as services_handled
+		FROM invoice_items ii
+		INNER JOIN invoices i ON i.id = ii.invoice_id
+		INNER JOIN users u ON u.id = COALESCE(ii.performed_by_user_id, i.created_by_user_id)
 		WHERE i.salon_id = ? 
+		  AND ii.item_type = 'service'
+		  AND i.status = 'finalized'
 		  AND i.invoice_date BETWEEN ? AND ?
 		GROUP BY u.id, u.name
 		ORDER BY revenue DESC
@@ -399,13 +408,15 @@ func (rc *ReportController) getEmployeeServiceDistribution(salonID uuid.UUID, st
 	query := `
 		SELECT u.name as employee_name, 
 			   s.name as service_name, 
-			   SUM(ii.quantity) as count, 
-			   SUM(ii.total_price) as revenue
+			   SUM(ii.quantity - ii.refunded_quantity) as count, 
+			   SUM(ROUND(ii.taxable_value * (ii.quantity - ii.refunded_quantity) / NULLIF(ii.quantity, 0), 2)) as revenue
 		FROM invoice_items ii
 		INNER JOIN invoices i ON i.id = ii.invoice_id
-		INNER JOIN users u ON u.id = i.created_by_user_id
+		INNER JOIN users u ON u.id = COALESCE(ii.performed_by_user_id, i.created_by_user_id)
 		INNER JOIN services s ON s.id = ii.service_id
 		WHERE i.salon_id = ? 
+		  AND ii.item_type = 'service'
+		  AND i.status = 'finalized'
 		  AND i.invoice_date BETWEEN ? AND ?
 		GROUP BY u.id, u.name, s.id, s.name
 		ORDER BY u.name, s.name
@@ -426,12 +437,17 @@ func (rc *ReportController) getQuarterEnd(date time.Time) time.Time {
 	return rc.getQuarterStart(date).AddDate(0, 3, -1)
 }
 
-func (rc *ReportController) calculateGrowthPercentage(current, previous float64) float64 {
+// calculateGrowthPercentage is the change from previous to current as a percentage of previous. It divides
+// by the size of previous so signed figures such as profit read correctly: -100 to +100 is +200%.
+func (rc *ReportController) calculateGrowthPercentage(current, previous models.Money) float64 {
 	if previous == 0 {
-		if current == 0 {
-			return 0
+		switch {
+		case current > 0:
+			return 100
+		case current < 0:
+			return -100
 		}
-		return 100
+		return 0
 	}
-	return ((current - previous) / previous) * 100
+	return ((current - previous).Float64() / math.Abs(previous.Float64())) * 100
 }
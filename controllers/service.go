@@ -15,21 +15,23 @@ import (
 
 // CreateServiceInput defines the expected JSON structure for creating a service
 type CreateServiceInput struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required,min=0"`
-	Duration    int     `json:"duration" binding:"min=0"` // in minutes
-	Category    string  `json:"category"`
+	Name        string       `json:"name" binding:"required"`
+	Description string       `json:"description"`
+	Price       models.Money `json:"price" binding:"required,min=0"`
+	Duration    int          `json:"duration" binding:"min=0"` // in minutes
+	Category    string       `json:"category"`
+	TaxClassID  *uuid.UUID   `json:"taxClassId"`
 }
 
 // UpdateServiceInput defines the expected JSON structure for updating a service
 type UpdateServiceInput struct {
-	Name        *string  `json:"name"`
-	Description *string  `json:"description"`
-	Price       *float64 `json:"price"`
-	Duration    *int     `json:"duration"`
-	Category    *string  `json:"category"`
-	IsActive    *bool    `json:"isActive"`
+	Name        *string       `json:"name"`
+	Description *string       `json:"description"`
+	Price       *models.Money `json:"price" binding:"omitempty,min=0"`
+	Duration    *int          `json:"duration"`
+	Category    *string       `json:"category"`
+	IsActive    *bool         `json:"isActive"`
+	TaxClassID  *string       `json:"taxClassId"` // "" removes the tax class
 }
 
 // CreateService creates a new service for the salon
@@ -52,6 +54,13 @@ func CreateService(c *gin.Context) {
 		return
 	}
 
+	if input.TaxClassID != nil {
+		if err := checkTaxClass(config.DB, salonUUID, *input.TaxClassID); err != nil {
+			respondTaxClassError(c, err)
+			return
+		}
+	}
+
 	// Create new service
 	service := models.Service{
 		ID:          uuid.New(),
@@ -62,6 +71,7 @@ func CreateService(c *gin.Context) {
 		Duration:    input.Duration,
 		Category:    input.Category,
 		IsActive:    true,
+		TaxClassID:  input.TaxClassID,
 	}
 
 	if err := config.DB.Create(&service).Error; err != nil {
@@ -188,6 +198,22 @@ func UpdateService(c *gin.Context) {
 	if input.IsActive != nil {
 		service.IsActive = *input.IsActive
 	}
+	if input.TaxClassID != nil {
+		if *input.TaxClassID == "" {
+			service.TaxClassID = nil
+		} else {
+			classUUID, err := uuid.Parse(*input.TaxClassID)
+			if err != nil {
+				utils.RespondWithError(c, http.StatusBadRequest, "Invalid tax class ID format")
+				return
+			}
+			if err := checkTaxClass(config.DB, salonUUID, classUUID); err != nil {
+				respondTaxClassError(c, err)
+				return
+			}
+			service.TaxClassID = &classUUID
+		}
+	}
 
 	if err := config.DB.Save(&service).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update service")
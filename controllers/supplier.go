@@ -0,0 +1,257 @@
+// controllers/supplier.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SupplierInput defines the expected JSON structure for creating or replacing a supplier
+type SupplierInput struct {
+	Name        string `json:"name" binding:"required"`
+	ContactName string `json:"contactName"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	GSTIN       string `json:"gstin"`
+	Address     string `json:"address"`
+	Notes       string `json:"notes"`
+	IsActive    *bool  `json:"isActive"`
+}
+
+// GetSuppliers lists the salon's suppliers. ?active=true hides retired ones.
+func GetSuppliers(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage suppliers", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var suppliers []models.Supplier
+	if err := query.Order("name").Find(&suppliers).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve suppliers")
+		return
+	}
+
+	c.JSON(http.StatusOK, suppliers)
+}
+
+// GetSupplier returns a single supplier
+func GetSupplier(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	supplierUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid supplier ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage suppliers", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var supplier models.Supplier
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, supplierUUID).First(&supplier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Supplier not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// CreateSupplier adds a supplier
+func CreateSupplier(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage suppliers", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	supplier := models.Supplier{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+	}
+	if !applySupplierInput(c, &supplier, input) {
+		return
+	}
+
+	if err := config.DB.Create(&supplier).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create supplier")
+		return
+	}
+
+	c.JSON(http.StatusCreated, supplier)
+}
+
+// UpdateSupplier replaces a supplier's details
+func UpdateSupplier(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	supplierUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid supplier ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage suppliers", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var supplier models.Supplier
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, supplierUUID).First(&supplier).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Supplier not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if !applySupplierInput(c, &supplier, input) {
+		return
+	}
+
+	if err := config.DB.Save(&supplier).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update supplier")
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// DeleteSupplier removes a supplier with no purchase orders. Others are retired with isActive=false instead.
+func DeleteSupplier(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	supplierUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid supplier ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage suppliers", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var orders int64
+	if err := config.DB.Model(&models.PurchaseOrder{}).Where("supplier_id = ?", supplierUUID).Count(&orders).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if orders > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Supplier has purchase orders; deactivate it instead")
+		return
+	}
+
+	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, supplierUUID).Delete(&models.Supplier{})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete supplier")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Supplier not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier deleted successfully"})
+}
+
+// applySupplierInput validates input and copies it onto supplier. On failure it writes the error response.
+func applySupplierInput(c *gin.Context, supplier *models.Supplier, input SupplierInput) bool {
+	gstin := utils.NormalizeGSTIN(input.GSTIN)
+	if gstin != "" {
+		if err := utils.ValidateGSTIN(gstin); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+			return false
+		}
+	}
+
+	supplier.Name = strings.TrimSpace(input.Name)
+	supplier.ContactName = input.ContactName
+	supplier.Phone = input.Phone
+	supplier.Email = input.Email
+	supplier.GSTIN = gstin
+	supplier.Address = input.Address
+	supplier.Notes = input.Notes
+	supplier.IsActive = input.IsActive == nil || *input.IsActive
+	return true
+}
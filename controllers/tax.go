@@ -0,0 +1,428 @@
+// controllers/tax.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaxClassInput defines the expected JSON structure for creating or updating a tax class
+type TaxClassInput struct {
+	Name string   `json:"name" binding:"required"`
+	Rate *float64 `json:"rate" binding:"required,min=0,max=100"` // percent; 0 for exempt
+	Code string   `json:"code" binding:"omitempty,max=10"`       // HSN/SAC
+}
+
+// TaxSummaryRow totals tax at one rate
+type TaxSummaryRow struct {
+	Rate         float64      `json:"rate"`
+	Documents    int          `json:"documents"`
+	TaxableValue models.Money `json:"taxableValue"`
+	CGST         models.Money `json:"cgst"`
+	SGST         models.Money `json:"sgst"`
+	IGST         models.Money `json:"igst"`
+	TotalTax     models.Money `json:"totalTax"`
+}
+
+// errDiscountExceedsSubtotal is returned when an invoice discount is larger than what it discounts
+var errDiscountExceedsSubtotal = errors.New("Discount cannot exceed the subtotal")
+
+// errInvalidPlaceOfSupply is returned for a place of supply that is not a GST state code
+var errInvalidPlaceOfSupply = errors.New("placeOfSupply must be a two-digit GST state code")
+
+// taxClassNotFoundError is returned when a service is given a tax class outside the salon
+type taxClassNotFoundError struct {
+	TaxClassID uuid.UUID
+}
+
+func (e taxClassNotFoundError) Error() string {
+	return "Tax class not found: " + e.TaxClassID.String()
+}
+
+// GetTaxClasses lists the salon's tax classes
+func GetTaxClasses(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var classes []models.TaxClass
+	if err := config.DB.Where("salon_id = ?", salonUUID).Order("rate, name").Find(&classes).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve tax classes")
+		return
+	}
+
+	c.JSON(http.StatusOK, classes)
+}
+
+// CreateTaxClass adds a tax class
+func CreateTaxClass(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage tax classes", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input TaxClassInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	class := models.TaxClass{
+		ID:      uuid.New(),
+		SalonID: salonUUID,
+		Name:    input.Name,
+		Rate:    *input.Rate,
+		Code:    input.Code,
+	}
+	if err := config.DB.Create(&class).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create tax class")
+		return
+	}
+
+	c.JSON(http.StatusCreated, class)
+}
+
+// UpdateTaxClass changes a tax class. Invoices already issued keep the rate they were billed at;
+// drafts pick up the new rate the next time they are edited.
+func UpdateTaxClass(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	classUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid tax class ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage tax classes", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var input TaxClassInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var class models.TaxClass
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, classUUID).First(&class).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Tax class not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	class.Name = input.Name
+	class.Rate = *input.Rate
+	class.Code = input.Code
+	if err := config.DB.Save(&class).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update tax class")
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// DeleteTaxClass removes a tax class that no service uses
+func DeleteTaxClass(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	classUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid tax class ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can manage tax classes", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	var inUse int64
+	if err := config.DB.Model(&models.Service{}).
+		Where("salon_id = ? AND tax_class_id = ?", salonUUID, classUUID).
+		Count(&inUse).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if inUse > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Tax class is assigned to services; move them to another class first")
+		return
+	}
+
+	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, classUUID).Delete(&models.TaxClass{})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete tax class")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Tax class not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tax class deleted successfully"})
+}
+
+// GetTaxSummary totals taxable value and CGST/SGST/IGST by rate for a filing period.
+// Invoices count on their invoice date and credit notes on their own date, so a refund
+// reduces the period it was issued in.
+// GET /api/reports/tax-summary?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (rc *ReportController) GetTaxSummary(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var invoiceRows []TaxSummaryRow
+	if err := config.DB.Raw(`
+		SELECT ii.tax_rate AS rate,
+			COUNT(DISTINCT i.id) AS documents,
+			COALESCE(SUM(ii.taxable_value), 0) AS taxable_value,
+			COALESCE(SUM(ii.cgst_amount), 0) AS cgst,
+			COALESCE(SUM(ii.sgst_amount), 0) AS sgst,
+			COALESCE(SUM(ii.igst_amount), 0) AS igst,
+			COALESCE(SUM(ii.tax_amount), 0) AS total_tax
+		FROM invoice_items ii
+		JOIN invoices i ON i.id = ii.invoice_id
+		WHERE i.salon_id = ? AND i.status = 'finalized'
+			AND i.invoice_date >= ? AND i.invoice_date < ?
+		GROUP BY ii.tax_rate
+		ORDER BY ii.tax_rate`, salonUUID, from, to).Scan(&invoiceRows).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate tax summary")
+		return
+	}
+
+	// Credit notes return tax in proportion to the units refunded
+	var creditRows []TaxSummaryRow
+	if err := config.DB.Raw(`
+		SELECT ii.tax_rate AS rate,
+			COUNT(DISTINCT cn.id) AS documents,
+			COALESCE(ROUND(SUM(ii.taxable_value * cni.quantity / ii.quantity), 2), 0) AS taxable_value,
+			COALESCE(ROUND(SUM(ii.cgst_amount * cni.quantity / ii.quantity), 2), 0) AS cgst,
+			COALESCE(ROUND(SUM(ii.sgst_amount * cni.quantity / ii.quantity), 2), 0) AS sgst,
+			COALESCE(ROUND(SUM(ii.igst_amount * cni.quantity / ii.quantity), 2), 0) AS igst,
+			COALESCE(ROUND(SUM(ii.tax_amount * cni.quantity / ii.quantity), 2), 0) AS total_tax
+		FROM credit_note_items cni
+		JOIN credit_notes cn ON cn.id = cni.credit_note_id
+		JOIN invoice_items ii ON ii.id = cni.invoice_item_id
+		WHERE cn.salon_id = ? AND cn.credit_note_date >= ? AND cn.credit_note_date < ?
+		GROUP BY ii.tax_rate
+		ORDER BY ii.tax_rate`, salonUUID, from, to).Scan(&creditRows).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate tax summary")
+		return
+	}
+
+	byRate := make(map[float64]*TaxSummaryRow)
+	for _, r := range invoiceRows {
+		row := r
+		byRate[r.Rate] = &row
+	}
+	for _, r := range creditRows {
+		row, ok := byRate[r.Rate]
+		if !ok {
+			row = &TaxSummaryRow{Rate: r.Rate}
+			byRate[r.Rate] = row
+		}
+		row.TaxableValue -= r.TaxableValue
+		row.CGST -= r.CGST
+		row.SGST -= r.SGST
+		row.IGST -= r.IGST
+		row.TotalTax -= r.TotalTax
+	}
+
+	net := make([]TaxSummaryRow, 0, len(byRate))
+	total := TaxSummaryRow{}
+	for _, row := range byRate {
+		// Documents is not meaningful once invoices and credit notes are netted
+		row.Documents = 0
+		net = append(net, *row)
+		total.TaxableValue += row.TaxableValue
+		total.CGST += row.CGST
+		total.SGST += row.SGST
+		total.IGST += row.IGST
+		total.TotalTax += row.TotalTax
+	}
+	sort.Slice(net, func(i, j int) bool { return net[i].Rate < net[j].Rate })
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format("2006-01-02"),
+		"to":          to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"invoices":    invoiceRows,
+		"creditNotes": creditRows,
+		"net":         net,
+		"total": gin.H{
+			"taxableValue": total.TaxableValue,
+			"cgst":         total.CGST,
+			"sgst":         total.SGST,
+			"igst":         total.IGST,
+			"totalTax":     total.TotalTax,
+		},
+	})
+}
+
+// priceInvoice works out tax for every item of an invoice and its totals. It copies the salon's
+// pricing mode and, unless one is given, its state as the place of supply. The discount is shared
+// across lines by value before tax; lines without a tax class are taxed at the invoice's flat Tax
+// rate. With tax-inclusive pricing the tax is taken out of the line amount instead of added to it.
+func priceInvoice(db *gorm.DB, invoice *models.Invoice, placeOfSupply string) error {
+	var salon models.Salon
+	if err := db.Select("id", "state_code", "prices_include_tax").
+		First(&salon, "id = ?", invoice.SalonID).Error; err != nil {
+		return err
+	}
+
+	if placeOfSupply == "" {
+		placeOfSupply = salon.StateCode
+	} else if !utils.ValidGSTStateCode(placeOfSupply) {
+		return errInvalidPlaceOfSupply
+	}
+	invoice.PlaceOfSupply = placeOfSupply
+	invoice.PricesIncludeTax = salon.PricesIncludeTax
+	interState := salon.StateCode != "" && placeOfSupply != salon.StateCode
+
+	var subtotal, promotion models.Money
+	for _, item := range invoice.Items {
+		subtotal += item.TotalPrice
+		promotion += item.PromotionDiscount
+	}
+	// The promotion already sits on the lines it targets. Loyalty redemption is shared across
+	// what is left of every line together with the ordinary discount.
+	remaining := subtotal - promotion
+	discount := invoice.Discount + invoice.LoyaltyDiscount
+	if discount > remaining {
+		return errDiscountExceedsSubtotal
+	}
+
+	invoice.Subtotal = subtotal
+	invoice.PromotionDiscount = promotion
+	invoice.TaxAmount, invoice.CGSTAmount, invoice.SGSTAmount, invoice.IGSTAmount = 0, 0, 0, 0
+	invoice.Total = 0
+	discountLeft := discount
+
+	for i := range invoice.Items {
+		item := &invoice.Items[i]
+		if item.ItemType == ItemTypeGiftCard {
+			item.TaxRate = 0
+		} else if item.TaxClassID == nil {
+			item.TaxRate = invoice.Tax
+		}
+
+		// The last line takes whatever discount rounding left over
+		share := discountLeft
+		if i < len(invoice.Items)-1 {
+			share = discount.Share(item.TotalPrice-item.PromotionDiscount, remaining)
+		}
+		discountLeft -= share
+		item.DiscountAmount = item.PromotionDiscount + share
+
+		amount := item.TotalPrice - item.DiscountAmount
+		if invoice.PricesIncludeTax {
+			item.TaxableValue = amount.WithoutPercent(item.TaxRate)
+			item.TaxAmount = amount - item.TaxableValue
+		} else {
+			item.TaxableValue = amount
+			item.TaxAmount = amount.Percent(item.TaxRate)
+		}
+
+		// An odd paisa of tax goes to SGST so the halves always add up
+		item.CGSTAmount, item.SGSTAmount, item.IGSTAmount = 0, 0, 0
+		if interState {
+			item.IGSTAmount = item.TaxAmount
+		} else {
+			item.CGSTAmount = item.TaxAmount / 2
+			item.SGSTAmount = item.TaxAmount - item.CGSTAmount
+		}
+		item.LineTotal = item.TaxableValue + item.TaxAmount
+
+		invoice.TaxAmount += item.TaxAmount
+		invoice.CGSTAmount += item.CGSTAmount
+		invoice.SGSTAmount += item.SGSTAmount
+		invoice.IGSTAmount += item.IGSTAmount
+		invoice.Total += item.LineTotal
+	}
+
+	return nil
+}
+
+// respondTaxClassError maps an error from checkTaxClass to an HTTP response
+func respondTaxClassError(c *gin.Context, err error) {
+	var notFound taxClassNotFoundError
+	if errors.As(err, &notFound) {
+		utils.RespondWithError(c, http.StatusBadRequest, notFound.Error())
+		return
+	}
+	utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+}
+
+// checkTaxClass verifies a tax class belongs to the salon
+func checkTaxClass(db *gorm.DB, salonID, taxClassID uuid.UUID) error {
+	var class models.TaxClass
+	if err := db.Select("id").Where("salon_id = ? AND id = ?", salonID, taxClassID).First(&class).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return taxClassNotFoundError{TaxClassID: taxClassID}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+// controllers/twilio_webhook.go
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"salonpro-backend/config"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// emptyTwiML answers an inbound message without replying to it; Twilio confirms STOP and START itself
+const emptyTwiML = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+// TwilioStatusWebhook records the delivery status Twilio reports for a message we sent.
+// POST /webhooks/twilio/status (form: MessageSid, MessageStatus, ErrorCode), signed by Twilio
+func TwilioStatusWebhook(c *gin.Context) {
+	messageSid := c.PostForm("MessageSid")
+	status := c.PostForm("MessageStatus")
+
+	updated, err := services.RecordDeliveryStatus(config.DB, messageSid, status, c.PostForm("ErrorCode"))
+	if err != nil {
+		log.Printf("Twilio status %s for %s not recorded: %v", status, messageSid, err)
+		// Twilio does not retry status callbacks, so there is nothing to gain from an error response
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// TwilioInboundWebhook handles replies to our numbers: STOP (and Twilio's other opt-out keywords)
+// withdraws the sender's consent to every message on that channel, START restores it. Both are kept in
+// the consent history. Other replies are ignored.
+// POST /webhooks/twilio/inbound (form: From, Body), signed by Twilio
+func TwilioInboundWebhook(c *gin.Context) {
+	from := c.PostForm("From")
+	body := c.PostForm("Body")
+
+	if optOut, ok := services.ConsentKeyword(body); ok {
+		changed, err := services.ApplyConsentReply(config.DB, from, body, optOut)
+		if err != nil {
+			log.Printf("Opt-out reply from %s not recorded: %v", from, err)
+			// Twilio retries when the webhook fails
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record opt-out")
+			return
+		}
+		log.Printf("Reply from %s: opt-out %t, %d customer record(s) changed", from, optOut, changed)
+	}
+
+	c.Data(http.StatusOK, "text/xml", []byte(emptyTwiML))
+}
@@ -0,0 +1,312 @@
+// controllers/wallet.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Wallet ledger entry types
+const (
+	WalletDeposit = "deposit" // advance paid in by the customer
+	WalletRefund  = "refund"  // credit note credited to the wallet instead of paid out
+	WalletPayment = "payment" // balance used to pay an invoice
+)
+
+// WalletDepositInput defines the expected JSON structure for taking an advance into a customer's wallet
+type WalletDepositInput struct {
+	Amount    models.Money `json:"amount" binding:"required,gt=0"`
+	Method    string       `json:"method" binding:"required,oneof=cash card upi wallet"`
+	Reference string       `json:"reference"`
+	Notes     string       `json:"notes"`
+}
+
+// LiabilityMovement sums one kind of balance movement over a period
+type LiabilityMovement struct {
+	Type   string       `json:"type"`
+	Count  int          `json:"count"`
+	Amount models.Money `json:"amount"`
+}
+
+// LiabilitySummary reconciles a stored-value balance over a period: Opening plus the
+// movements equals Closing. Outstanding is the current balance held for customers.
+type LiabilitySummary struct {
+	Opening     models.Money        `json:"opening"`
+	Movements   []LiabilityMovement `json:"movements"`
+	Closing     models.Money        `json:"closing"`
+	Outstanding models.Money        `json:"outstanding"`
+	Holders     int64               `json:"holders"` // cards or customers with a balance
+}
+
+// insufficientWalletError is returned when a wallet payment exceeds the customer's balance
+type insufficientWalletError struct {
+	Balance models.Money
+}
+
+func (e insufficientWalletError) Error() string {
+	return "Customer wallet balance is only " + e.Balance.String()
+}
+
+// GetCustomerWallet returns a customer's wallet balance and ledger, newest first
+func GetCustomerWallet(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var history []models.WalletTransaction
+	if err := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID).
+		Order("created_at DESC").Find(&history).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve wallet history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerId": customer.ID,
+		"balance":    customer.WalletBalance,
+		"history":    history,
+	})
+}
+
+// AddWalletDeposit takes an advance from a customer into their wallet
+func AddWalletDeposit(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var input WalletDepositInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Select("id").Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	entry, err := addWalletEntry(tx, models.WalletTransaction{
+		SalonID:         salonUUID,
+		CustomerID:      customer.ID,
+		Type:            WalletDeposit,
+		Amount:          input.Amount,
+		Method:          input.Method,
+		Reference:       input.Reference,
+		Description:     input.Notes,
+		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+	})
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record deposit")
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// GetLiabilities reconciles gift card and wallet balances the salon owes its customers.
+// GET /api/reports/liabilities?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (rc *ReportController) GetLiabilities(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	if _, ok := requireRole(c, "Only owners and managers can view liabilities", RoleOwner, RoleManager); !ok {
+		return
+	}
+
+	from, to, err := utils.ParseDateRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Balances past expiry are written off before they are reported
+	if err := expireGiftCards(config.DB, salonUUID, time.Now()); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update expired gift cards")
+		return
+	}
+
+	giftCards, err := liabilitySummary(config.DB, "gift_card_transactions", salonUUID, from, to)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate gift card liabilities")
+		return
+	}
+	if err := config.DB.Model(&models.GiftCard{}).
+		Where("salon_id = ? AND status = ? AND balance > 0", salonUUID, GiftCardActive).
+		Select("COALESCE(SUM(balance), 0)").Scan(&giftCards.Outstanding).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate gift card liabilities")
+		return
+	}
+	if err := config.DB.Model(&models.GiftCard{}).
+		Where("salon_id = ? AND status = ? AND balance > 0", salonUUID, GiftCardActive).
+		Count(&giftCards.Holders).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate gift card liabilities")
+		return
+	}
+
+	wallets, err := liabilitySummary(config.DB, "wallet_transactions", salonUUID, from, to)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate wallet liabilities")
+		return
+	}
+	if err := config.DB.Model(&models.Customer{}).
+		Where("salon_id = ? AND wallet_balance > 0", salonUUID).
+		Select("COALESCE(SUM(wallet_balance), 0)").Scan(&wallets.Outstanding).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate wallet liabilities")
+		return
+	}
+	if err := config.DB.Model(&models.Customer{}).
+		Where("salon_id = ? AND wallet_balance > 0", salonUUID).
+		Count(&wallets.Holders).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate wallet liabilities")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":             from.Format("2006-01-02"),
+		"to":               to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"giftCards":        giftCards,
+		"wallets":          wallets,
+		"totalOutstanding": giftCards.Outstanding + wallets.Outstanding,
+	})
+}
+
+// liabilitySummary sums a stored-value ledger table for [from, to)
+func liabilitySummary(db *gorm.DB, table string, salonID uuid.UUID, from, to time.Time) (LiabilitySummary, error) {
+	summary := LiabilitySummary{Movements: []LiabilityMovement{}}
+
+	if err := db.Table(table).Where("salon_id = ? AND created_at < ?", salonID, from).
+		Select("COALESCE(SUM(amount), 0)").Scan(&summary.Opening).Error; err != nil {
+		return summary, err
+	}
+
+	if err := db.Table(table).Where("salon_id = ? AND created_at >= ? AND created_at < ?", salonID, from, to).
+		Select("type, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Group("type").Order("type").Scan(&summary.Movements).Error; err != nil {
+		return summary, err
+	}
+
+	summary.Closing = summary.Opening
+	for _, m := range summary.Movements {
+		summary.Closing += m.Amount
+	}
+	return summary, nil
+}
+
+// addWalletEntry records a wallet movement and moves the customer's cached balance with it.
+// A debit larger than the balance fails with insufficientWalletError.
+func addWalletEntry(tx *gorm.DB, entry models.WalletTransaction) (models.WalletTransaction, error) {
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "wallet_balance").
+		First(&customer, "id = ?", entry.CustomerID).Error; err != nil {
+		return entry, err
+	}
+
+	if customer.WalletBalance+entry.Amount < 0 {
+		return entry, insufficientWalletError{Balance: customer.WalletBalance}
+	}
+
+	entry.ID = uuid.New()
+	entry.Balance = customer.WalletBalance + entry.Amount
+	if err := tx.Model(&customer).Update("wallet_balance", entry.Balance).Error; err != nil {
+		return entry, err
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// payFromWallet pays part of an invoice from the customer's wallet
+func payFromWallet(tx *gorm.DB, invoice models.Invoice, payment models.Payment) error {
+	_, err := addWalletEntry(tx, models.WalletTransaction{
+		SalonID:         invoice.SalonID,
+		CustomerID:      invoice.CustomerID,
+		Type:            WalletPayment,
+		Amount:          -payment.Amount,
+		InvoiceID:       &invoice.ID,
+		PaymentID:       &payment.ID,
+		Description:     "Paid towards invoice " + invoice.InvoiceNumber,
+		CreatedByUserID: payment.TakenByUserID,
+	})
+	return err
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -22,14 +23,139 @@ func init() {
 
 	config.DB.AutoMigrate(
 		&models.Salon{},
+		&models.SalonLogo{},
 		&models.User{},
 		&models.Customer{},
 		&models.Service{},
+		&models.TaxClass{},
 		&models.Invoice{},
 		&models.InvoiceItem{},
+		&models.Payment{},
+		&models.CreditNote{},
+		&models.CreditNoteItem{},
+		&models.DocumentSequence{},
 		&models.ReminderTemplate{},
-		//&models.ReminderLog{},
+		&models.Appointment{},
+		&models.AppointmentService{},
+		&models.CommissionRule{},
+		&models.CommissionTier{},
+		&models.CommissionStatement{},
+		&models.CommissionStatementLine{},
+		&models.LoyaltySettings{},
+		&models.LoyaltyMultiplier{},
+		&models.LoyaltyTransaction{},
+		&models.ServicePackage{},
+		&models.ServicePackageItem{},
+		&models.CustomerPackage{},
+		&models.CustomerPackageCredit{},
+		&models.GiftCard{},
+		&models.GiftCardTransaction{},
+		&models.WalletTransaction{},
+		&models.Promotion{},
+		&models.PromotionTarget{},
+		&models.Product{},
+		&models.StockMovement{},
+		&models.ServiceRecipeItem{},
+		&models.InvoiceItemConsumption{},
+		&models.Supplier{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderItem{},
+		&models.Expense{},
+		&models.ExpenseAttachment{},
+		&models.RecurringExpense{},
+		&models.ReminderLog{},
+		&models.CustomerConsent{},
+		&models.ConsentEvent{},
+		&models.DataMigration{},
 	)
+
+	// Invoice numbers are unique per salon (idx_invoices_salon_number), no longer globally
+	config.DB.Exec("DROP INDEX IF EXISTS idx_invoices_invoice_number")
+
+	backfillLegacyPayments()
+	runDataMigration("reminder_logs_send_failed", markReminderSendFailures)
+	runDataMigration("customer_consents_import_reminders", importReminderConsents)
+}
+
+// runDataMigration applies a one-off change to existing rows the first time the server starts with it,
+// and records it in data_migrations so it never runs again. A failure stops the server rather than
+// leaving the data half converted.
+func runDataMigration(name string, migrate func(tx *gorm.DB) error) {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Another instance applying the same migration holds this row until it commits
+		result := tx.Exec("INSERT INTO data_migrations (name, applied_at) VALUES (?, NOW()) ON CONFLICT (name) DO NOTHING", name)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return migrate(tx)
+	})
+	if err != nil {
+		panic("Data migration " + name + " failed: " + err.Error())
+	}
+}
+
+// markReminderSendFailures relabels reminders the provider never accepted. They were logged as "failed",
+// which now means the carrier rejected a sent message, and would otherwise never be retried.
+func markReminderSendFailures(tx *gorm.DB) error {
+	result := tx.Exec("UPDATE reminder_logs SET status = ? WHERE status = 'failed' AND COALESCE(twilio_sid, '') = ''",
+		services.ReminderFailed)
+	if result.Error == nil && result.RowsAffected > 0 {
+		log.Printf("Marked %d reminders the provider never accepted as %s", result.RowsAffected, services.ReminderFailed)
+	}
+	return result.Error
+}
+
+// backfillLegacyPayments gives invoices paid before the payment ledger existed one Payment for what
+// they recorded as paid, so balances, further payments and refunds work from the ledger for them too.
+// Invoices that already have payments are left alone, so it is safe to run on every start.
+// The free-text payment_method is mapped onto the methods a payment can have, with anything that is
+// not recognisably card, UPI or wallet recorded as cash. Gift card and store credit payments need a
+// balance behind them, so legacy ones are not mapped to those.
+func backfillLegacyPayments() {
+	result := config.DB.Exec(`
+		INSERT INTO payments (salon_id, invoice_id, amount, method, reference, paid_at, taken_by_user_id, created_at)
+		SELECT i.salon_id, i.id, i.paid_amount,
+			   CASE
+				   WHEN LOWER(TRIM(i.payment_method)) IN ('card', 'credit card', 'debit card', 'credit_card', 'debit_card') THEN 'card'
+				   WHEN LOWER(TRIM(i.payment_method)) = 'upi' THEN 'upi'
+				   WHEN LOWER(TRIM(i.payment_method)) = 'wallet' THEN 'wallet'
+				   ELSE 'cash'
+			   END,
+			   'Recorded before the payment ledger', i.invoice_date, i.created_by_user_id, NOW()
+		FROM invoices i
+		WHERE i.paid_amount > 0
+		  AND NOT EXISTS (SELECT 1 FROM payments p WHERE p.invoice_id = i.id)
+	`)
+	if result.Error != nil {
+		// Without the backfill legacy invoices show their whole total as outstanding
+		panic("Failed to backfill payments for legacy invoices: " + result.Error.Error())
+	} else if result.RowsAffected > 0 {
+		log.Printf("Backfilled payments for %d legacy invoices", result.RowsAffected)
+	}
+}
+
+// importReminderConsents records consent to reminders by SMS and WhatsApp for the customers there are when
+// consent tracking starts, since they have been getting birthday and anniversary texts without having to
+// opt in. Without it every existing customer would silently stop getting them. A STOP a customer already
+// sent is kept, and each import is in the consent history.
+func importReminderConsents(tx *gorm.DB) error {
+	result := tx.Exec(`
+		WITH imported AS (
+			INSERT INTO customer_consents (salon_id, customer_id, channel, purpose, granted, source, recorded_at)
+			SELECT c.salon_id, c.id, ch.channel, ?, true, ?, NOW()
+			FROM customers c
+			CROSS JOIN (VALUES ('sms'), ('whatsapp')) AS ch(channel)
+			ON CONFLICT (customer_id, channel, purpose) DO NOTHING
+			RETURNING salon_id, customer_id, channel, purpose, granted, source, recorded_at
+		)
+		INSERT INTO consent_events (salon_id, customer_id, channel, purpose, granted, source, evidence, recorded_at)
+		SELECT salon_id, customer_id, channel, purpose, granted, source, 'Sent reminders before consent was tracked', recorded_at
+		FROM imported
+	`, models.PurposeReminders, models.ConsentSourceImport)
+	if result.Error == nil {
+		log.Printf("Imported reminder consent for %d customer channels from before consent tracking", result.RowsAffected)
+	}
+	return result.Error
 }
 
 func main() {
@@ -37,6 +163,9 @@ func main() {
 	reminderSvc := services.NewReminderService(config.DB)
 	reminderSvc.StartScheduler()
 
+	// Post recurring expenses (rent, salaries, ...) as they fall due
+	services.NewRecurringExpenseService(config.DB).StartScheduler()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
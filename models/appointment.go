@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Appointment struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	CustomerID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	StaffUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+	StartTime   time.Time `gorm:"index;not null"`
+	EndTime     time.Time `gorm:"index;not null"` // StartTime + sum of service durations
+
+	Status             string `gorm:"type:appointment_status;default:'booked'"`
+	Notes              string
+	CancellationReason string
+
+	// Set once the appointment has been billed
+	InvoiceID *uuid.UUID `gorm:"type:uuid;index"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Services []AppointmentService `gorm:"foreignKey:AppointmentID"`
+}
+
+type AppointmentService struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	AppointmentID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceName   string    `gorm:"not null"`
+	Duration      int       // in minutes, copied from the service at booking time
+	Price         Money     `gorm:"type:decimal(10,2);not null"`
+	Position      int       // order in which the services are performed
+}
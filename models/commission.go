@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CommissionRule sets the commission rate for invoice lines. A rule can target a single employee
+// (UserID) or everyone with a role, and can be narrowed to a service category and item type.
+// The most specific matching rule wins.
+type CommissionRule struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Name     string     `gorm:"not null"`
+	UserID   *uuid.UUID `gorm:"type:uuid;index"`                    // empty applies to every employee
+	Role     string     `gorm:"type:varchar(20)"`                   // empty applies to every role
+	ItemType string     `gorm:"type:varchar(20);default:'service'"` // 'service' or 'product'
+	Category string     // matches Service.Category; empty applies to every category
+	Rate     float64    `gorm:"type:decimal(5,2);not null"` // percentage of line revenue
+	IsActive bool       `gorm:"default:true"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Tiers []CommissionTier `gorm:"foreignKey:RuleID"`
+}
+
+// CommissionTier raises a rule's rate once the employee's revenue for the calendar month reaches MinMonthlyRevenue
+type CommissionTier struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	RuleID            uuid.UUID `gorm:"type:uuid;index;not null"`
+	MinMonthlyRevenue Money     `gorm:"type:decimal(10,2);not null"`
+	Rate              float64   `gorm:"type:decimal(5,2);not null"`
+}
+
+// CommissionStatement is a paid, locked snapshot of an employee's commission for a period
+type CommissionStatement struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID uuid.UUID `gorm:"type:uuid;index;not null"`
+	UserID  uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	PeriodStart time.Time `gorm:"not null"`
+	PeriodEnd   time.Time `gorm:"not null"` // inclusive
+	Revenue     Money     `gorm:"type:decimal(10,2);not null"`
+	Commission  Money     `gorm:"type:decimal(10,2);not null"`
+
+	PaymentReference string
+	LockedByUserID   uuid.UUID `gorm:"type:uuid;not null"`
+	LockedAt         time.Time `gorm:"autoCreateTime"`
+
+	Lines []CommissionStatementLine `gorm:"foreignKey:StatementID"`
+}
+
+// CommissionStatementLine is one invoice line as it was paid in a statement
+type CommissionStatementLine struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	StatementID   uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID     uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceItemID uuid.UUID  `gorm:"type:uuid;index;not null"`
+	RuleID        *uuid.UUID `gorm:"type:uuid"`
+
+	InvoiceNumber string
+	InvoiceDate   time.Time
+	ItemName      string
+	ItemType      string
+	Category      string
+	Quantity      int
+	Revenue       Money   `gorm:"type:decimal(10,2);not null"`
+	Rate          float64 `gorm:"type:decimal(5,2);not null"`
+	Commission    Money   `gorm:"type:decimal(10,2);not null"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What a customer consents to be messaged about
+const (
+	PurposeTransactional = "transactional" // receipts and other messages about their own visits
+	PurposeReminders     = "reminders"     // birthday and anniversary greetings
+	PurposeMarketing     = "marketing"     // offers and promotions
+)
+
+// Where a consent was captured
+const (
+	ConsentSourceInPerson = "in_person" // asked at the salon and recorded by staff
+	ConsentSourceForm     = "form"      // a signed or online form
+	ConsentSourcePhone    = "phone"     // asked over a call
+	ConsentSourceImport   = "import"    // brought over from another system
+	ConsentSourceReply    = "reply"     // the customer replied STOP or START to a text
+)
+
+var (
+	ConsentChannels = []string{"sms", "whatsapp", "email"}
+	ConsentPurposes = []string{PurposeTransactional, PurposeReminders, PurposeMarketing}
+)
+
+// CustomerConsent is a customer's current choice for one channel and purpose. Every change is also
+// kept in ConsentEvent.
+type CustomerConsent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_customer_consents_choice"`
+	Channel    string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_customer_consents_choice"` // sms, whatsapp, email
+	Purpose    string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_customer_consents_choice"` // transactional, reminders, marketing
+	Granted    bool      `gorm:"not null"`
+
+	Source           string     `gorm:"type:varchar(20);not null"`
+	RecordedByUserID *uuid.UUID `gorm:"type:uuid"` // nil when the customer changed it themselves
+	RecordedAt       time.Time  `gorm:"not null"`
+}
+
+// ConsentEvent is one grant or withdrawal of consent. Events are only ever added, so they show when
+// and how a customer agreed to or refused each kind of message.
+type ConsentEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID uuid.UUID `gorm:"type:uuid;index;not null"`
+	Channel    string    `gorm:"type:varchar(20);not null"`
+	Purpose    string    `gorm:"type:varchar(20);not null"`
+	Granted    bool      `gorm:"not null"`
+
+	Source           string     `gorm:"type:varchar(20);not null"`
+	Evidence         string     // e.g. the form reference or the text of the reply
+	RecordedByUserID *uuid.UUID `gorm:"type:uuid"`
+	RecordedAt       time.Time  `gorm:"not null;index"`
+}
+
+// Consented reports whether the customer may be messaged on channel for purpose. Without a recorded
+// choice, transactional messages are allowed and reminders and marketing are not. Customers who existed
+// when consent tracking started were given imported consent to reminders by SMS and WhatsApp, so only
+// customers added since need to opt in to greetings. Consents must be loaded.
+func (c Customer) Consented(channel, purpose string) bool {
+	for _, consent := range c.Consents {
+		if consent.Channel == channel && consent.Purpose == purpose {
+			return consent.Granted
+		}
+	}
+	return purpose == PurposeTransactional
+}
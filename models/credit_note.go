@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreditNote records a full or partial refund against an invoice. The invoice itself is never
+// altered beyond its refund totals, so the original bill stays on record.
+type CreditNote struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_credit_notes_salon_number"`
+	InvoiceID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	CreditNoteNumber string    `gorm:"not null;uniqueIndex:idx_credit_notes_salon_number"`
+	CreditNoteDate   time.Time `gorm:"index;not null"`
+	Amount           Money     `gorm:"type:decimal(10,2);not null"`
+	RefundMethod     string    `gorm:"type:varchar(20);not null"`
+	Reason           string    `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	Items []CreditNoteItem `gorm:"foreignKey:CreditNoteID"`
+}
+
+// CreditNoteItem is the refunded part of one invoice line
+type CreditNoteItem struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CreditNoteID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceItemID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceName   string    `gorm:"not null"`
+	Quantity      int       `gorm:"not null"`
+	Amount        Money     `gorm:"type:decimal(10,2);not null"`
+}
@@ -17,10 +17,16 @@ type Customer struct {
 	Birthday    *time.Time
 	Anniversary *time.Time
 	Notes       string
-	TotalVisits int     `gorm:"default:0"`
-	TotalSpent  float64 `gorm:"type:decimal(10,2);default:0.0"`
+	TotalVisits int   `gorm:"default:0"`
+	TotalSpent  Money `gorm:"type:decimal(10,2);default:0.0"`
 	LastVisit   *time.Time
 	IsActive    bool `gorm:"default:true"`
 
-	Invoices []Invoice `gorm:"foreignKey:CustomerID"`
+	// Cached balance of the loyalty ledger (LoyaltyTransaction)
+	LoyaltyPoints int `gorm:"default:0"`
+	// Cached balance of the wallet ledger (WalletTransaction): advances and refunds held as credit
+	WalletBalance Money `gorm:"type:decimal(10,2);default:0.0"`
+
+	Invoices []Invoice         `gorm:"foreignKey:CustomerID"`
+	Consents []CustomerConsent `gorm:"foreignKey:CustomerID"`
 }
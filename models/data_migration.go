@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// DataMigration records a one-off change to existing rows that has been applied, so that it runs once
+type DataMigration struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
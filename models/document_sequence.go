@@ -0,0 +1,13 @@
+package models
+
+import "github.com/google/uuid"
+
+// DocumentSequence holds the last number issued in a salon's numbering series (invoices,
+// credit notes) for one financial year. Rows are incremented in place so numbers never
+// repeat or skip, and a new year starts a new row at 1.
+type DocumentSequence struct {
+	SalonID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Series     string    `gorm:"type:varchar(30);primaryKey"`
+	FiscalYear string    `gorm:"type:varchar(10);primaryKey"`
+	LastValue  int64     `gorm:"not null;default:0"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Expense is money the salon spent. Amount is what was paid; TaxAmount is the GST in it that
+// can be claimed back, so the cost to the business is Amount less TaxAmount.
+type Expense struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	Category        string    `gorm:"type:varchar(30);index;not null"` // rent, salaries, utilities, product_purchases, ...
+	Description     string    `gorm:"not null"`
+	Amount          Money     `gorm:"type:decimal(10,2);not null"`
+	TaxAmount       Money     `gorm:"type:decimal(10,2);default:0.0"`
+	ExpenseDate     time.Time `gorm:"index;not null"`
+	PaymentMethod   string    `gorm:"type:varchar(20)"`
+	Vendor          string
+	Reference       string // the vendor's bill or receipt number
+	Notes           string
+	SupplierID      *uuid.UUID `gorm:"type:uuid;index"`
+	PurchaseOrderID *uuid.UUID `gorm:"type:uuid;index"`
+
+	RecurringExpenseID *uuid.UUID `gorm:"type:uuid;index"` // set when posted by a recurring schedule
+	CreatedByUserID    *uuid.UUID `gorm:"type:uuid"`       // empty when posted by a recurring schedule
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Attachments []ExpenseAttachment `gorm:"foreignKey:ExpenseID"`
+}
+
+// ExpenseAttachment is a scanned bill or receipt kept with an expense
+type ExpenseAttachment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ExpenseID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	FileName    string    `gorm:"not null"`
+	ContentType string    `gorm:"not null"`
+	Size        int       `gorm:"not null"`
+	Data        []byte    `gorm:"type:bytea;not null" json:"-"` // served by GetExpenseAttachment
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// RecurringExpense posts the same expense on a schedule, e.g. rent on the 1st of every month.
+// NextDueDate is the next occurrence not yet posted; PostedCount is how many have been.
+type RecurringExpense struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	Category      string    `gorm:"type:varchar(30);not null"`
+	Description   string    `gorm:"not null"`
+	Amount        Money     `gorm:"type:decimal(10,2);not null"`
+	TaxAmount     Money     `gorm:"type:decimal(10,2);default:0.0"`
+	PaymentMethod string    `gorm:"type:varchar(20)"`
+	Vendor        string
+	SupplierID    *uuid.UUID `gorm:"type:uuid"`
+
+	Frequency   string     `gorm:"type:varchar(20);not null"` // weekly, monthly, quarterly, yearly
+	StartDate   time.Time  `gorm:"not null"`
+	EndDate     *time.Time // last day an occurrence may fall on; empty runs until stopped
+	NextDueDate time.Time  `gorm:"index;not null"`
+	PostedCount int        `gorm:"default:0"`
+	IsActive    bool       `gorm:"default:true"`
+
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime"`
+}
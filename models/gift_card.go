@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GiftCard is a prepaid voucher sold on an invoice and redeemed, in part or in full, as a
+// payment on later invoices. Balance is cached from the card's GiftCardTransaction ledger.
+type GiftCard struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_gift_cards_salon_code"`
+	Code         string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_gift_cards_salon_code"`
+	InitialValue Money      `gorm:"type:decimal(10,2);not null"`
+	Balance      Money      `gorm:"type:decimal(10,2);not null"`
+	Status       string     `gorm:"type:varchar(20);default:'active';index"` // active, cancelled or expired
+	IssuedAt     time.Time  `gorm:"not null"`
+	ExpiresAt    *time.Time `gorm:"index"`
+
+	// Invoice the card was sold on and the customer who bought it
+	InvoiceID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceItemID uuid.UUID `gorm:"type:uuid;not null"`
+	CustomerID    uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	Transactions []GiftCardTransaction `gorm:"foreignKey:GiftCardID"`
+}
+
+// GiftCardTransaction is one movement of a gift card's balance. Amount is signed; Balance is
+// the card's balance after the movement.
+type GiftCardTransaction struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	GiftCardID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	Type            string     `gorm:"type:varchar(20);not null"` // issue, redeem, cancel, expire
+	Amount          Money      `gorm:"type:decimal(10,2);not null"`
+	Balance         Money      `gorm:"type:decimal(10,2);not null"`
+	InvoiceID       *uuid.UUID `gorm:"type:uuid;index"`
+	PaymentID       *uuid.UUID `gorm:"type:uuid"`
+	CreatedByUserID *uuid.UUID `gorm:"type:uuid"`
+	Description     string
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
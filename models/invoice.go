@@ -8,32 +8,98 @@ import (
 
 type Invoice struct {
 	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_invoices_salon_number"`
 	CreatedByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
 
-	InvoiceNumber string    `gorm:"uniqueIndex;not null"`
+	InvoiceNumber string    `gorm:"uniqueIndex:idx_invoices_salon_number;not null"` // DRAFT-... until finalized
 	CustomerID    uuid.UUID `gorm:"type:uuid;index;not null"`
 	InvoiceDate   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 
-	Subtotal float64 `gorm:"type:decimal(10,2);not null"`
-	Discount float64 `gorm:"type:decimal(10,2);default:0.0"`
-	Tax      float64 `gorm:"type:decimal(10,2);default:0.0"`
-	Total    float64 `gorm:"type:decimal(10,2);not null"`
+	Subtotal Money   `gorm:"type:decimal(10,2);not null"`
+	Discount Money   `gorm:"type:decimal(10,2);default:0.0"`
+	Tax      float64 `gorm:"type:decimal(10,2);default:0.0"` // flat rate (%) for lines whose service has no tax class
+	Total    Money   `gorm:"type:decimal(10,2);not null"`
 
-	PaymentStatus string  `gorm:"type:payment_status;default:'unpaid'"`
-	PaidAmount    float64 `gorm:"type:decimal(10,2);default:0.0"`
+	// Loyalty points redeemed at checkout are a second discount, shared across lines with
+	// Discount. LoyaltyPointsEarned is credited to the customer when the invoice is finalized.
+	LoyaltyPointsRedeemed int   `gorm:"default:0"`
+	LoyaltyDiscount       Money `gorm:"type:decimal(10,2);default:0.0"`
+	LoyaltyPointsEarned   int   `gorm:"default:0"`
+
+	// Promotion code applied and the discount it produced, on top of Discount
+	PromotionID       *uuid.UUID `gorm:"type:uuid;index"`
+	PromotionCode     string     `gorm:"type:varchar(30)"`
+	PromotionDiscount Money      `gorm:"type:decimal(10,2);default:0.0"`
+
+	// Tax charged, summed from the items. PricesIncludeTax and PlaceOfSupply are copied
+	// from the salon when the invoice is priced so later setting changes do not alter it.
+	TaxAmount        Money  `gorm:"type:decimal(10,2);default:0.0"`
+	CGSTAmount       Money  `gorm:"type:decimal(10,2);default:0.0"`
+	SGSTAmount       Money  `gorm:"type:decimal(10,2);default:0.0"`
+	IGSTAmount       Money  `gorm:"type:decimal(10,2);default:0.0"`
+	PricesIncludeTax bool   `gorm:"default:false"`
+	PlaceOfSupply    string `gorm:"type:varchar(2)"` // GST state code; IGST applies when it differs from the salon's
+
+	// draft -> finalized -> void. Only drafts can be edited; rows created before
+	// the lifecycle existed default to finalized.
+	Status         string `gorm:"type:invoice_status;default:'finalized';index"`
+	FinalizedAt    *time.Time
+	VoidedAt       *time.Time
+	VoidedByUserID *uuid.UUID `gorm:"type:uuid"`
+	VoidReason     string
+
+	// Derived from Payments; never set directly by clients
+	PaymentStatus string `gorm:"type:payment_status;default:'unpaid'"`
+	PaidAmount    Money  `gorm:"type:decimal(10,2);default:0.0"`
 	PaymentMethod string
 	Notes         string
 
-	Items []InvoiceItem `gorm:"foreignKey:InvoiceID"`
+	// Sum of credit notes issued against the invoice
+	RefundedAmount Money `gorm:"type:decimal(10,2);default:0.0"`
+
+	Items    []InvoiceItem `gorm:"foreignKey:InvoiceID"`
+	Payments []Payment     `gorm:"foreignKey:InvoiceID"`
+
+	CreditNotes []CreditNote `gorm:"foreignKey:InvoiceID"`
 }
 
 type InvoiceItem struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	InvoiceID   uuid.UUID `gorm:"type:uuid;index;not null"`
-	ServiceID   uuid.UUID `gorm:"type:uuid;index;not null"`
-	ServiceName string    `gorm:"not null"`
-	Quantity    int       `gorm:"default:1"`
-	UnitPrice   float64   `gorm:"type:decimal(10,2);not null"`
-	TotalPrice  float64   `gorm:"type:decimal(10,2);not null"`
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	InvoiceID   uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ServiceID   *uuid.UUID `gorm:"type:uuid;index"`
+	ServiceName string     `gorm:"not null"` // or the package or product name on those lines
+	Quantity    int        `gorm:"default:1"`
+	UnitPrice   Money      `gorm:"type:decimal(10,2);not null"`
+	TotalPrice  Money      `gorm:"type:decimal(10,2);not null"`
+
+	// A line bills a service or sells a package, gift card or product. Service lines covered by
+	// a customer's package are priced at zero and record the package whose credits paid for them.
+	ItemType          string     `gorm:"type:varchar(20);default:'service'"`
+	PackageID         *uuid.UUID `gorm:"type:uuid"`
+	CustomerPackageID *uuid.UUID `gorm:"type:uuid;index"`
+	ProductID         *uuid.UUID `gorm:"type:uuid;index"`
+
+	// Tax for the line. A promotion discounts only the lines it targets (PromotionDiscount);
+	// the invoice discount is then shared across lines by value before tax. DiscountAmount is
+	// both together, and LineTotal is what the customer pays for the line, TaxableValue + TaxAmount.
+	TaxClassID        *uuid.UUID `gorm:"type:uuid"`
+	TaxCode           string     `gorm:"type:varchar(10)"`
+	TaxRate           float64    `gorm:"type:decimal(5,2);default:0"`
+	PromotionDiscount Money      `gorm:"type:decimal(10,2);default:0.0"`
+	DiscountAmount    Money      `gorm:"type:decimal(10,2);default:0.0"`
+	TaxableValue      Money      `gorm:"type:decimal(10,2);default:0.0"`
+	CGSTAmount        Money      `gorm:"type:decimal(10,2);default:0.0"`
+	SGSTAmount        Money      `gorm:"type:decimal(10,2);default:0.0"`
+	IGSTAmount        Money      `gorm:"type:decimal(10,2);default:0.0"`
+	TaxAmount         Money      `gorm:"type:decimal(10,2);default:0.0"`
+	LineTotal         Money      `gorm:"type:decimal(10,2);default:0.0"`
+
+	// Units returned through credit notes; reports count Quantity - RefundedQuantity
+	RefundedQuantity int `gorm:"default:0"`
+
+	// Back-bar product the line used up
+	Consumptions []InvoiceItemConsumption `gorm:"foreignKey:InvoiceItemID"`
+
+	// Staff member who performed the service; credited in employee reports
+	PerformedByUserID *uuid.UUID `gorm:"type:uuid;index"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltySettings configures a salon's points program. Customers earn PointsPerUnit points for
+// every unit of currency spent before tax, times the multiplier of the service's category, and
+// can redeem points on later invoices at PointValue each. With ExpiryMonths set, points expire
+// that many months after they were earned, oldest first.
+type LoyaltySettings struct {
+	SalonID       uuid.UUID `gorm:"type:uuid;primary_key"`
+	Enabled       bool      `gorm:"default:false"`
+	PointsPerUnit float64   `gorm:"type:decimal(10,4);not null;default:0"`
+	PointValue    Money     `gorm:"type:decimal(10,2);not null;default:0"`
+	ExpiryMonths  int       `gorm:"default:0"` // 0 never expires
+
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Multipliers []LoyaltyMultiplier `gorm:"foreignKey:SalonID;references:SalonID"`
+}
+
+// LoyaltyMultiplier boosts (or reduces) the points earned on services of one category
+type LoyaltyMultiplier struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	Category   string    `gorm:"not null"`
+	Multiplier float64   `gorm:"type:decimal(5,2);not null"`
+}
+
+// LoyaltyTransaction is one entry of a customer's points ledger. Points is signed; Balance is
+// the customer's balance after the entry.
+type LoyaltyTransaction struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID   uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID    *uuid.UUID `gorm:"type:uuid;index"`
+	CreditNoteID *uuid.UUID `gorm:"type:uuid"`
+	Type         string     `gorm:"type:varchar(20);not null"` // earn, redeem, reverse, restore, expire
+	Points       int        `gorm:"not null"`
+	Balance      int        `gorm:"not null"`
+	Description  string
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
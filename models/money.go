@@ -0,0 +1,209 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (paise), so sums and differences are exact. It is stored
+// in decimal(10,2) columns and written to JSON as a plain number with two decimals, the same
+// shape clients received when amounts were float64.
+//
+// Rounding rules: every derived amount (a discount share, a tax, a refund share) is rounded to
+// the paisa, half away from zero, on the line it belongs to. Invoice totals are then the sum of
+// the rounded lines and are never rounded again, so printed lines always add up to stored totals.
+type Money int64
+
+// ParseMoney reads a decimal amount such as "1250", "-3.5" or "99.999". Digits beyond the
+// second decimal place are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty amount")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	for _, r := range whole + fraction {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	var units int64
+	if whole != "" {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || n > math.MaxInt64/100-1 {
+			return 0, fmt.Errorf("amount %q out of range", s)
+		}
+		units = n * 100
+	}
+
+	padded := fraction + "00"
+	cents, _ := strconv.ParseInt(padded[:2], 10, 64)
+	units += cents
+	if len(fraction) > 2 && fraction[2] >= '5' {
+		units++
+	}
+
+	if negative {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+// MoneyFromFloat converts a float amount, rounding to the paisa. Only for values that are
+// already approximate, such as averages computed by the database.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// String formats the amount with exactly two decimals, e.g. "-12.50"
+func (m Money) String() string {
+	sign := ""
+	units := int64(m)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/100, units%100)
+}
+
+// Float64 returns the amount in major units. Use it for ratios and percentages, never to do
+// arithmetic that is stored.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// MulDiv returns m * num / den rounded half away from zero, without intermediate overflow.
+// It is the building block for shares and percentages.
+func (m Money) MulDiv(num, den int64) Money {
+	if den == 0 {
+		return 0
+	}
+
+	negative := false
+	a, n, d := int64(m), num, den
+	if a < 0 {
+		a, negative = -a, !negative
+	}
+	if n < 0 {
+		n, negative = -n, !negative
+	}
+	if d < 0 {
+		d, negative = -d, !negative
+	}
+
+	hi, lo := bits.Mul64(uint64(a), uint64(n))
+	lo, carry := bits.Add64(lo, uint64(d)/2, 0)
+	hi += carry
+	if hi >= uint64(d) {
+		panic("models.Money: MulDiv overflow")
+	}
+	q, _ := bits.Div64(hi, lo, uint64(d))
+
+	if negative {
+		return -Money(q)
+	}
+	return Money(q)
+}
+
+// Percent returns rate percent of m, rounded to the paisa. Rates are decimal(5,2) percentages,
+// so they are applied exactly in hundredths of a percent.
+func (m Money) Percent(rate float64) Money {
+	return m.MulDiv(rateHundredths(rate), 100*100)
+}
+
+// WithoutPercent removes an included percentage: the amount that, with rate percent added,
+// makes m. Used to split tax-inclusive prices.
+func (m Money) WithoutPercent(rate float64) Money {
+	return m.MulDiv(100*100, 100*100+rateHundredths(rate))
+}
+
+// Share returns m * part / whole, rounded to the paisa; zero when whole is zero
+func (m Money) Share(part, whole Money) Money {
+	return m.MulDiv(int64(part), int64(whole))
+}
+
+// rateHundredths converts a percentage with at most two decimals to hundredths of a percent
+func rateHundredths(rate float64) int64 {
+	return int64(math.Round(rate * 100))
+}
+
+// Value stores the amount as a decimal string so the numeric column receives it exactly
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a numeric column
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		*m = MoneyFromFloat(v)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Money", value)
+}
+
+func (m *Money) scanString(s string) error {
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// MarshalJSON writes the amount as a JSON number with two decimals
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %s", data)
+		}
+		*m = MoneyFromFloat(f)
+		return nil
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
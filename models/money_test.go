@@ -0,0 +1,77 @@
+package models
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"1250", 125000, false},
+		{"12.5", 1250, false},
+		{"12.50", 1250, false},
+		{".5", 50, false},
+		{"7.", 700, false},
+		{" +3.25 ", 325, false},
+		{"-3.5", -350, false},
+		{"0.004", 0, false},
+		{"0.005", 1, false},
+		{"99.999", 10000, false},
+		{"-0.005", -1, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{".", 0, true},
+		{"1,000", 0, true},
+		{"12a", 0, true},
+		{"1e3", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulDiv(t *testing.T) {
+	tests := []struct {
+		m        Money
+		num, den int64
+		want     Money
+	}{
+		{1000, 1, 3, 333},
+		{2000, 1, 3, 667},
+		{1, 1, 2, 1},   // half rounds away from zero
+		{-1, 1, 2, -1}, // also when negative
+		{-1000, 1, 3, -333},
+		{1000, -1, 3, -333},
+		{1000, 1, -3, -333},
+		{-1000, -1, -3, -333},
+		{1000, 0, 7, 0},
+		{1000, 5, 0, 0}, // zero denominator
+		// The product overflows int64 but the result does not
+		{Money(1) << 62, 1 << 40, 1 << 41, Money(1) << 61},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.MulDiv(tt.num, tt.den); got != tt.want {
+			t.Errorf("Money(%d).MulDiv(%d, %d) = %d, want %d", tt.m, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulDivOverflowPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MulDiv did not panic on a result that overflows")
+		}
+	}()
+	Money(1<<62).MulDiv(4, 1)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServicePackage is sold ahead of the services it covers: a bundle of credits such as
+// "10 haircuts for the price of 8", or a membership. Selling one on an invoice gives the
+// customer a CustomerPackage whose credits pay for covered services until it expires.
+type ServicePackage struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name         string    `gorm:"not null"`
+	Description  string
+	Kind         string     `gorm:"type:varchar(20);default:'package'"` // package or membership
+	Price        Money      `gorm:"type:decimal(10,2);not null"`
+	ValidityDays int        `gorm:"default:0"` // 0 never expires
+	TaxClassID   *uuid.UUID `gorm:"type:uuid"`
+	IsActive     bool       `gorm:"default:true"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	Items []ServicePackageItem `gorm:"foreignKey:PackageID"`
+}
+
+// ServicePackageItem is a service a package covers and how many times
+type ServicePackageItem struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PackageID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceID uuid.UUID `gorm:"type:uuid;not null"`
+	Quantity  int       `gorm:"not null"` // 0 unlimited while the package is valid
+}
+
+// CustomerPackage is a package sold to a customer. Name, Kind and the credits are copied from
+// the definition at sale time so later edits do not change what the customer bought.
+type CustomerPackage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID    uuid.UUID  `gorm:"type:uuid;index;not null"`
+	PackageID     uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID     uuid.UUID  `gorm:"type:uuid;index;not null"` // invoice the package was sold on
+	InvoiceItemID uuid.UUID  `gorm:"type:uuid;not null"`
+	Name          string     `gorm:"not null"`
+	Kind          string     `gorm:"type:varchar(20);not null"`
+	PurchasedAt   time.Time  `gorm:"not null"`
+	ExpiresAt     *time.Time `gorm:"index"`
+	Status        string     `gorm:"type:varchar(20);default:'active'"` // active or cancelled (sale voided or refunded)
+
+	Credits []CustomerPackageCredit `gorm:"foreignKey:CustomerPackageID"`
+}
+
+// CustomerPackageCredit tracks the use of one covered service
+type CustomerPackageCredit struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CustomerPackageID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceID         uuid.UUID `gorm:"type:uuid;not null"`
+	ServiceName       string    `gorm:"not null"`
+	Quantity          int       `gorm:"not null"` // 0 unlimited
+	Used              int       `gorm:"default:0"`
+}
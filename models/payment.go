@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payment is one tender taken against an invoice. An invoice paid half cash, half UPI has two.
+// Invoice.PaidAmount, PaymentStatus and PaymentMethod are derived from these rows.
+type Payment struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Amount    Money     `gorm:"type:decimal(10,2);not null"`
+	Method    string    `gorm:"type:varchar(20);not null"` // cash, card, upi, wallet
+	Reference string    // card slip, UPI transaction ID, etc.
+	PaidAt    time.Time `gorm:"index;not null"`
+
+	TakenByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
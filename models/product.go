@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Product is a retail item sold over the counter or used at the back bar. StockQuantity is
+// kept in step with the StockMovement ledger; every change to it is recorded there.
+type Product struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name        string    `gorm:"not null"`
+	Description string
+	Brand       string
+	Category    string `gorm:"default:'Retail'"`
+	SKU         string `gorm:"type:varchar(50);index"`
+	Barcode     string `gorm:"type:varchar(50);index"`
+
+	CostPrice  Money      `gorm:"type:decimal(10,2);default:0.0"`
+	SalePrice  Money      `gorm:"type:decimal(10,2);not null"`
+	TaxClassID *uuid.UUID `gorm:"type:uuid"`
+
+	// Stock is counted in units sold (a tube, a bottle). Services use product in UsageUnit, e.g.
+	// ml; UnitSize is how much of it one stock unit holds, and 0 means services count in stock units.
+	StockQuantity     float64 `gorm:"type:decimal(12,3);default:0"`
+	LowStockThreshold float64 `gorm:"type:decimal(12,3);default:0"` // alert at or below this; 0 no alert
+	UsageUnit         string  `gorm:"type:varchar(10)"`
+	UnitSize          float64 `gorm:"type:decimal(10,3);default:0"`
+	IsActive          bool    `gorm:"default:true"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// StockMovement is one change to a product's stock: Quantity is signed and Balance is the
+// stock left after it.
+type StockMovement struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ProductID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	Type            string     `gorm:"type:varchar(20);not null"` // opening, sale, consumption, return, void, adjustment, purchase
+	Quantity        float64    `gorm:"type:decimal(12,3);not null"`
+	Balance         float64    `gorm:"type:decimal(12,3);not null"`
+	UnitCost        Money      `gorm:"type:decimal(10,2);default:0.0"`
+	InvoiceID       *uuid.UUID `gorm:"type:uuid;index"`
+	InvoiceItemID   *uuid.UUID `gorm:"type:uuid;index"`
+	CreditNoteID    *uuid.UUID `gorm:"type:uuid"`
+	PurchaseOrderID *uuid.UUID `gorm:"type:uuid;index"`
+	Reason          string
+	CreatedByUserID *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+}
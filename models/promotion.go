@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Promotion is a discount code applied to invoices. The rules are checked server-side when the
+// code is used, and each invoice records the promotion and the discount it produced so
+// campaigns can be measured.
+type Promotion struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_promotions_salon_code"`
+	Code        string    `gorm:"type:varchar(30);not null;uniqueIndex:idx_promotions_salon_code"`
+	Name        string    `gorm:"not null"`
+	Description string
+
+	DiscountType string  `gorm:"type:varchar(10);not null"` // percent or fixed
+	Percent      float64 `gorm:"type:decimal(5,2);default:0"`
+	Amount       Money   `gorm:"type:decimal(10,2);default:0.0"`
+	MaxDiscount  Money   `gorm:"type:decimal(10,2);default:0.0"` // caps a percent discount; 0 no cap
+	MinSpend     Money   `gorm:"type:decimal(10,2);default:0.0"` // invoice subtotal required
+
+	ValidFrom             *time.Time
+	ValidTo               *time.Time // last day the code can be used
+	UsageLimit            int        `gorm:"default:0"` // invoices in total; 0 unlimited
+	UsageLimitPerCustomer int        `gorm:"default:0"`
+	BirthdayMonthOnly     bool       `gorm:"default:false"`
+	IsActive              bool       `gorm:"default:true"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	// Services and categories the discount applies to; none means every line but gift cards
+	Targets []PromotionTarget `gorm:"foreignKey:PromotionID"`
+}
+
+// PromotionTarget limits a promotion to one service or one service category
+type PromotionTarget struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PromotionID uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ServiceID   *uuid.UUID `gorm:"type:uuid"`
+	Category    string
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ServiceRecipeItem is product a service uses up each time it is performed, measured in the
+// product's UsageUnit
+type ServiceRecipeItem struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ServiceID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null"`
+	Quantity  float64   `gorm:"type:decimal(10,3);not null"`
+}
+
+// InvoiceItemConsumption is product an invoice line used, from the service's recipe or as
+// reported for that line. Stock is taken and Cost recorded when the invoice is finalized.
+type InvoiceItemConsumption struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	InvoiceItemID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProductID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	Quantity      float64   `gorm:"type:decimal(10,3);not null"` // in the product's UsageUnit
+	Cost          Money     `gorm:"type:decimal(10,2);default:0.0"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -11,3 +13,27 @@ type ReminderTemplate struct {
 	Message  string    `gorm:"type:text;not null"`
 	IsActive bool      `gorm:"default:true"`
 }
+
+// ReminderLog records a birthday or anniversary message to a customer. There is one row per
+// customer, event and year, which is what stops the scheduler messaging them again on each of
+// the days the event is upcoming.
+type ReminderLog struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_reminder_logs_occurrence"`
+	CustomerID     uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_reminder_logs_occurrence"`
+	EventType      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_reminder_logs_occurrence"` // birthday, anniversary
+	OccurrenceYear int       `gorm:"not null;uniqueIndex:idx_reminder_logs_occurrence"`
+	EventDate      time.Time `gorm:"type:date;not null"`
+
+	Channel   string `gorm:"type:varchar(20)"` // sms, whatsapp, email
+	Recipient string
+	Message   string `gorm:"type:text"`
+	Status    string `gorm:"type:varchar(20);index;not null"` // sending, then Twilio's status (queued, sent, delivered, failed, ...) or send_failed
+	TwilioSID string `gorm:"type:varchar(64);index"`
+	Error     string
+	Attempts  int `gorm:"default:0"`
+	SentAt    *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
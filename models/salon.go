@@ -1,9 +1,15 @@
 package models
 
 import (
+	"strings"
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// DefaultChannelOrder is the order customers are tried on when a salon has not set its own
+const DefaultChannelOrder = "whatsapp,sms,email"
+
 type Salon struct {
 	ID                    uuid.UUID `gorm:"type:uuid;primary_key"`
 	Name                  string    `gorm:"not null"`
@@ -13,10 +19,68 @@ type Salon struct {
 	AnniversaryReminders  bool  `gorm:"default:true"`
 	WhatsAppNotifications bool  `gorm:"default:false"`
 	SMSNotifications      bool  `gorm:"default:false"`
+	EmailNotifications    bool  `gorm:"default:false"`
+	ReceiptNotifications  bool  `gorm:"default:false"` // message customers a receipt link after billing
+
+	// Channels tried in turn for each message, comma separated; the first enabled channel the
+	// customer can be reached on is used, and the next ones if sending on it fails
+	NotificationChannelOrder string `gorm:"type:varchar(50);default:'whatsapp,sms,email'"`
+
+	// Booking settings used by appointments and the availability search
+	BookingBufferMinutes int `gorm:"default:0"`  // gap kept free after every booking
+	SlotIntervalMinutes  int `gorm:"default:15"` // granularity of offered start times
+
+	// Document numbering. Formats accept {SALON}, {FY}, {YYYY}, {YY}, {MM} and {SEQ:05};
+	// sequences restart when the financial year starting in FiscalYearStartMonth rolls over.
+	Code                   string `gorm:"type:varchar(10)"` // {SALON}; derived from the name when empty
+	InvoiceNumberFormat    string `gorm:"default:'{SALON}-{FY}-{SEQ:05}'"`
+	CreditNoteNumberFormat string `gorm:"default:'{SALON}-CN-{FY}-{SEQ:05}'"`
+	FiscalYearStartMonth   int    `gorm:"default:4"`
+
+	// GST registration. StateCode is the two-digit GST state code (the first two digits of the GSTIN)
+	// used to decide between CGST+SGST and IGST. With PricesIncludeTax service prices are gross.
+	GSTIN            string `gorm:"type:varchar(15)"`
+	StateCode        string `gorm:"type:varchar(2)"`
+	PricesIncludeTax bool   `gorm:"default:false"`
+
+	// Gift cards sold on invoices expire this many months after issue; 0 never expires
+	GiftCardValidityMonths int `gorm:"default:12"`
 
 	Users             []User             `gorm:"foreignKey:SalonID"`
 	Customers         []Customer         `gorm:"foreignKey:SalonID"`
 	Services          []Service          `gorm:"foreignKey:SalonID"`
 	Invoices          []Invoice          `gorm:"foreignKey:SalonID"`
 	ReminderTemplates []ReminderTemplate `gorm:"foreignKey:SalonID"`
+	Appointments      []Appointment      `gorm:"foreignKey:SalonID"`
+}
+
+// SalonLogo is the image printed on a salon's invoices. It is kept out of Salon so loading a salon
+// does not read the image with it.
+type SalonLogo struct {
+	SalonID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Data        []byte    `gorm:"type:bytea;not null"`
+	ContentType string    `gorm:"type:varchar(50)"`
+	UpdatedAt   time.Time
+}
+
+// ChannelOrder returns the salon's channels in order of preference. Channels left out of
+// NotificationChannelOrder follow in the default order, so every channel appears once.
+func (s Salon) ChannelOrder() []string {
+	known := make(map[string]bool)
+	for _, channel := range strings.Split(DefaultChannelOrder, ",") {
+		known[channel] = true
+	}
+
+	var order []string
+	seen := make(map[string]bool)
+	for _, list := range []string{s.NotificationChannelOrder, DefaultChannelOrder} {
+		for _, channel := range strings.Split(list, ",") {
+			channel = strings.ToLower(strings.TrimSpace(channel))
+			if known[channel] && !seen[channel] {
+				seen[channel] = true
+				order = append(order, channel)
+			}
+		}
+	}
+	return order
 }
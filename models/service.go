@@ -9,10 +9,16 @@ type Service struct {
 	SalonID     uuid.UUID `gorm:"type:uuid;index;not null"`
 	Name        string    `gorm:"not null"`
 	Description string
-	Price       float64 `gorm:"type:decimal(10,2);not null"`
-	Duration    int     // in minutes
-	Category    string  `gorm:"default:'General'"`
-	IsActive    bool    `gorm:"default:true"`
+	Price       Money  `gorm:"type:decimal(10,2);not null"`
+	Duration    int    // in minutes
+	Category    string `gorm:"default:'General'"`
+	IsActive    bool   `gorm:"default:true"`
+
+	// GST rate billed on the service; services without one use the invoice's flat Tax rate
+	TaxClassID *uuid.UUID `gorm:"type:uuid;index"`
+
+	// Products used up each time the service is performed
+	Recipe []ServiceRecipeItem `gorm:"foreignKey:ServiceID"`
 
 	InvoiceItems []InvoiceItem `gorm:"foreignKey:ServiceID"`
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supplier is a vendor the salon buys products from
+type Supplier struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name        string    `gorm:"not null"`
+	ContactName string
+	Phone       string
+	Email       string
+	GSTIN       string `gorm:"type:varchar(15)"`
+	Address     string
+	Notes       string
+	IsActive    bool `gorm:"default:true"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// PurchaseOrder is stock ordered from a supplier. It is edited as a draft, then ordered and
+// received line by line, possibly over several deliveries.
+type PurchaseOrder struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	SupplierID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	OrderNumber     string    `gorm:"type:varchar(50);not null"`
+	Status          string    `gorm:"type:varchar(20);default:'draft'"` // draft, ordered, partial, received, closed, cancelled
+	OrderedAt       *time.Time
+	ExpectedAt      *time.Time
+	ReceivedAt      *time.Time // when the last line was received in full
+	Total           Money      `gorm:"type:decimal(10,2);default:0.0"`
+	Notes           string
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Items []PurchaseOrderItem `gorm:"foreignKey:PurchaseOrderID"`
+}
+
+// PurchaseOrderItem is a product ordered, in stock units, and how much of it has arrived
+type PurchaseOrderItem struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PurchaseOrderID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProductID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	ProductName      string    `gorm:"not null"`
+	Quantity         float64   `gorm:"type:decimal(12,3);not null"`
+	ReceivedQuantity float64   `gorm:"type:decimal(12,3);default:0"`
+	UnitCost         Money     `gorm:"type:decimal(10,2);not null"`
+	TotalCost        Money     `gorm:"type:decimal(10,2);not null"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaxClass is a GST rate that services are billed at, e.g. "GST 18%" or "Exempt" (rate 0).
+// Intra-state sales split the rate equally into CGST and SGST; inter-state sales charge it as IGST.
+type TaxClass struct {
+	ID      uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name    string    `gorm:"not null"`
+	Rate    float64   `gorm:"type:decimal(5,2);not null;default:0"` // percent
+	Code    string    `gorm:"type:varchar(10)"`                     // HSN/SAC code printed on invoices
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
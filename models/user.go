@@ -23,6 +23,9 @@ type User struct {
 
 	Salon Salon `gorm:"foreignKey:SalonID"`
 
+	// Weekly shifts in the same shape as Salon.WorkingHours; empty means the salon's hours
+	WorkingHours JSONB `gorm:"type:jsonb;default:'{}'"`
+
 	LastLogin *time.Time
 	IsActive  bool `gorm:"default:true"`
 
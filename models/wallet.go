@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletTransaction is one movement of a customer's wallet: an advance paid in, a refund
+// credited instead of paid out, or a payment made from the balance. Amount is signed; Balance
+// is the customer's wallet balance after the movement (cached on Customer.WalletBalance).
+type WalletTransaction struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	Type         string    `gorm:"type:varchar(20);not null"` // deposit, refund, payment
+	Amount       Money     `gorm:"type:decimal(10,2);not null"`
+	Balance      Money     `gorm:"type:decimal(10,2);not null"`
+	Method       string    `gorm:"type:varchar(20)"` // how a deposit was paid: cash, card, upi, wallet
+	Reference    string
+	InvoiceID    *uuid.UUID `gorm:"type:uuid;index"`
+	PaymentID    *uuid.UUID `gorm:"type:uuid"`
+	CreditNoteID *uuid.UUID `gorm:"type:uuid"`
+	Description  string
+
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt       time.Time `gorm:"autoCreateTime;index"`
+}
@@ -36,6 +36,19 @@ func SetupRouter() *gin.Engine {
 		auth.GET("/me", controllers.Me)
 	}
 
+	// Signed customer links; no login
+	public := r.Group("/public")
+	{
+		public.GET("/invoices/:token", controllers.GetPublicInvoice)
+	}
+
+	// Twilio callbacks; authenticated by the request signature
+	twilioHooks := r.Group("/webhooks/twilio", utils.TwilioSignatureMiddleware())
+	{
+		twilioHooks.POST("/status", controllers.TwilioStatusWebhook)
+		twilioHooks.POST("/inbound", controllers.TwilioInboundWebhook)
+	}
+
 	api := r.Group("/api")
 	api.Use(utils.AuthMiddleware())
 	{
@@ -47,6 +60,12 @@ func SetupRouter() *gin.Engine {
 			customers.GET("/:id", controllers.GetCustomer)
 			customers.PUT("/:id", controllers.UpdateCustomer)
 			customers.DELETE("/:id", controllers.DeleteCustomer)
+			customers.GET("/:id/consents", controllers.GetCustomerConsents)
+			customers.PUT("/:id/consents", controllers.UpdateCustomerConsents)
+			customers.GET("/:id/loyalty", controllers.GetCustomerLoyalty)
+			customers.GET("/:id/packages", controllers.GetCustomerPackages)
+			customers.GET("/:id/wallet", controllers.GetCustomerWallet)
+			customers.POST("/:id/wallet/deposits", controllers.AddWalletDeposit)
 		}
 
 		// Service routes
@@ -57,6 +76,109 @@ func SetupRouter() *gin.Engine {
 			services.GET("/:id", controllers.GetService)
 			services.PUT("/:id", controllers.UpdateService)
 			services.DELETE("/:id", controllers.DeleteService)
+			services.GET("/:id/recipe", controllers.GetServiceRecipe)
+			services.PUT("/:id/recipe", controllers.UpdateServiceRecipe)
+		}
+
+		// Tax class routes
+		taxClasses := api.Group("/tax-classes")
+		{
+			taxClasses.GET("", controllers.GetTaxClasses)
+			taxClasses.POST("", controllers.CreateTaxClass)
+			taxClasses.PUT("/:id", controllers.UpdateTaxClass)
+			taxClasses.DELETE("/:id", controllers.DeleteTaxClass)
+		}
+
+		// Package and membership routes
+		packages := api.Group("/packages")
+		{
+			packages.GET("", controllers.GetPackages)
+			packages.POST("", controllers.CreatePackage)
+			packages.GET("/:id", controllers.GetPackage)
+			packages.PUT("/:id", controllers.UpdatePackage)
+			packages.DELETE("/:id", controllers.DeletePackage)
+		}
+
+		// Gift card routes
+		giftCards := api.Group("/gift-cards")
+		{
+			giftCards.GET("", controllers.GetGiftCards)
+			giftCards.GET("/settings", controllers.GetGiftCardSettings)
+			giftCards.PUT("/settings", controllers.UpdateGiftCardSettings)
+			giftCards.GET("/lookup/:code", controllers.LookupGiftCard)
+			giftCards.GET("/:id", controllers.GetGiftCard)
+		}
+
+		// Retail product and stock routes
+		products := api.Group("/products")
+		{
+			products.GET("", controllers.GetProducts)
+			products.POST("", controllers.CreateProduct)
+			products.GET("/low-stock", controllers.GetLowStockProducts)
+			products.GET("/barcode/:code", controllers.LookupProductBarcode)
+			products.GET("/:id", controllers.GetProduct)
+			products.PUT("/:id", controllers.UpdateProduct)
+			products.DELETE("/:id", controllers.DeleteProduct)
+			products.GET("/:id/movements", controllers.GetStockMovements)
+			products.POST("/:id/adjustments", controllers.AdjustStock)
+		}
+
+		// Supplier routes
+		suppliers := api.Group("/suppliers")
+		{
+			suppliers.GET("", controllers.GetSuppliers)
+			suppliers.POST("", controllers.CreateSupplier)
+			suppliers.GET("/:id", controllers.GetSupplier)
+			suppliers.PUT("/:id", controllers.UpdateSupplier)
+			suppliers.DELETE("/:id", controllers.DeleteSupplier)
+		}
+
+		// Purchase order and stock receiving routes
+		purchaseOrders := api.Group("/purchase-orders")
+		{
+			purchaseOrders.GET("", controllers.GetPurchaseOrders)
+			purchaseOrders.POST("", controllers.CreatePurchaseOrder)
+			purchaseOrders.GET("/reorder-suggestions", controllers.GetReorderSuggestions)
+			purchaseOrders.GET("/:id", controllers.GetPurchaseOrder)
+			purchaseOrders.PUT("/:id", controllers.UpdatePurchaseOrder)
+			purchaseOrders.DELETE("/:id", controllers.DeletePurchaseOrder)
+			purchaseOrders.POST("/:id/order", controllers.PlacePurchaseOrder)
+			purchaseOrders.POST("/:id/receive", controllers.ReceivePurchaseOrder)
+			purchaseOrders.POST("/:id/cancel", controllers.CancelPurchaseOrder)
+		}
+
+		// Expense routes
+		expenses := api.Group("/expenses")
+		{
+			expenses.GET("", controllers.GetExpenses)
+			expenses.POST("", controllers.CreateExpense)
+			expenses.GET("/recurring", controllers.GetRecurringExpenses)
+			expenses.POST("/recurring", controllers.CreateRecurringExpense)
+			expenses.PUT("/recurring/:id", controllers.UpdateRecurringExpense)
+			expenses.DELETE("/recurring/:id", controllers.DeleteRecurringExpense)
+			expenses.GET("/:id", controllers.GetExpense)
+			expenses.PUT("/:id", controllers.UpdateExpense)
+			expenses.DELETE("/:id", controllers.DeleteExpense)
+			expenses.POST("/:id/attachments", controllers.UploadExpenseAttachment)
+			expenses.GET("/:id/attachments/:attachmentId", controllers.GetExpenseAttachment)
+			expenses.DELETE("/:id/attachments/:attachmentId", controllers.DeleteExpenseAttachment)
+		}
+
+		// Promotion code routes
+		promotions := api.Group("/promotions")
+		{
+			promotions.GET("", controllers.GetPromotions)
+			promotions.POST("", controllers.CreatePromotion)
+			promotions.GET("/:id", controllers.GetPromotion)
+			promotions.PUT("/:id", controllers.UpdatePromotion)
+			promotions.DELETE("/:id", controllers.DeletePromotion)
+		}
+
+		// Loyalty program routes
+		loyalty := api.Group("/loyalty")
+		{
+			loyalty.GET("/settings", controllers.GetLoyaltySettings)
+			loyalty.PUT("/settings", controllers.UpdateLoyaltySettings)
 		}
 
 		// Invoice routes
@@ -67,21 +189,70 @@ func SetupRouter() *gin.Engine {
 			invoices.GET("/:id", controllers.GetInvoice)
 			invoices.PUT("/:id", controllers.UpdateInvoice)
 			invoices.DELETE("/:id", controllers.DeleteInvoice)
+			invoices.POST("/:id/finalize", controllers.FinalizeInvoice)
+			invoices.POST("/:id/void", controllers.VoidInvoice)
+			invoices.GET("/:id/pdf", controllers.GetInvoicePrint)
+			invoices.POST("/:id/send-receipt", controllers.SendInvoiceReceipt)
+			invoices.GET("/:id/payments", controllers.GetInvoicePayments)
+			invoices.POST("/:id/payments", controllers.AddInvoicePayment)
+			invoices.GET("/:id/refunds", controllers.GetInvoiceRefunds)
+			invoices.POST("/:id/refunds", controllers.CreateRefund)
+		}
+
+		// Appointment routes
+		appointments := api.Group("/appointments")
+		{
+			appointments.POST("", controllers.CreateAppointment)
+			appointments.GET("", controllers.GetAppointments)
+			appointments.GET("/:id", controllers.GetAppointment)
+			appointments.PUT("/:id", controllers.UpdateAppointment)
+			appointments.PUT("/:id/status", controllers.UpdateAppointmentStatus)
+			appointments.POST("/:id/complete", controllers.CompleteAppointment)
+			appointments.DELETE("/:id", controllers.DeleteAppointment)
+		}
+
+		// Availability routes
+		api.GET("/availability", controllers.GetAvailability)
+
+		// Commission routes
+		commissions := api.Group("/commissions")
+		{
+			commissions.GET("", controllers.GetCommissions)
+			commissions.GET("/rules", controllers.GetCommissionRules)
+			commissions.POST("/rules", controllers.CreateCommissionRule)
+			commissions.PUT("/rules/:id", controllers.UpdateCommissionRule)
+			commissions.DELETE("/rules/:id", controllers.DeleteCommissionRule)
+			commissions.GET("/statements", controllers.GetCommissionStatements)
+			commissions.POST("/statements", controllers.LockCommissionStatements)
+			commissions.GET("/statements/:id", controllers.GetCommissionStatement)
 		}
 
 		//Reports routes
 		reportController := controllers.ReportController{}
 		api.GET("/reports", reportController.GetReportAnalytics)
+		api.GET("/reports/tax-summary", reportController.GetTaxSummary)
+		api.GET("/reports/liabilities", reportController.GetLiabilities)
+		api.GET("/reports/promotions", reportController.GetPromotionReport)
+		api.GET("/reports/service-profitability", reportController.GetServiceProfitability)
+		api.GET("/reports/profit-loss", reportController.GetProfitAndLoss)
 
 		// Dashboard routes
 		api.GET("/dashboard", controllers.GetDashboardOverview)
 
+		// Reminder delivery log
+		api.GET("/reminders/log", controllers.GetReminderLog)
+
 		// Settings routes
 		profile := auth.Group("/profile", utils.AuthMiddleware()) // utils.AuthMiddleware()
 		{
 			profile.GET("", controllers.GetProfile)
 			profile.PUT("/update-salon", controllers.UpdateSalonProfile)
 			profile.PUT("/update-hours", controllers.UpdateWorkingHours)
+			profile.PUT("/update-booking", controllers.UpdateBookingSettings)
+			profile.PUT("/update-numbering", controllers.UpdateNumberingSettings)
+			profile.PUT("/update-tax", controllers.UpdateTaxSettings)
+			profile.GET("/logo", controllers.GetSalonLogo)
+			profile.PUT("/update-logo", controllers.UpdateSalonLogo)
 			profile.PUT("/update-templates", controllers.UpdateReminderTemplates)
 			profile.PUT("/update-notifications", controllers.UpdateNotifications)
 			profile.POST("/test-notification", controllers.SendTestNotification)
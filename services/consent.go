@@ -0,0 +1,167 @@
+// services/consent.go
+package services
+
+import (
+	"strings"
+	"time"
+
+	"salonpro-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConsentChange grants or withdraws consent for one channel and purpose
+type ConsentChange struct {
+	Channel string
+	Purpose string
+	Granted bool
+}
+
+// ConsentRecord is who captured a set of consent changes, and how
+type ConsentRecord struct {
+	Source     string
+	Evidence   string
+	RecordedBy *uuid.UUID
+	RecordedAt time.Time
+}
+
+// RecordConsent applies changes to a customer's consents and adds each one that changes anything to the
+// consent history. It returns how many changed. Run it in the same transaction as the edit that captured them.
+func RecordConsent(db *gorm.DB, customer models.Customer, changes []ConsentChange, record ConsentRecord) (int, error) {
+	if record.RecordedAt.IsZero() {
+		record.RecordedAt = time.Now()
+	}
+
+	var current []models.CustomerConsent
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("customer_id = ?", customer.ID).Find(&current).Error; err != nil {
+		return 0, err
+	}
+	existing := make(map[[2]string]bool, len(current))
+	for _, consent := range current {
+		existing[[2]string{consent.Channel, consent.Purpose}] = consent.Granted
+	}
+
+	changed := 0
+	for _, change := range changes {
+		key := [2]string{change.Channel, change.Purpose}
+		if granted, ok := existing[key]; ok && granted == change.Granted {
+			continue
+		}
+		existing[key] = change.Granted
+
+		consent := models.CustomerConsent{
+			SalonID:          customer.SalonID,
+			CustomerID:       customer.ID,
+			Channel:          change.Channel,
+			Purpose:          change.Purpose,
+			Granted:          change.Granted,
+			Source:           record.Source,
+			RecordedByUserID: record.RecordedBy,
+			RecordedAt:       record.RecordedAt,
+		}
+		if err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "customer_id"}, {Name: "channel"}, {Name: "purpose"}},
+			DoUpdates: clause.AssignmentColumns([]string{"granted", "source", "recorded_by_user_id", "recorded_at"}),
+		}).Create(&consent).Error; err != nil {
+			return changed, err
+		}
+
+		event := models.ConsentEvent{
+			SalonID:          customer.SalonID,
+			CustomerID:       customer.ID,
+			Channel:          change.Channel,
+			Purpose:          change.Purpose,
+			Granted:          change.Granted,
+			Source:           record.Source,
+			Evidence:         record.Evidence,
+			RecordedByUserID: record.RecordedBy,
+			RecordedAt:       record.RecordedAt,
+		}
+		if err := db.Create(&event).Error; err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// ApplyConsentReply records a STOP or START reply from a phone number against every customer with that
+// number, since all salons send from the same numbers, and returns how many customers changed. STOP
+// withdraws every purpose on the channel the reply came in on; START restores what a STOP withdrew.
+func ApplyConsentReply(db *gorm.DB, from, body string, optOut bool) (int, error) {
+	channel := ChannelSMS
+	phone := strings.TrimSpace(from)
+	if strings.HasPrefix(phone, "whatsapp:") {
+		channel, phone = ChannelWhatsApp, strings.TrimPrefix(phone, "whatsapp:")
+	}
+	if phone == "" {
+		return 0, nil
+	}
+
+	var customers []models.Customer
+	if err := db.Where("phone IN ?", []string{phone, strings.TrimPrefix(phone, "+")}).
+		Preload("Consents").Find(&customers).Error; err != nil {
+		return 0, err
+	}
+
+	record := ConsentRecord{Source: models.ConsentSourceReply, Evidence: strings.TrimSpace(body)}
+	changed := 0
+	for _, customer := range customers {
+		var changes []ConsentChange
+		for _, purpose := range models.ConsentPurposes {
+			if optOut {
+				changes = append(changes, ConsentChange{Channel: channel, Purpose: purpose, Granted: false})
+				continue
+			}
+			for _, consent := range customer.Consents {
+				if consent.Channel == channel && consent.Purpose == purpose && !consent.Granted && consent.Source == models.ConsentSourceReply {
+					changes = append(changes, ConsentChange{Channel: channel, Purpose: purpose, Granted: true})
+				}
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		var n int
+		err := db.Transaction(func(tx *gorm.DB) (err error) {
+			n, err = RecordConsent(tx, customer, changes, record)
+			return err
+		})
+		if err != nil {
+			return changed, err
+		}
+		if n > 0 {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// loadConsents fills in each customer's Consents
+func loadConsents(db *gorm.DB, customers []models.Customer) error {
+	if len(customers) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(customers))
+	for i, customer := range customers {
+		ids[i] = customer.ID
+	}
+
+	var consents []models.CustomerConsent
+	if err := db.Where("customer_id IN ?", ids).Find(&consents).Error; err != nil {
+		return err
+	}
+	byCustomer := make(map[uuid.UUID][]models.CustomerConsent)
+	for _, consent := range consents {
+		byCustomer[consent.CustomerID] = append(byCustomer[consent.CustomerID], consent)
+	}
+	for i := range customers {
+		customers[i].Consents = byCustomer[customers[i].ID]
+	}
+	return nil
+}
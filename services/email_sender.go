@@ -0,0 +1,257 @@
+// services/email_sender.go
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// smtpTimeout bounds connecting and the whole SMTP session, so a stalled server cannot hold up a send
+const smtpTimeout = 30 * time.Second
+
+// SMTP connection security
+const (
+	SMTPStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SMTPTLS      = "tls"      // TLS from the start, usually port 465
+	SMTPNone     = "none"     // no encryption; only for a local mail catcher
+)
+
+// SMTPSender sends email through an SMTP server
+type SMTPSender struct {
+	host     string
+	port     string
+	username string
+	password string
+	security string
+	from     mail.Address
+}
+
+// NewSMTPSenderFromEnv configures email from SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD,
+// SMTP_FROM, SMTP_FROM_NAME and SMTP_SECURITY (starttls, tls or none; default starttls).
+// It returns nil when SMTP_HOST is not set.
+func NewSMTPSenderFromEnv() (*SMTPSender, error) {
+	host := strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	if host == "" {
+		return nil, nil
+	}
+
+	security := strings.ToLower(strings.TrimSpace(os.Getenv("SMTP_SECURITY")))
+	if security == "" {
+		security = SMTPStartTLS
+	}
+	port := strings.TrimSpace(os.Getenv("SMTP_PORT"))
+	switch security {
+	case SMTPStartTLS:
+		if port == "" {
+			port = "587"
+		}
+	case SMTPTLS:
+		if port == "" {
+			port = "465"
+		}
+	case SMTPNone:
+		if port == "" {
+			port = "25"
+		}
+	default:
+		return nil, fmt.Errorf("SMTP_SECURITY must be starttls, tls or none, got %q", security)
+	}
+
+	from, err := mail.ParseAddress(strings.TrimSpace(os.Getenv("SMTP_FROM")))
+	if err != nil {
+		return nil, fmt.Errorf("SMTP_FROM must be an email address: %v", err)
+	}
+	if name := strings.TrimSpace(os.Getenv("SMTP_FROM_NAME")); name != "" {
+		from.Name = name
+	}
+
+	return &SMTPSender{
+		host:     host,
+		port:     port,
+		username: os.Getenv("SMTP_USERNAME"),
+		password: os.Getenv("SMTP_PASSWORD"),
+		security: security,
+		from:     *from,
+	}, nil
+}
+
+func (s *SMTPSender) Name() string { return "smtp" }
+
+func (s *SMTPSender) Supports(channel string) bool {
+	return channel == ChannelEmail
+}
+
+func (s *SMTPSender) Send(msg Message) (SendResult, error) {
+	if msg.Channel != ChannelEmail {
+		return SendResult{}, fmt.Errorf("smtp only sends email, got %q", msg.Channel)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return SendResult{}, fmt.Errorf("invalid email address %q", msg.To)
+	}
+
+	messageID := fmt.Sprintf("<%s@%s>", uuid.NewString(), s.fromDomain())
+	body, err := buildEmail(s.from, *to, messageID, msg)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer client.Close()
+
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return SendResult{}, err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return SendResult{}, err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return SendResult{}, err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return SendResult{}, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return SendResult{}, err
+	}
+	if err := w.Close(); err != nil {
+		return SendResult{}, err
+	}
+	if err := client.Quit(); err != nil {
+		return SendResult{}, err
+	}
+
+	return SendResult{ID: messageID, Status: "sent"}, nil
+}
+
+// dial connects and secures the session as configured. The connection has a deadline of smtpTimeout.
+func (s *SMTPSender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.host, s.port)
+	tlsConfig := &tls.Config{ServerName: s.host}
+	deadline := time.Now().Add(smtpTimeout)
+
+	if s.security == SMTPTLS {
+		conn, err := tls.DialWithDialer(&net.Dialer{Deadline: deadline}, "tcp", addr, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		client, err := smtp.NewClient(conn, s.host)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return client, nil
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, smtpTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.security == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server %s does not support STARTTLS", s.host)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+func (s *SMTPSender) fromDomain() string {
+	if at := strings.LastIndex(s.from.Address, "@"); at >= 0 {
+		return s.from.Address[at+1:]
+	}
+	return s.host
+}
+
+// buildEmail writes a MIME message with the plain-text body and, when there is one, the HTML
+// alternative
+func buildEmail(from, to mail.Address, messageID string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Body},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,102 @@
+// services/email_templates.go
+package services
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	textTemplate "text/template"
+
+	"salonpro-backend/models"
+)
+
+// emailData is what the email templates are rendered with
+type emailData struct {
+	Salon      models.Salon
+	Customer   models.Customer
+	Paragraphs []string // the message, split on blank lines
+	Link       string
+	LinkLabel  string
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body style="font-family:-apple-system,Helvetica,Arial,sans-serif;background:#f4f4f5;margin:0;padding:16px;color:#18181b">
+<div style="max-width:560px;margin:0 auto;background:#fff;border-radius:8px;padding:24px">
+<h1 style="font-size:20px;margin:0 0 16px">{{.Salon.Name}}</h1>
+{{range .Paragraphs}}<p style="font-size:15px;line-height:1.5">{{.}}</p>
+{{end}}{{if .Link}}<a href="{{.Link}}" style="display:block;text-align:center;margin-top:20px;padding:10px;background:#18181b;color:#fff;border-radius:6px;text-decoration:none">{{.LinkLabel}}</a>
+{{end}}{{if .Salon.Address}}<p style="color:#71717a;font-size:13px;margin-top:24px">{{.Salon.Address}}</p>
+{{end}}</div>
+</body>
+</html>
+`))
+
+var emailTextTemplate = textTemplate.Must(textTemplate.New("email").Parse(`{{range .Paragraphs}}{{.}}
+
+{{end}}{{if .Link}}{{.LinkLabel}}: {{.Link}}
+
+{{end}}{{.Salon.Name}}{{if .Salon.Address}}
+{{.Salon.Address}}{{end}}
+`))
+
+// reminderSubjects are the email subjects for each reminder type
+var reminderSubjects = map[string]string{
+	"birthday":    "Happy birthday from %s!",
+	"anniversary": "Happy anniversary from %s!",
+}
+
+// ReminderEmail renders a birthday or anniversary message as an email with HTML and plain-text parts
+func ReminderEmail(salon models.Salon, customer models.Customer, eventType, message string) (Message, error) {
+	subject, ok := reminderSubjects[eventType]
+	if !ok {
+		subject = "A message from %s"
+	}
+	return renderEmail(strings.Replace(subject, "%s", salon.Name, 1), emailData{
+		Salon:      salon,
+		Customer:   customer,
+		Paragraphs: splitParagraphs(message),
+	})
+}
+
+// ReceiptEmail renders a receipt as an email: the short receipt text and the link to the invoice
+func ReceiptEmail(doc InvoiceDocument, link string) (Message, error) {
+	return renderEmail("Your receipt "+doc.Invoice.InvoiceNumber+" from "+doc.Salon.Name, emailData{
+		Salon:      doc.Salon,
+		Customer:   doc.Customer,
+		Paragraphs: []string{receiptSummary(doc.Salon, doc.Customer, doc.Invoice)},
+		Link:       link,
+		LinkLabel:  "View your bill",
+	})
+}
+
+func renderEmail(subject string, data emailData) (Message, error) {
+	var html, text bytes.Buffer
+	if err := emailHTMLTemplate.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	if err := emailTextTemplate.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Channel: ChannelEmail,
+		To:      data.Customer.Email,
+		Subject: subject,
+		Body:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func splitParagraphs(message string) []string {
+	var paragraphs []string
+	for _, p := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
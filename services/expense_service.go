@@ -0,0 +1,132 @@
+// services/expense_service.go
+package services
+
+import (
+	"errors"
+	"log"
+	"salonpro-backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxCatchUp bounds how many occurrences one run posts for a schedule, so a start date far in
+// the past cannot flood the ledger
+const maxCatchUp = 400
+
+type RecurringExpenseService struct {
+	db *gorm.DB
+}
+
+func NewRecurringExpenseService(db *gorm.DB) *RecurringExpenseService {
+	return &RecurringExpenseService{db: db}
+}
+
+func (s *RecurringExpenseService) StartScheduler() {
+	c := cron.New()
+	_, _ = c.AddFunc("15 0 * * *", s.PostDueExpenses) // Every day just after midnight
+	c.Start()
+	s.PostDueExpenses() // Catch up on anything missed while the server was down
+	log.Println("Recurring expense scheduler started (runs daily at 00:15 and once on startup)")
+}
+
+// PostDueExpenses posts every active schedule's occurrences due up to today
+func (s *RecurringExpenseService) PostDueExpenses() {
+	today := time.Now()
+
+	var ids []uuid.UUID
+	if err := s.db.Model(&models.RecurringExpense{}).
+		Where("is_active = ? AND next_due_date < ?", true, startOfNextDay(today)).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("Failed to fetch due recurring expenses: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := PostRecurringExpense(s.db, id, today); err != nil {
+			log.Printf("Recurring expense %s: failed to post: %v", id, err)
+		}
+	}
+}
+
+// PostRecurringExpense posts the occurrences of one schedule that fall on or before today and
+// moves its NextDueDate on. It is safe to call repeatedly; each occurrence is posted once.
+func PostRecurringExpense(db *gorm.DB, id uuid.UUID, today time.Time) (int, error) {
+	posted := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var schedule models.RecurringExpense
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if !schedule.IsActive {
+			return nil
+		}
+
+		cutoff := startOfNextDay(today)
+		if schedule.EndDate != nil && startOfNextDay(*schedule.EndDate).Before(cutoff) {
+			cutoff = startOfNextDay(*schedule.EndDate)
+		}
+
+		for posted < maxCatchUp && schedule.NextDueDate.Before(cutoff) {
+			expense := models.Expense{
+				ID:                 uuid.New(),
+				SalonID:            schedule.SalonID,
+				Category:           schedule.Category,
+				Description:        schedule.Description,
+				Amount:             schedule.Amount,
+				TaxAmount:          schedule.TaxAmount,
+				ExpenseDate:        schedule.NextDueDate,
+				PaymentMethod:      schedule.PaymentMethod,
+				Vendor:             schedule.Vendor,
+				SupplierID:         schedule.SupplierID,
+				RecurringExpenseID: &schedule.ID,
+			}
+			if err := tx.Create(&expense).Error; err != nil {
+				return err
+			}
+			posted++
+			schedule.PostedCount++
+			schedule.NextDueDate = NextOccurrence(schedule.StartDate, schedule.Frequency, schedule.PostedCount)
+		}
+
+		if posted == 0 {
+			return nil
+		}
+		return tx.Model(&schedule).Updates(map[string]interface{}{
+			"posted_count":  schedule.PostedCount,
+			"next_due_date": schedule.NextDueDate,
+		}).Error
+	})
+	return posted, err
+}
+
+// NextOccurrence returns occurrence n (0 is the start date) of a schedule. Monthly dates keep the
+// start's day of month, falling back to the month's last day, so the 31st stays at month end.
+func NextOccurrence(start time.Time, frequency string, n int) time.Time {
+	months := 0
+	switch frequency {
+	case "weekly":
+		return start.AddDate(0, 0, 7*n)
+	case "monthly":
+		months = n
+	case "quarterly":
+		months = 3 * n
+	case "yearly":
+		months = 12 * n
+	}
+
+	firstOfMonth := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location()).AddDate(0, months, 0)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
+
+func startOfNextDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+}
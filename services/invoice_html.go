@@ -0,0 +1,66 @@
+// services/invoice_html.go
+package services
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var invoiceHTMLTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"amount": formatAmount,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Doc.Invoice.InvoiceNumber}} - {{.Doc.Salon.Name}}</title>
+<style>
+body{font-family:-apple-system,Helvetica,Arial,sans-serif;background:#f4f4f5;margin:0;padding:16px;color:#18181b}
+.card{max-width:560px;margin:0 auto;background:#fff;border-radius:8px;padding:24px}
+h1{font-size:20px;margin:0}.muted{color:#71717a;font-size:13px}
+.banner{text-align:center;font-weight:bold;color:#b91c1c;border:2px solid #b91c1c;padding:6px;margin:16px 0}
+table{width:100%;border-collapse:collapse;margin-top:16px;font-size:14px}
+th{text-align:left;font-size:12px;color:#71717a;border-bottom:1px solid #e4e4e7;padding:6px 0}
+td{padding:6px 0;border-bottom:1px solid #f4f4f5;vertical-align:top}.r{text-align:right}
+.totals td{border:0;padding:3px 0}.b{font-weight:bold}
+a.btn{display:block;text-align:center;margin-top:20px;padding:10px;background:#18181b;color:#fff;border-radius:6px;text-decoration:none}
+</style>
+</head>
+<body>
+<div class="card">
+<h1>{{.Doc.Salon.Name}}</h1>
+{{if .Doc.Salon.Address}}<div class="muted">{{.Doc.Salon.Address}}</div>{{end}}
+{{if .Doc.Salon.GSTIN}}<div class="muted">GSTIN {{.Doc.Salon.GSTIN}}</div>{{end}}
+<p><strong>{{.Title}}</strong> {{.Doc.Invoice.InvoiceNumber}}<br>
+<span class="muted">{{.Doc.Invoice.InvoiceDate.Format "02 Jan 2006 15:04"}} &middot; {{.Doc.Customer.Name}}</span></p>
+{{if .Banner}}<div class="banner">{{.Banner}}</div>{{end}}
+<table>
+<tr><th>Item</th><th class="r">Qty</th><th class="r">Amount</th></tr>
+{{range .Doc.Invoice.Items}}<tr><td>{{.ServiceName}}</td><td class="r">{{.Quantity}}</td><td class="r">{{amount .TotalPrice}}</td></tr>
+{{end}}</table>
+<table class="totals">
+{{range .Totals}}<tr{{if .Bold}} class="b"{{end}}><td>{{.Label}}</td><td class="r">{{amount .Value}}</td></tr>
+{{end}}</table>
+{{if .Doc.Invoice.Payments}}<table>
+<tr><th>Payment</th><th class="r">Amount</th></tr>
+{{range .Doc.Invoice.Payments}}<tr><td>{{.Method}} <span class="muted">{{.PaidAt.Format "02 Jan 2006"}}</span></td><td class="r">{{amount .Amount}}</td></tr>
+{{end}}</table>{{end}}
+{{if .PDFURL}}<a class="btn" href="{{.PDFURL}}">Download PDF</a>{{end}}
+</div>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML renders the invoice as a standalone web page for customers. pdfURL, when set, adds a download button.
+func RenderInvoiceHTML(doc InvoiceDocument, pdfURL string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := invoiceHTMLTemplate.Execute(&buf, map[string]interface{}{
+		"Doc":    doc,
+		"Title":  doc.title(),
+		"Banner": doc.statusBanner(),
+		"Totals": doc.totals(),
+		"PDFURL": pdfURL,
+	})
+	return buf.Bytes(), err
+}
@@ -0,0 +1,529 @@
+// services/invoice_renderer.go
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+
+	"github.com/google/uuid"
+)
+
+// Characters per line on common thermal printers (Font A)
+const (
+	Receipt58mmColumns = 32
+	Receipt80mmColumns = 48
+)
+
+// InvoiceDocument is everything needed to print an invoice
+type InvoiceDocument struct {
+	Salon      models.Salon
+	Logo       []byte // the salon's logo, if it has one
+	Customer   models.Customer
+	Invoice    models.Invoice // with Items and Payments loaded
+	StaffNames map[uuid.UUID]string
+}
+
+// invoiceTotalLine is one row of the totals block
+type invoiceTotalLine struct {
+	Label string
+	Value models.Money
+	Bold  bool
+}
+
+// title is the document heading; GST-registered salons issue tax invoices
+func (d InvoiceDocument) title() string {
+	if d.Salon.GSTIN != "" {
+		return "TAX INVOICE"
+	}
+	return "INVOICE"
+}
+
+// statusBanner is printed prominently on invoices that are not valid bills
+func (d InvoiceDocument) statusBanner() string {
+	switch d.Invoice.Status {
+	case "void":
+		return "VOID"
+	case "draft":
+		return "DRAFT - NOT A VALID INVOICE"
+	}
+	return ""
+}
+
+func (d InvoiceDocument) totals() []invoiceTotalLine {
+	inv := d.Invoice
+	lines := []invoiceTotalLine{{Label: "Subtotal", Value: inv.Subtotal}}
+	if inv.Discount > 0 {
+		lines = append(lines, invoiceTotalLine{Label: "Discount", Value: -inv.Discount})
+	}
+	if inv.PromotionDiscount > 0 {
+		lines = append(lines, invoiceTotalLine{Label: "Promo " + inv.PromotionCode, Value: -inv.PromotionDiscount})
+	}
+	if inv.LoyaltyDiscount > 0 {
+		lines = append(lines, invoiceTotalLine{Label: fmt.Sprintf("Loyalty (%d pts)", inv.LoyaltyPointsRedeemed), Value: -inv.LoyaltyDiscount})
+	}
+
+	if d.itemisedTax() {
+		taxLines := d.taxLines()
+		if inv.PricesIncludeTax {
+			lines = append(lines, invoiceTotalLine{Label: "Total", Value: inv.Total, Bold: true})
+			for _, t := range taxLines {
+				t.Label = "Incl. " + t.Label
+				lines = append(lines, t)
+			}
+		} else {
+			if len(taxLines) > 0 {
+				lines = append(lines, invoiceTotalLine{Label: "Taxable value", Value: inv.Total - inv.TaxAmount})
+			}
+			lines = append(lines, taxLines...)
+			lines = append(lines, invoiceTotalLine{Label: "Total", Value: inv.Total, Bold: true})
+		}
+	} else {
+		// Invoices from before per-item tax only recorded the flat rate
+		if inv.Tax > 0 {
+			lines = append(lines, invoiceTotalLine{Label: fmt.Sprintf("Tax (%s%%)", formatRate(inv.Tax)), Value: inv.Subtotal.Percent(inv.Tax)})
+		}
+		lines = append(lines, invoiceTotalLine{Label: "Total", Value: inv.Total, Bold: true})
+	}
+
+	if inv.RefundedAmount > 0 {
+		lines = append(lines, invoiceTotalLine{Label: "Refunded", Value: -inv.RefundedAmount})
+	}
+	lines = append(lines, invoiceTotalLine{Label: "Paid", Value: inv.PaidAmount})
+	if due := inv.Total - inv.RefundedAmount - inv.PaidAmount; due > 0 {
+		lines = append(lines, invoiceTotalLine{Label: "Balance due", Value: due, Bold: true})
+	}
+	return lines
+}
+
+// itemisedTax reports whether tax was worked out per item, which is true of every invoice
+// priced since tax classes were introduced
+func (d InvoiceDocument) itemisedTax() bool {
+	for _, item := range d.Invoice.Items {
+		if item.LineTotal != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// taxLines lists the CGST, SGST and IGST charged. The rate is shown when all taxed lines share one.
+func (d InvoiceDocument) taxLines() []invoiceTotalLine {
+	inv := d.Invoice
+	rate := -1.0
+	for _, item := range inv.Items {
+		if item.TaxAmount == 0 {
+			continue
+		}
+		if rate >= 0 && rate != item.TaxRate {
+			rate = -1
+			break
+		}
+		rate = item.TaxRate
+	}
+
+	label := func(name string, share float64) string {
+		if rate < 0 {
+			return name
+		}
+		return fmt.Sprintf("%s %s%%", name, formatRate(rate*share))
+	}
+
+	var lines []invoiceTotalLine
+	if inv.CGSTAmount != 0 || inv.SGSTAmount != 0 {
+		lines = append(lines,
+			invoiceTotalLine{Label: label("CGST", 0.5), Value: inv.CGSTAmount},
+			invoiceTotalLine{Label: label("SGST", 0.5), Value: inv.SGSTAmount})
+	}
+	if inv.IGSTAmount != 0 {
+		lines = append(lines, invoiceTotalLine{Label: label("IGST", 1), Value: inv.IGSTAmount})
+	}
+	return lines
+}
+
+// itemTaxNote is the HSN/SAC code and rate printed under an item, noting lines paid from a package
+func (d InvoiceDocument) itemTaxNote(item models.InvoiceItem) string {
+	var parts []string
+	if item.CustomerPackageID != nil {
+		parts = append(parts, "Paid from package")
+	}
+	if item.TaxCode != "" {
+		parts = append(parts, "SAC "+item.TaxCode)
+	}
+	if item.LineTotal != 0 && (item.TaxRate > 0 || d.Salon.GSTIN != "") {
+		parts = append(parts, "GST "+formatRate(item.TaxRate)+"%")
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (d InvoiceDocument) staffName(item models.InvoiceItem) string {
+	if item.PerformedByUserID == nil {
+		return ""
+	}
+	return d.StaffNames[*item.PerformedByUserID]
+}
+
+// RenderInvoicePDF lays the invoice out on A4 or A5 pages (utils.PageA4 / utils.PageA5)
+func RenderInvoicePDF(doc InvoiceDocument, size [2]float64) []byte {
+	pdf := utils.NewPDF(size)
+	pdf.AddPage()
+
+	scale := pdf.Width() / utils.PageA4[0]
+	if scale < 0.8 {
+		scale = 0.8
+	}
+	margin := 40 * scale
+	right := pdf.Width() - margin
+	contentWidth := right - margin
+	body := 9 * scale
+	small := 7.5 * scale
+	lineHeight := 13 * scale
+
+	inv := doc.Invoice
+	y := margin
+
+	// Header: logo and salon details on the left, document details on the right
+	textX := margin
+	if len(doc.Logo) > 0 {
+		if logo, err := utils.NewPDFImage(doc.Logo); err == nil {
+			h := 48 * scale
+			w := h * float64(logo.Width) / float64(logo.Height)
+			if w > 120*scale {
+				w = 120 * scale
+				h = w * float64(logo.Height) / float64(logo.Width)
+			}
+			pdf.Image(logo, margin, y, w, h)
+			textX = margin + w + 10*scale
+		}
+	}
+
+	pdf.Text(textX, y+14*scale, 15*scale, true, doc.Salon.Name)
+	addressY := y + 14*scale + lineHeight
+	for _, line := range strings.Split(doc.Salon.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			pdf.Text(textX, addressY, small, false, line)
+			addressY += 10 * scale
+		}
+	}
+	if doc.Salon.GSTIN != "" {
+		pdf.Text(textX, addressY, small, true, "GSTIN "+doc.Salon.GSTIN)
+		addressY += 10 * scale
+	}
+
+	pdf.TextRight(right, y+14*scale, 15*scale, true, doc.title())
+	pdf.TextRight(right, y+14*scale+lineHeight, body, false, "No. "+inv.InvoiceNumber)
+	pdf.TextRight(right, y+14*scale+2*lineHeight, body, false, "Date "+inv.InvoiceDate.Format("02 Jan 2006"))
+
+	y = addressY
+	if headerBottom := margin + 14*scale + 3*lineHeight; y < headerBottom {
+		y = headerBottom
+	}
+	y += 6 * scale
+
+	if banner := doc.statusBanner(); banner != "" {
+		pdf.Gray(0.4)
+		pdf.TextCenter(pdf.Width()/2, y+14*scale, 16*scale, true, banner)
+		pdf.Gray(0)
+		y += 26 * scale
+	}
+
+	// Bill to
+	pdf.Text(margin, y+lineHeight, small, true, "BILL TO")
+	y += lineHeight
+	pdf.Text(margin, y+lineHeight, body, true, doc.Customer.Name)
+	y += lineHeight
+	if doc.Customer.Phone != "" {
+		pdf.Text(margin, y+lineHeight, body, false, doc.Customer.Phone)
+		y += lineHeight
+	}
+	if doc.Salon.GSTIN != "" && inv.PlaceOfSupply != "" {
+		pdf.Text(margin, y+lineHeight, small, false, "Place of supply: "+inv.PlaceOfSupply)
+		y += lineHeight
+	}
+	y += 10 * scale
+
+	// Item table
+	colNo := margin
+	colItem := margin + 22*scale
+	colQty := margin + contentWidth*0.62
+	colRate := margin + contentWidth*0.80
+	colAmount := right
+	itemWidth := colQty - colItem - 30*scale
+
+	tableHeader := func() {
+		pdf.Line(margin, y, right, y, 0.8)
+		y += lineHeight
+		pdf.Text(colNo, y-3*scale, small, true, "#")
+		pdf.Text(colItem, y-3*scale, small, true, "ITEM")
+		pdf.TextRight(colQty, y-3*scale, small, true, "QTY")
+		pdf.TextRight(colRate, y-3*scale, small, true, "RATE")
+		pdf.TextRight(colAmount, y-3*scale, small, true, "AMOUNT")
+		pdf.Line(margin, y+2*scale, right, y+2*scale, 0.5)
+		y += 4 * scale
+	}
+	tableHeader()
+
+	bottom := pdf.Height() - margin
+	for i, item := range inv.Items {
+		rowHeight := lineHeight
+		detail := doc.itemTaxNote(item)
+		if staff := doc.staffName(item); staff != "" {
+			detail = strings.TrimSpace("by " + staff + "  " + detail)
+		}
+		if detail != "" {
+			rowHeight += 9 * scale
+		}
+		if y+rowHeight > bottom {
+			pdf.AddPage()
+			y = margin
+			tableHeader()
+		}
+
+		y += lineHeight
+		pdf.Text(colNo, y, body, false, fmt.Sprintf("%d", i+1))
+		pdf.Text(colItem, y, body, false, fitText(item.ServiceName, itemWidth, body))
+		pdf.TextRight(colQty, y, body, false, fmt.Sprintf("%d", item.Quantity))
+		pdf.TextRight(colRate, y, body, false, formatAmount(item.UnitPrice))
+		pdf.TextRight(colAmount, y, body, false, formatAmount(item.TotalPrice))
+		if detail != "" {
+			y += 9 * scale
+			pdf.Gray(0.35)
+			pdf.Text(colItem, y, small, false, fitText(detail, colRate-colItem, small))
+			pdf.Gray(0)
+		}
+	}
+	y += 6 * scale
+	pdf.Line(margin, y, right, y, 0.5)
+
+	// Totals and payments need roughly this much room; move to a new page rather than split them
+	totals := doc.totals()
+	needed := float64(len(totals)+len(inv.Payments)+4) * lineHeight
+	if y+needed > bottom {
+		pdf.AddPage()
+		y = margin
+	}
+
+	labelX := margin + contentWidth*0.62
+	for _, t := range totals {
+		y += lineHeight
+		pdf.Text(labelX, y, body, t.Bold, t.Label)
+		pdf.TextRight(right, y, body, t.Bold, formatAmount(t.Value))
+	}
+
+	if len(inv.Payments) > 0 {
+		y += lineHeight * 1.6
+		pdf.Text(margin, y, small, true, "PAYMENTS")
+		for _, p := range inv.Payments {
+			y += lineHeight
+			desc := strings.ToUpper(p.Method)
+			if p.Reference != "" {
+				desc += "  " + p.Reference
+			}
+			pdf.Text(margin, y, body, false, p.PaidAt.Format("02 Jan 2006 15:04"))
+			pdf.Text(margin+contentWidth*0.25, y, body, false, fitText(desc, contentWidth*0.5, body))
+			pdf.TextRight(right, y, body, false, formatAmount(p.Amount))
+		}
+	}
+
+	if inv.Notes != "" {
+		y += lineHeight * 1.6
+		pdf.Text(margin, y, small, true, "NOTES")
+		y += lineHeight
+		pdf.Text(margin, y, body, false, fitText(inv.Notes, contentWidth, body))
+	}
+
+	pdf.TextCenter(pdf.Width()/2, bottom, small, false, "Thank you for visiting "+doc.Salon.Name)
+
+	return pdf.Bytes()
+}
+
+// receiptLine is one printed line of a thermal receipt
+type receiptLine struct {
+	text   string
+	center bool
+	bold   bool
+	double bool // double width and height; halves the columns available
+}
+
+// buildReceipt lays the invoice out for a thermal printer with the given number of columns
+func buildReceipt(doc InvoiceDocument, columns int) []receiptLine {
+	inv := doc.Invoice
+	var lines []receiptLine
+	add := func(l receiptLine) { lines = append(lines, l) }
+	rule := receiptLine{text: strings.Repeat("-", columns)}
+
+	for _, part := range wrapText(doc.Salon.Name, columns/2) {
+		add(receiptLine{text: part, center: true, bold: true, double: true})
+	}
+	for _, line := range strings.Split(doc.Salon.Address, "\n") {
+		for _, part := range wrapText(strings.TrimSpace(line), columns) {
+			add(receiptLine{text: part, center: true})
+		}
+	}
+	if doc.Salon.GSTIN != "" {
+		add(receiptLine{text: "GSTIN " + doc.Salon.GSTIN, center: true})
+	}
+	add(receiptLine{})
+	add(receiptLine{text: doc.title(), center: true, bold: true})
+	if banner := doc.statusBanner(); banner != "" {
+		add(receiptLine{text: banner, center: true, bold: true})
+	}
+	add(receiptLine{text: "No. " + inv.InvoiceNumber})
+	add(receiptLine{text: twoColumns(inv.InvoiceDate.Format("02/01/2006"), inv.InvoiceDate.Format("15:04"), columns)})
+	add(receiptLine{text: "Customer: " + doc.Customer.Name})
+	add(rule)
+
+	for _, item := range inv.Items {
+		for _, part := range wrapText(item.ServiceName, columns) {
+			add(receiptLine{text: part})
+		}
+		qty := fmt.Sprintf("  %d x %s", item.Quantity, formatAmount(item.UnitPrice))
+		add(receiptLine{text: twoColumns(qty, formatAmount(item.TotalPrice), columns)})
+		if staff := doc.staffName(item); staff != "" {
+			add(receiptLine{text: "  by " + staff})
+		}
+		if note := doc.itemTaxNote(item); note != "" {
+			add(receiptLine{text: "  " + note})
+		}
+	}
+	add(rule)
+
+	for _, t := range doc.totals() {
+		add(receiptLine{text: twoColumns(t.Label, formatAmount(t.Value), columns), bold: t.Bold})
+	}
+
+	if len(inv.Payments) > 0 {
+		add(rule)
+		for _, p := range inv.Payments {
+			add(receiptLine{text: twoColumns(strings.ToUpper(p.Method), formatAmount(p.Amount), columns)})
+		}
+	}
+
+	add(receiptLine{})
+	add(receiptLine{text: "Thank you!", center: true})
+	return lines
+}
+
+// RenderInvoiceText renders the receipt as plain text for a printer with the given number of columns
+func RenderInvoiceText(doc InvoiceDocument, columns int) string {
+	var b strings.Builder
+	for _, l := range buildReceipt(doc, columns) {
+		text := l.text
+		if l.center {
+			if pad := (columns - utf8.RuneCountInString(text)) / 2; pad > 0 {
+				text = strings.Repeat(" ", pad) + text
+			}
+		}
+		b.WriteString(text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RenderInvoiceESCPOS renders the receipt as an ESC/POS job ready to be sent to the printer
+func RenderInvoiceESCPOS(doc InvoiceDocument, columns int) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0x1b, 0x40}) // initialise
+
+	for _, l := range buildReceipt(doc, columns) {
+		align := byte(0)
+		if l.center {
+			align = 1
+		}
+		b.Write([]byte{0x1b, 0x61, align})
+		if l.bold {
+			b.Write([]byte{0x1b, 0x45, 1})
+		}
+		if l.double {
+			b.Write([]byte{0x1d, 0x21, 0x11})
+		}
+		b.WriteString(asciiOnly(l.text))
+		b.WriteByte('\n')
+		if l.double {
+			b.Write([]byte{0x1d, 0x21, 0x00})
+		}
+		if l.bold {
+			b.Write([]byte{0x1b, 0x45, 0})
+		}
+	}
+
+	b.Write([]byte{0x1b, 0x64, 4})       // feed 4 lines
+	b.Write([]byte{0x1d, 0x56, 0x42, 0}) // partial cut
+	return b.Bytes()
+}
+
+func formatAmount(amount models.Money) string {
+	return amount.String()
+}
+
+func formatRate(rate float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", rate), "0"), ".")
+}
+
+// twoColumns puts left and right on one line of the given width, truncating left if needed
+func twoColumns(left, right string, columns int) string {
+	space := columns - utf8.RuneCountInString(right) - 1
+	if space < 0 {
+		space = 0
+	}
+	if utf8.RuneCountInString(left) > space {
+		left = string([]rune(left)[:space])
+	}
+	return left + strings.Repeat(" ", columns-utf8.RuneCountInString(left)-utf8.RuneCountInString(right)) + right
+}
+
+// wrapText breaks s into lines of at most columns characters, on spaces where possible
+func wrapText(s string, columns int) []string {
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		for len(w) > columns {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = nil
+			}
+			lines = append(lines, string(w[:columns]))
+			w = w[columns:]
+		}
+		switch {
+		case len(current) == 0:
+			current = w
+		case len(current)+1+len(w) <= columns:
+			current = append(append(current, ' '), w...)
+		default:
+			lines = append(lines, string(current))
+			current = w
+		}
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// fitText shortens s with an ellipsis so it fits width points in Helvetica
+func fitText(s string, width, size float64) string {
+	if utils.TextWidth(s, size, false) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && utils.TextWidth(string(runes)+"...", size, false) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// asciiOnly replaces characters thermal printers' default code page cannot print
+func asciiOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
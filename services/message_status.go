@@ -0,0 +1,76 @@
+// services/message_status.go
+package services
+
+import (
+	"strings"
+
+	"salonpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// deliveryStages orders the statuses Twilio reports for a message. Status callbacks can arrive out of
+// order, so a status never replaces one from a later stage.
+var deliveryStages = map[string]int{
+	"accepted":    1,
+	"scheduled":   1,
+	"queued":      1,
+	"sending":     2,
+	"sent":        3,
+	"delivered":   4,
+	"undelivered": 4,
+	"failed":      4,
+	"canceled":    4,
+	"read":        5,
+}
+
+// Keywords customers reply with to stop and restart messages, as Twilio recognises them
+var (
+	optOutKeywords = map[string]bool{"STOP": true, "STOPALL": true, "UNSUBSCRIBE": true, "CANCEL": true, "END": true, "QUIT": true, "OPTOUT": true, "REVOKE": true}
+	optInKeywords  = map[string]bool{"START": true, "YES": true, "UNSTOP": true, "OPTIN": true}
+)
+
+// RecordDeliveryStatus updates the reminder logged under a provider message ID with a status the provider
+// reported later, and returns whether a logged message was updated. errorCode is the provider's error
+// code for undelivered and failed messages.
+func RecordDeliveryStatus(db *gorm.DB, messageID, status, errorCode string) (bool, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	stage, known := deliveryStages[status]
+	if messageID == "" || !known {
+		return false, nil
+	}
+
+	var entry models.ReminderLog
+	if err := db.Where("twilio_sid = ?", messageID).Take(&entry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil // e.g. a receipt, which is not logged
+		}
+		return false, err
+	}
+	if deliveryStages[entry.Status] > stage {
+		return false, nil
+	}
+
+	updates := map[string]interface{}{"status": status}
+	if errorCode != "" {
+		updates["error"] = "provider error " + errorCode
+	}
+	// Only apply it if no other callback got in first
+	result := db.Model(&models.ReminderLog{}).
+		Where("id = ? AND status = ?", entry.ID, entry.Status).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ConsentKeyword reads a reply for an opt-out or opt-in keyword. ok is false when the reply is neither.
+func ConsentKeyword(body string) (optOut bool, ok bool) {
+	word := strings.ToUpper(strings.Join(strings.Fields(body), ""))
+	word = strings.TrimRight(word, ".!")
+	switch {
+	case optOutKeywords[word]:
+		return true, true
+	case optInKeywords[word]:
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestConsentKeyword(t *testing.T) {
+	tests := []struct {
+		body       string
+		wantOptOut bool
+		wantOK     bool
+	}{
+		{"STOP", true, true},
+		{" stop. ", true, true},
+		{"Unsubscribe", true, true},
+		{"STOP ALL", true, true},
+		{"start", false, true},
+		{"Yes!", false, true},
+		{"Stop sending me offers", false, false},
+		{"Thanks, see you Friday", false, false},
+		{"", false, false},
+	}
+
+	for _, tt := range tests {
+		optOut, ok := ConsentKeyword(tt.body)
+		if optOut != tt.wantOptOut || ok != tt.wantOK {
+			t.Errorf("ConsentKeyword(%q) = %t, %t, want %t, %t", tt.body, optOut, ok, tt.wantOptOut, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// services/notifier.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"salonpro-backend/utils"
+
+	"github.com/google/uuid"
+	"github.com/twilio/twilio-go"
+	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
+)
+
+// Messaging channels
+const (
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+	ChannelEmail    = "email"
+)
+
+// Message is a text to one customer. To is the phone number in E.164 format, or the email
+// address on the email channel, where Body is the plain-text part.
+type Message struct {
+	Channel string
+	To      string
+	Body    string
+	Subject string // email only
+	HTML    string // email only; optional HTML alternative to Body
+}
+
+// SendResult is what the provider reports for an accepted message
+type SendResult struct {
+	ID     string // the provider's message ID, e.g. the Twilio SID
+	Status string // e.g. queued or sent
+}
+
+// MessageSender delivers messages through a provider
+type MessageSender interface {
+	// Name identifies the provider in logs and responses
+	Name() string
+	// Supports reports whether the channel is configured for sending
+	Supports(channel string) bool
+	Send(msg Message) (SendResult, error)
+}
+
+var (
+	defaultSender     MessageSender
+	defaultSenderOnce sync.Once
+)
+
+// DefaultMessageSender returns the process-wide sender chosen by NOTIFICATION_PROVIDER:
+//   - "twilio" (the default when TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are set) sends SMS and WhatsApp for real
+//   - "fake" records messages on every channel in memory, and appends them to NOTIFICATION_FAKE_FILE as JSON lines when set
+//
+// Email goes through SMTP when SMTP_HOST is set (see NewSMTPSenderFromEnv), whichever provider sends texts.
+// It returns nil when nothing is configured, which disables notifications.
+func DefaultMessageSender() MessageSender {
+	defaultSenderOnce.Do(func() {
+		defaultSender = newMessageSenderFromEnv()
+	})
+	return defaultSender
+}
+
+func newMessageSenderFromEnv() MessageSender {
+	// SMTP goes first so that it takes email even alongside the fake, e.g. with a local mail catcher
+	var senders channelRouter
+	email, err := NewSMTPSenderFromEnv()
+	if err != nil {
+		log.Printf("SMTP not configured: %v. Email notifications disabled.", err)
+	} else if email != nil {
+		log.Printf("SMTP configured (%s:%s); email notifications will be sent.", email.host, email.port)
+		senders = append(senders, email)
+	}
+
+	if text := newTextSenderFromEnv(); text != nil {
+		senders = append(senders, text)
+	}
+
+	switch len(senders) {
+	case 0:
+		return nil
+	case 1:
+		return senders[0]
+	}
+	return senders
+}
+
+// newTextSenderFromEnv picks the SMS and WhatsApp provider
+func newTextSenderFromEnv() MessageSender {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("NOTIFICATION_PROVIDER")))
+	accountSid := strings.TrimSpace(os.Getenv("TWILIO_ACCOUNT_SID"))
+	authToken := strings.TrimSpace(os.Getenv("TWILIO_AUTH_TOKEN"))
+
+	switch {
+	case provider == "fake":
+		path := strings.TrimSpace(os.Getenv("NOTIFICATION_FAKE_FILE"))
+		log.Printf("Fake notification provider in use; messages are recorded, not sent (file: %q)", path)
+		return NewFakeSender(path)
+	case provider != "" && provider != "twilio":
+		log.Printf("Unknown NOTIFICATION_PROVIDER %q. Notifications disabled.", provider)
+		return nil
+	case accountSid != "" && authToken != "":
+		log.Println("Twilio client initialized; notifications will be sent when scheduler runs.")
+		sender := NewTwilioSender(accountSid, authToken,
+			os.Getenv("TWILIO_PHONE_NUMBER"), os.Getenv("TWILIO_WHATSAPP_NUMBER"))
+		sender.StatusCallback = twilioStatusCallbackFromEnv()
+		return sender
+	default:
+		log.Println("Twilio not configured (TWILIO_ACCOUNT_SID or TWILIO_AUTH_TOKEN missing). Reminder notifications disabled.")
+		return nil
+	}
+}
+
+// twilioStatusCallbackFromEnv is TWILIO_STATUS_CALLBACK_URL, or the status webhook under
+// PUBLIC_BASE_URL when only that is set
+func twilioStatusCallbackFromEnv() string {
+	if callback := strings.TrimSpace(os.Getenv("TWILIO_STATUS_CALLBACK_URL")); callback != "" {
+		return callback
+	}
+	if base, err := utils.PublicBaseURL(); err == nil {
+		return base + "/webhooks/twilio/status"
+	}
+	return ""
+}
+
+// channelRouter sends each message through the first sender that supports its channel
+type channelRouter []MessageSender
+
+func (r channelRouter) Name() string {
+	names := make([]string, 0, len(r))
+	for _, sender := range r {
+		names = append(names, sender.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (r channelRouter) Supports(channel string) bool {
+	return r.senderFor(channel) != nil
+}
+
+func (r channelRouter) Send(msg Message) (SendResult, error) {
+	sender := r.senderFor(msg.Channel)
+	if sender == nil {
+		return SendResult{}, fmt.Errorf("%s is not configured", msg.Channel)
+	}
+	return sender.Send(msg)
+}
+
+func (r channelRouter) senderFor(channel string) MessageSender {
+	for _, sender := range r {
+		if sender.Supports(channel) {
+			return sender
+		}
+	}
+	return nil
+}
+
+// TwilioSender sends through Twilio's Messages API
+type TwilioSender struct {
+	client       *twilio.RestClient
+	fromSMS      string
+	fromWhatsApp string // without the whatsapp: prefix
+	// StatusCallback is the URL Twilio posts delivery updates to; none are requested when empty
+	StatusCallback string
+}
+
+func NewTwilioSender(accountSid, authToken, fromSMS, fromWhatsApp string) *TwilioSender {
+	return &TwilioSender{
+		client: twilio.NewRestClientWithParams(twilio.ClientParams{
+			Username: accountSid,
+			Password: authToken,
+		}),
+		fromSMS:      strings.TrimSpace(fromSMS),
+		fromWhatsApp: strings.TrimPrefix(strings.TrimSpace(fromWhatsApp), "whatsapp:"),
+	}
+}
+
+func (t *TwilioSender) Name() string { return "twilio" }
+
+func (t *TwilioSender) Supports(channel string) bool {
+	switch channel {
+	case ChannelSMS:
+		return t.fromSMS != ""
+	case ChannelWhatsApp:
+		return t.fromWhatsApp != ""
+	}
+	return false
+}
+
+func (t *TwilioSender) Send(msg Message) (SendResult, error) {
+	to, from := msg.To, t.fromSMS
+	switch msg.Channel {
+	case ChannelWhatsApp:
+		if t.fromWhatsApp == "" {
+			return SendResult{}, fmt.Errorf("TWILIO_WHATSAPP_NUMBER not set")
+		}
+		to, from = "whatsapp:"+msg.To, "whatsapp:"+t.fromWhatsApp
+	case ChannelSMS:
+		if t.fromSMS == "" {
+			return SendResult{}, fmt.Errorf("TWILIO_PHONE_NUMBER not set")
+		}
+	default:
+		return SendResult{}, fmt.Errorf("channel must be sms or whatsapp, got %q", msg.Channel)
+	}
+
+	params := &twilioApi.CreateMessageParams{}
+	params.SetTo(to)
+	params.SetFrom(from)
+	params.SetBody(msg.Body)
+	if t.StatusCallback != "" {
+		params.SetStatusCallback(t.StatusCallback)
+	}
+
+	resp, err := t.client.Api.CreateMessage(params)
+	if err != nil {
+		return SendResult{}, err
+	}
+
+	result := SendResult{Status: "queued"}
+	if resp.Sid != nil {
+		result.ID = *resp.Sid
+	}
+	if resp.Status != nil {
+		result.Status = *resp.Status
+	}
+	return result, nil
+}
+
+// SentMessage is a message recorded by FakeSender
+type SentMessage struct {
+	ID      string    `json:"id"`
+	Channel string    `json:"channel"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject,omitempty"`
+	Body    string    `json:"body"`
+	HTML    string    `json:"html,omitempty"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// FakeSender accepts every message on every channel and records it instead of sending it, for
+// running and testing locally. Set Fail to make sends return that error.
+type FakeSender struct {
+	mu       sync.Mutex
+	path     string
+	messages []SentMessage
+	Fail     error
+}
+
+// NewFakeSender records messages in memory and, when path is not empty, appends each one to
+// that file as a JSON line
+func NewFakeSender(path string) *FakeSender {
+	return &FakeSender{path: path}
+}
+
+func (f *FakeSender) Name() string { return "fake" }
+
+func (f *FakeSender) Supports(channel string) bool {
+	return channel == ChannelSMS || channel == ChannelWhatsApp || channel == ChannelEmail
+}
+
+func (f *FakeSender) Send(msg Message) (SendResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.Fail != nil {
+		return SendResult{}, f.Fail
+	}
+	if !f.Supports(msg.Channel) {
+		return SendResult{}, fmt.Errorf("channel must be sms, whatsapp or email, got %q", msg.Channel)
+	}
+
+	sent := SentMessage{
+		ID:      "FAKE" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Channel: msg.Channel,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Body:    msg.Body,
+		HTML:    msg.HTML,
+		SentAt:  time.Now(),
+	}
+	f.messages = append(f.messages, sent)
+
+	if f.path != "" {
+		line, err := json.Marshal(sent)
+		if err != nil {
+			return SendResult{}, err
+		}
+		file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return SendResult{}, err
+		}
+		defer file.Close()
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return SendResult{}, err
+		}
+	}
+
+	return SendResult{ID: sent.ID, Status: "sent"}, nil
+}
+
+// Messages returns the messages sent so far, oldest first
+func (f *FakeSender) Messages() []SentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SentMessage(nil), f.messages...)
+}
+
+// Reset forgets the messages recorded in memory
+func (f *FakeSender) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = nil
+}
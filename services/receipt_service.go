@@ -0,0 +1,70 @@
+// services/receipt_service.go
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"salonpro-backend/models"
+)
+
+// ReceiptMessage is the short text sent to a customer with the link to their invoice
+func ReceiptMessage(salon models.Salon, customer models.Customer, invoice models.Invoice, link string) string {
+	return receiptSummary(salon, customer, invoice) + " View your bill: " + link
+}
+
+func receiptSummary(salon models.Salon, customer models.Customer, invoice models.Invoice) string {
+	return fmt.Sprintf("Hi %s, thank you for visiting %s! Invoice %s: total %s, paid %s.",
+		customer.Name, salon.Name, invoice.InvoiceNumber, invoice.Total-invoice.RefundedAmount, invoice.PaidAmount)
+}
+
+// SendReceipt messages a receipt with the link to the invoice and returns the channel used.
+// channel may force "sms", "whatsapp" or "email"; empty tries the salon's channels in order until one is accepted.
+func (s *ReminderService) SendReceipt(doc InvoiceDocument, link, channel string) (string, error) {
+	if s.sender == nil {
+		return "", errNotConfigured
+	}
+
+	customers := []models.Customer{doc.Customer}
+	if err := loadConsents(s.db, customers); err != nil {
+		return "", err
+	}
+	doc.Customer = customers[0]
+
+	channels := []string{channel}
+	if channel == "" {
+		channels = deliveryChannels(&doc.Salon, doc.Customer, s.sender, models.PurposeTransactional)
+		if len(channels) == 0 {
+			return "", fmt.Errorf("no messaging channel available; turn on WhatsApp, SMS or email notifications and check the customer's contact details and consent")
+		}
+	} else if !canReach(doc.Customer, channel) {
+		switch {
+		case channel == ChannelEmail:
+			return "", fmt.Errorf("customer has no email address")
+		case channel == ChannelWhatsApp && recipient(doc.Customer, channel) != "":
+			return "", fmt.Errorf("customer's phone number must be in international format for WhatsApp")
+		}
+		return "", fmt.Errorf("customer has no phone number")
+	} else if !doc.Customer.Consented(channel, models.PurposeTransactional) {
+		return "", fmt.Errorf("customer has withdrawn consent to receipts by %s", channel)
+	}
+
+	var err error
+	for _, channel = range channels {
+		msg := Message{Channel: channel, To: recipient(doc.Customer, channel), Body: ReceiptMessage(doc.Salon, doc.Customer, doc.Invoice, link)}
+		if channel == ChannelEmail {
+			if msg, err = ReceiptEmail(doc, link); err != nil {
+				continue
+			}
+		}
+
+		var result SendResult
+		if result, err = s.sender.Send(msg); err != nil {
+			log.Printf("Receipt to %s via %s failed: %v", msg.To, channel, err)
+			continue
+		}
+		log.Printf("Receipt sent to %s via %s (%s), ID: %s", msg.To, channel, s.sender.Name(), result.ID)
+		return channel, nil
+	}
+	return channel, err
+}
@@ -4,47 +4,64 @@ package services
 import (
 	"fmt"
 	"log"
-	"os"
 	"salonpro-backend/models"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
-	"github.com/twilio/twilio-go"
-	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// Reminder log statuses of our own; otherwise the log holds the status the provider reports. A send
+// failure is kept apart from the provider's "failed", which means the carrier rejected a sent message
+// and is not retried.
+const (
+	ReminderSending = "sending"     // claimed by a run that has not yet heard back from the provider
+	ReminderFailed  = "send_failed" // the provider did not accept the message; retried on the next run
+)
+
+// maxReminderAttempts is how many runs may try a failed reminder before it is left alone
+const maxReminderAttempts = 3
+
+// staleSendingAfter is how long a reminder may stay claimed as sending. Older claims were left by a run
+// that stopped before recording the result, and are tried again like failed sends.
+const staleSendingAfter = time.Hour
+
+// errNotConfigured is returned when there is no message sender to send through
+var errNotConfigured = fmt.Errorf("messaging not configured; set TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN, or NOTIFICATION_PROVIDER=fake")
+
 type ReminderService struct {
 	db     *gorm.DB
-	client *twilio.RestClient
+	sender MessageSender
 }
 
+// NewReminderService sends through the provider configured in the environment; see DefaultMessageSender
 func NewReminderService(db *gorm.DB) *ReminderService {
-	accountSid := strings.TrimSpace(os.Getenv("TWILIO_ACCOUNT_SID"))
-	authToken := strings.TrimSpace(os.Getenv("TWILIO_AUTH_TOKEN"))
-
-	var client *twilio.RestClient
-	if accountSid != "" && authToken != "" {
-		client = twilio.NewRestClientWithParams(twilio.ClientParams{
-			Username: accountSid,
-			Password: authToken,
-		})
-		log.Println("Twilio client initialized; notifications will be sent when scheduler runs.")
-	} else {
-		log.Println("Twilio not configured (TWILIO_ACCOUNT_SID or TWILIO_AUTH_TOKEN missing). Reminder notifications disabled.")
-	}
+	return NewReminderServiceWithSender(db, DefaultMessageSender())
+}
 
+// NewReminderServiceWithSender sends through the given sender, e.g. a FakeSender in tests.
+// A nil sender disables notifications.
+func NewReminderServiceWithSender(db *gorm.DB, sender MessageSender) *ReminderService {
 	return &ReminderService{
 		db:     db,
-		client: client,
+		sender: sender,
 	}
 }
 
+// Provider names the message sender in use, or is empty when notifications are disabled
+func (s *ReminderService) Provider() string {
+	if s.sender == nil {
+		return ""
+	}
+	return s.sender.Name()
+}
+
 func (s *ReminderService) StartScheduler() {
-	if s.client == nil {
-		log.Println("Reminder scheduler not started: Twilio client is not configured.")
+	if s.sender == nil {
+		log.Println("Reminder scheduler not started: no notification provider is configured.")
 		return
 	}
 	c := cron.New()
@@ -55,7 +72,7 @@ func (s *ReminderService) StartScheduler() {
 }
 
 func (s *ReminderService) SendDailyReminders() {
-	if s.client == nil {
+	if s.sender == nil {
 		return
 	}
 	log.Println("Starting daily reminder processing...")
@@ -86,8 +103,8 @@ func (s *ReminderService) ProcessSalonReminders(salonID uuid.UUID) {
 		return
 	}
 	// Only send if salon has at least one notification channel enabled
-	if !salon.WhatsAppNotifications && !salon.SMSNotifications {
-		log.Printf("Salon %s: notifications skipped (enable WhatsApp or SMS in profile)", salonID)
+	if !salon.WhatsAppNotifications && !salon.SMSNotifications && !salon.EmailNotifications {
+		log.Printf("Salon %s: notifications skipped (enable WhatsApp, SMS or email in profile)", salonID)
 		return
 	}
 
@@ -167,78 +184,197 @@ func (s *ReminderService) sendReminders(salonID uuid.UUID, customers []models.Cu
 		log.Printf("Salon %s: No active template for %s: %v", salonID, eventType, err)
 		return
 	}
+	if err := loadConsents(s.db, customers); err != nil {
+		log.Printf("Salon %s: Failed to load consents for %s reminders: %v", salonID, eventType, err)
+		return
+	}
 
-	fromSMS := os.Getenv("TWILIO_PHONE_NUMBER")
-	fromWhatsApp := strings.TrimPrefix(strings.TrimSpace(os.Getenv("TWILIO_WHATSAPP_NUMBER")), "whatsapp:")
-
+	now := time.Now()
 	for _, customer := range customers {
-		if strings.TrimSpace(customer.Phone) == "" {
-			continue
+		channels := deliveryChannels(salon, customer, s.sender, models.PurposeReminders)
+		if len(channels) == 0 {
+			continue // No channel available
 		}
 		message := strings.ReplaceAll(template.Message, "[CustomerName]", customer.Name)
 
-		channel := "sms"
-		to := customer.Phone
-		useWhatsApp := salon.WhatsAppNotifications && strings.HasPrefix(customer.Phone, "+") && fromWhatsApp != ""
-		useSMS := salon.SMSNotifications && fromSMS != ""
-
-		if useWhatsApp {
-			to = "whatsapp:" + customer.Phone
-			channel = "whatsapp"
-		} else if !useSMS {
-			continue // No channel available
+		eventDate := upcomingEventDate(customer, eventType, now)
+		entry, claimed, err := s.claimReminder(salonID, customer, eventType, eventDate, message)
+		if err != nil {
+			log.Printf("Salon %s: failed to log %s reminder for customer %s: %v", salonID, eventType, customer.ID, err)
+			continue
+		}
+		if !claimed {
+			continue // Already sent for this year's event
 		}
 
-		params := &twilioApi.CreateMessageParams{}
-		params.SetTo(to)
-		params.SetBody(message)
-		if channel == "whatsapp" {
-			params.SetFrom("whatsapp:" + fromWhatsApp)
-		} else {
-			params.SetFrom(fromSMS)
+		// Try each channel in the salon's order until one is accepted
+		var errs []string
+		for _, channel := range channels {
+			msg := Message{Channel: channel, To: recipient(customer, channel), Body: message}
+			if channel == ChannelEmail {
+				if msg, err = ReminderEmail(*salon, customer, eventType, message); err != nil {
+					errs = append(errs, channel+": "+err.Error())
+					continue
+				}
+			}
+
+			entry.Channel, entry.Recipient = channel, msg.To
+			result, err := s.sender.Send(msg)
+			if err != nil {
+				log.Printf("Failed to send %s reminder to %s via %s: %v", eventType, msg.To, channel, err)
+				errs = append(errs, channel+": "+err.Error())
+				continue
+			}
+
+			sentAt := time.Now()
+			entry.SentAt = &sentAt
+			entry.Status = result.Status
+			entry.TwilioSID = result.ID
+			log.Printf("Reminder sent to %s via %s (%s), ID: %s", msg.To, channel, s.sender.Name(), result.ID)
+			break
 		}
+		if entry.SentAt == nil {
+			entry.Status = ReminderFailed
+		}
+		entry.Error = strings.Join(errs, "; ")
 
-		resp, err := s.client.Api.CreateMessage(params)
-		if err != nil {
-			log.Printf("Failed to send %s reminder to %s: %v", eventType, customer.Phone, err)
-		} else if resp.Sid != nil {
-			log.Printf("Reminder sent to %s, SID: %s", customer.Phone, *resp.Sid)
+		if err := s.db.Model(&entry).Updates(map[string]interface{}{
+			"channel":    entry.Channel,
+			"recipient":  entry.Recipient,
+			"status":     entry.Status,
+			"twilio_sid": entry.TwilioSID,
+			"error":      entry.Error,
+			"sent_at":    entry.SentAt,
+		}).Error; err != nil {
+			log.Printf("Salon %s: failed to record %s reminder for customer %s: %v", salonID, eventType, customer.ID, err)
 		}
-		_ = resp
 	}
 }
 
-// SendTestMessage sends a single SMS or WhatsApp message (for testing).
-// channel must be "sms" or "whatsapp". Phone should be E.164 (e.g. +919799570493).
-func (s *ReminderService) SendTestMessage(phone, body, channel string) error {
-	if s.client == nil {
-		return fmt.Errorf("Twilio not configured; set TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN")
+// claimReminder reserves the log entry for a customer's event this year before anything is sent.
+// claimed is false when the reminder already went out, another run is sending it, or it has
+// failed maxReminderAttempts times; a reminder that failed earlier is claimed again for a retry.
+func (s *ReminderService) claimReminder(salonID uuid.UUID, customer models.Customer, eventType string, eventDate time.Time, message string) (models.ReminderLog, bool, error) {
+	entry := models.ReminderLog{
+		ID:             uuid.New(),
+		SalonID:        salonID,
+		CustomerID:     customer.ID,
+		EventType:      eventType,
+		OccurrenceYear: eventDate.Year(),
+		EventDate:      eventDate,
+		Message:        message,
+		Status:         ReminderSending,
+		Attempts:       1,
 	}
-	fromSMS := os.Getenv("TWILIO_PHONE_NUMBER")
-	fromWhatsApp := strings.TrimPrefix(strings.TrimSpace(os.Getenv("TWILIO_WHATSAPP_NUMBER")), "whatsapp:")
 
-	to := phone
-	var from string
-	switch channel {
-	case "whatsapp":
-		if fromWhatsApp == "" {
-			return fmt.Errorf("TWILIO_WHATSAPP_NUMBER not set")
-		}
-		to = "whatsapp:" + phone
-		from = "whatsapp:" + fromWhatsApp
-	case "sms":
-		if fromSMS == "" {
-			return fmt.Errorf("TWILIO_PHONE_NUMBER not set")
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return entry, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return entry, true, nil
+	}
+
+	// Already logged: only a failed send, or a stale claim, with attempts left is tried again
+	if err := s.db.Where("salon_id = ? AND customer_id = ? AND event_type = ? AND occurrence_year = ?",
+		salonID, customer.ID, eventType, eventDate.Year()).First(&entry).Error; err != nil {
+		return entry, false, err
+	}
+	stale := entry.Status == ReminderSending && time.Since(entry.UpdatedAt) > staleSendingAfter
+	if (entry.Status != ReminderFailed && !stale) || entry.Attempts >= maxReminderAttempts {
+		return entry, false, nil
+	}
+
+	result = s.db.Model(&models.ReminderLog{}).
+		Where("id = ? AND status = ? AND attempts = ?", entry.ID, entry.Status, entry.Attempts).
+		Updates(map[string]interface{}{
+			"status":   ReminderSending,
+			"attempts": entry.Attempts + 1,
+			"message":  message,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return entry, false, result.Error
+	}
+	entry.Status = ReminderSending
+	entry.Attempts++
+	entry.Message = message
+	return entry, true, nil
+}
+
+// upcomingEventDate is the customer's next birthday or anniversary on or after today.
+// A 29 February date falls on 1 March in other years.
+func upcomingEventDate(customer models.Customer, eventType string, now time.Time) time.Time {
+	original := customer.Birthday
+	if eventType == "anniversary" {
+		original = customer.Anniversary
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if original == nil {
+		return today
+	}
+
+	next := time.Date(today.Year(), original.Month(), original.Day(), 0, 0, 0, 0, now.Location())
+	if next.Before(today) {
+		next = time.Date(today.Year()+1, original.Month(), original.Day(), 0, 0, 0, 0, now.Location())
+	}
+	return next
+}
+
+// deliveryChannels lists the channels a customer can be reached on for purpose, in the salon's order
+// of preference: those the salon turned on, the sender can send on, and the customer has contact
+// details for and consented to. WhatsApp needs a number in international format.
+func deliveryChannels(salon *models.Salon, customer models.Customer, sender MessageSender, purpose string) []string {
+	var channels []string
+	for _, channel := range salon.ChannelOrder() {
+		if channelEnabled(salon, channel) && canReach(customer, channel) &&
+			customer.Consented(channel, purpose) && sender.Supports(channel) {
+			channels = append(channels, channel)
 		}
-		from = fromSMS
-	default:
-		return fmt.Errorf("channel must be sms or whatsapp, got %q", channel)
 	}
+	return channels
+}
+
+func channelEnabled(salon *models.Salon, channel string) bool {
+	switch channel {
+	case ChannelWhatsApp:
+		return salon.WhatsAppNotifications
+	case ChannelSMS:
+		return salon.SMSNotifications
+	case ChannelEmail:
+		return salon.EmailNotifications
+	}
+	return false
+}
 
-	params := &twilioApi.CreateMessageParams{}
-	params.SetTo(to)
-	params.SetFrom(from)
-	params.SetBody(body)
-	_, err := s.client.Api.CreateMessage(params)
+func canReach(customer models.Customer, channel string) bool {
+	to := recipient(customer, channel)
+	if channel == ChannelWhatsApp {
+		return strings.HasPrefix(to, "+")
+	}
+	return to != ""
+}
+
+// recipient is the customer's address on a channel
+func recipient(customer models.Customer, channel string) string {
+	if channel == ChannelEmail {
+		return strings.TrimSpace(customer.Email)
+	}
+	return strings.TrimSpace(customer.Phone)
+}
+
+// SendTestMessage sends a single SMS, WhatsApp or email message (for testing).
+// to is an E.164 phone number (e.g. +919799570493), or an email address when channel is "email".
+func (s *ReminderService) SendTestMessage(salon models.Salon, to, body, channel string) error {
+	if s.sender == nil {
+		return errNotConfigured
+	}
+	msg := Message{Channel: channel, To: to, Body: body}
+	if channel == ChannelEmail {
+		var err error
+		if msg, err = ReminderEmail(salon, models.Customer{Name: "Test Customer", Email: to}, "test", body); err != nil {
+			return err
+		}
+	}
+	_, err := s.sender.Send(msg)
 	return err
 }
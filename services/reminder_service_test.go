@@ -0,0 +1,461 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"salonpro-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The reminder pipeline relies on Postgres (ON CONFLICT claims, enum types), so these tests need a
+// scratch database: TEST_DATABASE_URL=postgres://... go test ./services/
+// Each test runs in a transaction that is rolled back.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := strings.TrimSpace(os.Getenv("TEST_DATABASE_URL"))
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	for _, stmt := range []string{
+		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+		`DO $$ BEGIN
+			CREATE TYPE reminder_type AS ENUM ('birthday', 'anniversary');
+		EXCEPTION
+			WHEN duplicate_object THEN null;
+		END $$;`,
+	} {
+		if err := tx.Exec(stmt).Error; err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+	if err := tx.AutoMigrate(&models.ReminderTemplate{}, &models.ReminderLog{},
+		&models.Customer{}, &models.CustomerConsent{}, &models.ConsentEvent{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return tx
+}
+
+// channelFailSender is a FakeSender whose sends fail on some channels
+type channelFailSender struct {
+	*FakeSender
+	fail map[string]bool
+}
+
+func (s *channelFailSender) Send(msg Message) (SendResult, error) {
+	if s.fail[msg.Channel] {
+		return SendResult{}, errors.New(msg.Channel + " unavailable")
+	}
+	return s.FakeSender.Send(msg)
+}
+
+// reminderFixture is a salon with all channels on, a birthday template and one customer whose
+// birthday is in three days
+func reminderFixture(t *testing.T, db *gorm.DB) (models.Salon, models.Customer) {
+	t.Helper()
+	salon := models.Salon{
+		ID:                       uuid.New(),
+		Name:                     "Glow Studio",
+		WhatsAppNotifications:    true,
+		SMSNotifications:         true,
+		EmailNotifications:       true,
+		NotificationChannelOrder: models.DefaultChannelOrder,
+	}
+	if err := db.Create(&models.ReminderTemplate{
+		ID:       uuid.New(),
+		SalonID:  salon.ID,
+		Type:     "birthday",
+		Message:  "Happy birthday [CustomerName]!",
+		IsActive: true,
+	}).Error; err != nil {
+		t.Fatalf("template: %v", err)
+	}
+
+	birthday := time.Now().AddDate(-30, 0, 3)
+	customer := models.Customer{
+		ID:       uuid.New(),
+		SalonID:  salon.ID,
+		Name:     "Asha",
+		Phone:    "+919799570493",
+		Email:    "asha@example.com",
+		Birthday: &birthday,
+		IsActive: true,
+	}
+	return salon, customer
+}
+
+func grantReminders(t *testing.T, db *gorm.DB, customer models.Customer, channels ...string) {
+	t.Helper()
+	var changes []ConsentChange
+	for _, channel := range channels {
+		changes = append(changes, ConsentChange{Channel: channel, Purpose: models.PurposeReminders, Granted: true})
+	}
+	if _, err := RecordConsent(db, customer, changes, ConsentRecord{Source: models.ConsentSourceForm}); err != nil {
+		t.Fatalf("consent: %v", err)
+	}
+}
+
+func reminderLogs(t *testing.T, db *gorm.DB, customer models.Customer) []models.ReminderLog {
+	t.Helper()
+	var logs []models.ReminderLog
+	if err := db.Where("customer_id = ?", customer.ID).Find(&logs).Error; err != nil {
+		t.Fatalf("reminder log: %v", err)
+	}
+	return logs
+}
+
+func TestSendRemindersThroughSender(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelWhatsApp, ChannelSMS, ChannelEmail)
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	sent := sender.Messages()
+	if len(sent) != 1 || sent[0].Channel != ChannelWhatsApp || sent[0].To != customer.Phone {
+		t.Fatalf("sent %+v, want one WhatsApp message to %s", sent, customer.Phone)
+	}
+	if sent[0].Body != "Happy birthday Asha!" {
+		t.Errorf("body = %q", sent[0].Body)
+	}
+
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 {
+		t.Fatalf("%d log entries, want 1", len(logs))
+	}
+	if logs[0].Channel != ChannelWhatsApp || logs[0].Status != "sent" || logs[0].TwilioSID != sent[0].ID {
+		t.Errorf("log = %+v, want sent by whatsapp with the message ID", logs[0])
+	}
+}
+
+func TestSendRemindersFallsBackToNextChannel(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelWhatsApp, ChannelSMS, ChannelEmail)
+
+	sender := &channelFailSender{FakeSender: NewFakeSender(""), fail: map[string]bool{ChannelWhatsApp: true}}
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	sent := sender.Messages()
+	if len(sent) != 1 || sent[0].Channel != ChannelSMS || sent[0].To != customer.Phone {
+		t.Fatalf("sent %+v, want one SMS to %s", sent, customer.Phone)
+	}
+
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 {
+		t.Fatalf("%d log entries, want 1", len(logs))
+	}
+	if logs[0].Channel != ChannelSMS || logs[0].Status != "sent" || logs[0].TwilioSID != sent[0].ID {
+		t.Errorf("log = %+v, want sent by sms with the message ID", logs[0])
+	}
+	if !strings.Contains(logs[0].Error, "whatsapp unavailable") {
+		t.Errorf("log error = %q, want the WhatsApp failure kept", logs[0].Error)
+	}
+}
+
+func TestSendRemindersByEmail(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	salon.WhatsAppNotifications, salon.SMSNotifications = false, false
+	grantReminders(t, db, customer, ChannelEmail)
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	sent := sender.Messages()
+	if len(sent) != 1 || sent[0].Channel != ChannelEmail || sent[0].To != customer.Email {
+		t.Fatalf("sent %+v, want one email to %s", sent, customer.Email)
+	}
+	if sent[0].Subject != "Happy birthday from Glow Studio!" || !strings.Contains(sent[0].HTML, "Happy birthday Asha!") {
+		t.Errorf("email subject %q, html %q", sent[0].Subject, sent[0].HTML)
+	}
+}
+
+func TestSendRemindersHonourStopReplies(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	if err := db.Create(&customer).Error; err != nil {
+		t.Fatalf("customer: %v", err)
+	}
+	grantReminders(t, db, customer, ChannelWhatsApp, ChannelSMS, ChannelEmail)
+
+	for _, from := range []string{"whatsapp:" + customer.Phone, customer.Phone} {
+		if changed, err := ApplyConsentReply(db, from, "STOP", true); err != nil || changed != 1 {
+			t.Fatalf("STOP from %s: %d changed, err %v", from, changed, err)
+		}
+	}
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	sent := sender.Messages()
+	if len(sent) != 1 || sent[0].Channel != ChannelEmail {
+		t.Fatalf("sent %+v, want only an email after STOP on both text channels", sent)
+	}
+
+	// START restores the channel it came in on
+	if changed, err := ApplyConsentReply(db, customer.Phone, "START", false); err != nil || changed != 1 {
+		t.Fatalf("START: %d changed, err %v", changed, err)
+	}
+	customers := []models.Customer{customer}
+	if err := loadConsents(db, customers); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(deliveryChannels(&salon, customers[0], sender, models.PurposeReminders), ","); got != "sms,email" {
+		t.Errorf("channels after START = %q, want %q", got, "sms,email")
+	}
+}
+
+func TestSendRemindersFollowsConsent(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	other := customer
+	other.ID, other.Name, other.Phone, other.Email = uuid.New(), "Ravi", "+919800000000", "ravi@example.com"
+
+	// Asha agreed to reminders by email only; Ravi was never asked
+	grantReminders(t, db, customer, ChannelEmail)
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer, other}, "birthday", &salon)
+
+	sent := sender.Messages()
+	if len(sent) != 1 || sent[0].Channel != ChannelEmail || sent[0].To != customer.Email {
+		t.Fatalf("sent %+v, want one email to %s", sent, customer.Email)
+	}
+	if logs := reminderLogs(t, db, other); len(logs) != 0 {
+		t.Errorf("customer without consent was logged: %+v", logs)
+	}
+
+	// Withdrawing consent stops the next year's greeting before it is claimed
+	if _, err := RecordConsent(db, customer, []ConsentChange{
+		{Channel: ChannelEmail, Purpose: models.PurposeReminders, Granted: false},
+	}, ConsentRecord{Source: models.ConsentSourceInPerson}); err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+	customers := []models.Customer{customer}
+	if err := loadConsents(db, customers); err != nil {
+		t.Fatal(err)
+	}
+	if channels := deliveryChannels(&salon, customers[0], sender, models.PurposeReminders); len(channels) != 0 {
+		t.Errorf("channels after withdrawal = %v, want none", channels)
+	}
+}
+
+func TestSendRemindersSendsOncePerOccurrence(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelSMS)
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	for i := 0; i < 3; i++ {
+		svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	}
+
+	if sent := sender.Messages(); len(sent) != 1 {
+		t.Fatalf("sent %d messages over three runs, want 1", len(sent))
+	}
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 || logs[0].Attempts != 1 {
+		t.Fatalf("log = %+v, want one entry with one attempt", logs)
+	}
+}
+
+func TestSendRemindersRetriesFailures(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelSMS)
+
+	sender := NewFakeSender("")
+	sender.Fail = errors.New("provider down")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 || logs[0].Status != ReminderFailed || !strings.Contains(logs[0].Error, "provider down") {
+		t.Fatalf("log = %+v, want one %s entry", logs, ReminderFailed)
+	}
+
+	sender.Fail = nil
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+
+	if sent := sender.Messages(); len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1 after the retry", len(sent))
+	}
+	logs = reminderLogs(t, db, customer)
+	if logs[0].Status != "sent" || logs[0].Attempts != 2 {
+		t.Errorf("log = %+v, want sent on the second attempt", logs[0])
+	}
+
+	// A carrier rejection reported later is final
+	if _, err := RecordDeliveryStatus(db, logs[0].TwilioSID, "failed", "30003"); err != nil {
+		t.Fatal(err)
+	}
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	if sent := sender.Messages(); len(sent) != 1 {
+		t.Errorf("carrier-rejected reminder was sent again")
+	}
+}
+
+func TestSendRemindersStopsAfterMaxAttempts(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelSMS)
+
+	sender := NewFakeSender("")
+	sender.Fail = errors.New("provider down")
+	svc := NewReminderServiceWithSender(db, sender)
+	for i := 0; i < maxReminderAttempts+2; i++ {
+		svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	}
+
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 || logs[0].Attempts != maxReminderAttempts {
+		t.Fatalf("log = %+v, want %d attempts", logs, maxReminderAttempts)
+	}
+}
+
+func TestSendRemindersRetriesStaleClaims(t *testing.T) {
+	db := testDB(t)
+	salon, customer := reminderFixture(t, db)
+	grantReminders(t, db, customer, ChannelSMS)
+
+	// A run that stopped after claiming the reminder, before it heard back from the provider
+	eventDate := upcomingEventDate(customer, "birthday", time.Now())
+	claim := models.ReminderLog{
+		ID:             uuid.New(),
+		SalonID:        salon.ID,
+		CustomerID:     customer.ID,
+		EventType:      "birthday",
+		OccurrenceYear: eventDate.Year(),
+		EventDate:      eventDate,
+		Status:         ReminderSending,
+		Attempts:       1,
+	}
+	if err := db.Create(&claim).Error; err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	sender := NewFakeSender("")
+	svc := NewReminderServiceWithSender(db, sender)
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	if sent := sender.Messages(); len(sent) != 0 {
+		t.Fatalf("sent %d messages while another run holds the claim", len(sent))
+	}
+
+	if err := db.Model(&claim).UpdateColumn("updated_at", time.Now().Add(-2*staleSendingAfter)).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc.sendReminders(salon.ID, []models.Customer{customer}, "birthday", &salon)
+	if sent := sender.Messages(); len(sent) != 1 {
+		t.Fatalf("sent %d messages, want the stale claim retried once", len(sent))
+	}
+	logs := reminderLogs(t, db, customer)
+	if len(logs) != 1 || logs[0].Status != "sent" || logs[0].Attempts != 2 {
+		t.Errorf("log = %+v, want sent on the second attempt", logs)
+	}
+}
+
+func TestDeliveryChannels(t *testing.T) {
+	granted := func(channels ...string) []models.CustomerConsent {
+		var consents []models.CustomerConsent
+		for _, channel := range channels {
+			consents = append(consents, models.CustomerConsent{Channel: channel, Purpose: models.PurposeReminders, Granted: true})
+		}
+		return consents
+	}
+	allOn := models.Salon{WhatsAppNotifications: true, SMSNotifications: true, EmailNotifications: true}
+
+	tests := []struct {
+		name     string
+		salon    models.Salon
+		customer models.Customer
+		purpose  string
+		sender   MessageSender
+		want     string
+	}{
+		{
+			name:     "salon order with every channel consented",
+			salon:    allOn,
+			customer: models.Customer{Phone: "+919799570493", Email: "a@example.com", Consents: granted("whatsapp", "sms", "email")},
+			purpose:  models.PurposeReminders,
+			want:     "whatsapp,sms,email",
+		},
+		{
+			name:     "custom order",
+			salon:    models.Salon{WhatsAppNotifications: true, SMSNotifications: true, EmailNotifications: true, NotificationChannelOrder: "email,sms"},
+			customer: models.Customer{Phone: "+919799570493", Email: "a@example.com", Consents: granted("whatsapp", "sms", "email")},
+			purpose:  models.PurposeReminders,
+			want:     "email,sms,whatsapp",
+		},
+		{
+			name:     "reminders need consent",
+			salon:    allOn,
+			customer: models.Customer{Phone: "+919799570493", Email: "a@example.com", Consents: granted("sms")},
+			purpose:  models.PurposeReminders,
+			want:     "sms",
+		},
+		{
+			name:     "receipts go out unless withdrawn",
+			salon:    allOn,
+			customer: models.Customer{Phone: "+919799570493", Consents: []models.CustomerConsent{{Channel: "whatsapp", Purpose: models.PurposeTransactional}}},
+			purpose:  models.PurposeTransactional,
+			want:     "sms",
+		},
+		{
+			name:     "whatsapp needs an international number",
+			salon:    allOn,
+			customer: models.Customer{Phone: "9799570493", Consents: granted("whatsapp", "sms")},
+			purpose:  models.PurposeReminders,
+			want:     "sms",
+		},
+		{
+			name:     "salon toggles",
+			salon:    models.Salon{SMSNotifications: true},
+			customer: models.Customer{Phone: "+919799570493", Email: "a@example.com", Consents: granted("whatsapp", "sms", "email")},
+			purpose:  models.PurposeReminders,
+			want:     "sms",
+		},
+		{
+			name:     "sender support",
+			salon:    allOn,
+			customer: models.Customer{Phone: "+919799570493", Email: "a@example.com", Consents: granted("whatsapp", "sms", "email")},
+			purpose:  models.PurposeReminders,
+			sender:   channelRouter{NewTwilioSender("AC", "token", "+15005550006", "")},
+			want:     "sms",
+		},
+	}
+
+	for _, tt := range tests {
+		sender := tt.sender
+		if sender == nil {
+			sender = NewFakeSender("")
+		}
+		got := strings.Join(deliveryChannels(&tt.salon, tt.customer, sender, tt.purpose), ",")
+		if got != tt.want {
+			t.Errorf("%s: channels = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// codeAlphabet leaves out 0/O and 1/I/L so codes read back over the phone without mistakes
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// RandomCode returns a random code of groups of four characters joined by dashes, e.g.
+// RandomCode("GC", 2) gives "GC-7K3M-Q9TW". It is meant for codes customers redeem, such as gift cards.
+func RandomCode(prefix string, groups int) (string, error) {
+	parts := make([]string, 0, groups+1)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for g := 0; g < groups; g++ {
+		var b strings.Builder
+		for i := 0; i < 4; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(codeAlphabet[n.Int64()])
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// NormalizeCode uppercases a code typed by a user and drops spaces
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
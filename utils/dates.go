@@ -1,7 +1,10 @@
 // utils/dates.go
 package utils
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 func BeginningOfDay(t time.Time) time.Time {
 	year, month, day := t.Date()
@@ -12,4 +15,21 @@ func DaysBetween(start, end time.Time) int {
 	start = BeginningOfDay(start)
 	end = BeginningOfDay(end)
 	return int(end.Sub(start).Hours() / 24)
+}
+
+// ParseDateRange parses inclusive YYYY-MM-DD bounds in the local zone and returns
+// the half-open range [from, to+1 day) suitable for invoice_date >= ? AND invoice_date < ?
+func ParseDateRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", from)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", to)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("to date must not be before from date")
+	}
+	return start, end.AddDate(0, 0, 1), nil
 }
\ No newline at end of file
@@ -0,0 +1,51 @@
+// utils/gst.go
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
+const gstinAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// NormalizeGSTIN upper-cases a GSTIN and strips spaces
+func NormalizeGSTIN(gstin string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(gstin), " ", ""))
+}
+
+// ValidateGSTIN checks the format, state code and check digit of a 15-character GSTIN
+func ValidateGSTIN(gstin string) error {
+	if !gstinPattern.MatchString(gstin) {
+		return errors.New("GSTIN must be 15 characters, e.g. 27ABCDE1234F1Z5")
+	}
+	if !ValidGSTStateCode(gstin[:2]) {
+		return errors.New("GSTIN has an unknown state code")
+	}
+
+	// Luhn mod 36 over the first 14 characters
+	sum := 0
+	for i := 0; i < 14; i++ {
+		product := strings.IndexByte(gstinAlphabet, gstin[i]) * (i%2 + 1)
+		sum += product/36 + product%36
+	}
+	if gstin[14] != gstinAlphabet[(36-sum%36)%36] {
+		return errors.New("GSTIN check digit does not match")
+	}
+	return nil
+}
+
+// ValidGSTStateCode reports whether code is a two-digit GST state or union territory code
+func ValidGSTStateCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return false
+	}
+	return (n >= 1 && n <= 38) || n == 97
+}
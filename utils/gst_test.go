@@ -0,0 +1,35 @@
+package utils
+
+import "testing"
+
+func TestValidateGSTIN(t *testing.T) {
+	tests := []struct {
+		gstin   string
+		wantErr bool
+	}{
+		{"27AAPFU0939F1ZV", false},
+		{"29AAGCB7383J1Z4", false},
+		{"27AAPFU0939F1ZW", true}, // wrong check digit
+		{"99AAPFU0939F1ZV", true}, // unknown state code
+		{"00AAPFU0939F1ZV", true},
+		{"27aapfu0939f1zv", true}, // must be normalized first
+		{"27AAPFU0939F1Z", true},  // too short
+		{"27AAPFU0939F1ZVX", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateGSTIN(tt.gstin); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateGSTIN(%q) error = %v, wantErr %v", tt.gstin, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeGSTIN(t *testing.T) {
+	if got := NormalizeGSTIN(" 27aapfu 0939f1zv "); got != "27AAPFU0939F1ZV" {
+		t.Errorf("NormalizeGSTIN = %q, want %q", got, "27AAPFU0939F1ZV")
+	}
+	if err := ValidateGSTIN(NormalizeGSTIN(" 27aapfu 0939f1zv ")); err != nil {
+		t.Errorf("normalized GSTIN rejected: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// numberTokenPattern matches the placeholders of a document number format,
+// e.g. {SALON}, {FY}, {YYYY}, {YY}, {MM}, {SEQ} and a zero-padded {SEQ:05}
+var numberTokenPattern = regexp.MustCompile(`\{(SALON|FY|YYYY|YY|MM|SEQ)(?::(\d{1,2}))?\}`)
+
+// FiscalYearLabel returns the financial year a date falls in, e.g. "2026-27" for an April start.
+// A January start gives the plain calendar year.
+func FiscalYearLabel(t time.Time, startMonth int) string {
+	if startMonth < 1 || startMonth > 12 {
+		startMonth = 1
+	}
+	year := t.Year()
+	if int(t.Month()) < startMonth {
+		year--
+	}
+	if startMonth == 1 {
+		return strconv.Itoa(year)
+	}
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+// FormatDocumentNumber fills a number format such as "{SALON}-{FY}-{SEQ:05}"
+func FormatDocumentNumber(format, salonCode string, date time.Time, fiscalYear string, seq int64) string {
+	return numberTokenPattern.ReplaceAllStringFunc(format, func(token string) string {
+		parts := numberTokenPattern.FindStringSubmatch(token)
+		switch parts[1] {
+		case "SALON":
+			return salonCode
+		case "FY":
+			return fiscalYear
+		case "YYYY":
+			return date.Format("2006")
+		case "YY":
+			return date.Format("06")
+		case "MM":
+			return date.Format("01")
+		default: // SEQ
+			width, _ := strconv.Atoi(parts[2])
+			return fmt.Sprintf("%0*d", width, seq)
+		}
+	})
+}
+
+// ValidateNumberFormat checks a number format has exactly one sequence placeholder and the financial
+// year. Sequences restart every financial year, so without {FY} numbers would repeat after the rollover.
+func ValidateNumberFormat(format string) error {
+	seqCount, hasFY := 0, false
+	for _, m := range numberTokenPattern.FindAllStringSubmatch(format, -1) {
+		switch m[1] {
+		case "SEQ":
+			seqCount++
+		case "FY":
+			hasFY = true
+		}
+	}
+	if seqCount != 1 {
+		return errors.New("number format must contain exactly one {SEQ} placeholder")
+	}
+	if !hasFY {
+		return errors.New("number format must contain {FY}, since numbering restarts every financial year")
+	}
+	if strings.ContainsAny(numberTokenPattern.ReplaceAllString(format, ""), "{}") {
+		return errors.New("number format contains an unknown placeholder")
+	}
+	return nil
+}
+
+// DefaultSalonCode derives a short upper-case code from a salon name, e.g. "Glam Studio" -> "GLA"
+func DefaultSalonCode(name string) string {
+	var code []rune
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			code = append(code, unicode.ToUpper(r))
+			if len(code) == 3 {
+				break
+			}
+		}
+	}
+	if len(code) == 0 {
+		return "SAL"
+	}
+	return string(code)
+}
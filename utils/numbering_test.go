@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFiscalYearLabel(t *testing.T) {
+	tests := []struct {
+		date       string
+		startMonth int
+		want       string
+	}{
+		{"2026-04-01", 4, "2026-27"},
+		{"2027-03-31", 4, "2026-27"},
+		{"2026-01-15", 4, "2025-26"},
+		{"2099-12-31", 4, "2099-00"},
+		{"2026-06-30", 7, "2025-26"},
+		{"2026-07-01", 7, "2026-27"},
+		{"2026-01-01", 1, "2026"},
+		{"2026-12-31", 1, "2026"},
+		{"2026-05-01", 0, "2026"},  // out of range falls back to a January start
+		{"2026-05-01", 13, "2026"}, // likewise
+	}
+
+	for _, tt := range tests {
+		date, _ := time.Parse("2006-01-02", tt.date)
+		if got := FiscalYearLabel(date, tt.startMonth); got != tt.want {
+			t.Errorf("FiscalYearLabel(%s, %d) = %q, want %q", tt.date, tt.startMonth, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDocumentNumber(t *testing.T) {
+	date := time.Date(2026, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format string
+		seq    int64
+		want   string
+	}{
+		{"{SALON}-{FY}-{SEQ:05}", 42, "GLA-2025-26-00042"},
+		{"{SALON}-CN-{FY}-{SEQ:05}", 7, "GLA-CN-2025-26-00007"},
+		{"INV/{YYYY}/{MM}/{FY}/{SEQ}", 123, "INV/2026/03/2025-26/123"},
+		{"{YY}{MM}-{FY}-{SEQ:3}", 5, "2603-2025-26-005"},
+		{"{FY}-{SEQ:02}", 12345, "2025-26-12345"},  // padding never truncates
+		{"{FY}-{LOC}-{SEQ}", 1, "2025-26-{LOC}-1"}, // unknown placeholders are left alone
+	}
+
+	for _, tt := range tests {
+		if got := FormatDocumentNumber(tt.format, "GLA", date, "2025-26", tt.seq); got != tt.want {
+			t.Errorf("FormatDocumentNumber(%q, %d) = %q, want %q", tt.format, tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNumberFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"{SALON}-{FY}-{SEQ:05}", false},
+		{"INV/{FY}/{MM}/{SEQ}", false},
+		{"{SALON}-{SEQ}", true},        // no {FY}: repeats after the yearly reset
+		{"{SALON}-{YYYY}-{SEQ}", true}, // a calendar year is not the financial year
+		{"{SALON}-{FY}", true},         // no {SEQ}
+		{"{FY}-{SEQ}-{SEQ}", true},     // two {SEQ}
+		{"{FY}-{LOC}-{SEQ}", true},     // unknown placeholder
+		{"{FY}-{SEQ:123}", true},       // padding too wide to be a placeholder
+	}
+
+	for _, tt := range tests {
+		if err := ValidateNumberFormat(tt.format); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateNumberFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
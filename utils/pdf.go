@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// Page sizes in PDF points (1/72 inch)
+var (
+	PageA4 = [2]float64{595.28, 841.89}
+	PageA5 = [2]float64{419.53, 595.28}
+)
+
+// PDF is a minimal PDF writer for generated documents such as invoices. It supports the
+// built-in Helvetica fonts (WinAnsi text), lines and JPEG/PNG/GIF images, which is all the
+// printable documents need and keeps rendering in-process with no external dependency.
+// Coordinates are measured from the top-left corner of the page.
+type PDF struct {
+	width, height float64
+	pages         []*bytes.Buffer
+	images        []*PDFImage
+}
+
+// PDFImage is an image prepared for embedding
+type PDFImage struct {
+	Width, Height int
+	colorSpace    string
+	filter        string
+	data          []byte
+	index         int
+}
+
+// NewPDF starts a document whose pages all have the given size
+func NewPDF(size [2]float64) *PDF {
+	return &PDF{width: size[0], height: size[1]}
+}
+
+// Width returns the page width in points
+func (p *PDF) Width() float64 { return p.width }
+
+// Height returns the page height in points
+func (p *PDF) Height() float64 { return p.height }
+
+// AddPage starts a new page; subsequent drawing goes to it
+func (p *PDF) AddPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+}
+
+func (p *PDF) page() *bytes.Buffer {
+	if len(p.pages) == 0 {
+		p.AddPage()
+	}
+	return p.pages[len(p.pages)-1]
+}
+
+// Text draws s with its baseline at (x, y)
+func (p *PDF) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(p.page(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.height-y, pdfEscape(s))
+}
+
+// TextRight draws s so that it ends at x
+func (p *PDF) TextRight(x, y, size float64, bold bool, s string) {
+	p.Text(x-TextWidth(s, size, bold), y, size, bold, s)
+}
+
+// TextCenter draws s centred on x
+func (p *PDF) TextCenter(x, y, size float64, bold bool, s string) {
+	p.Text(x-TextWidth(s, size, bold)/2, y, size, bold, s)
+}
+
+// Line draws a straight line
+func (p *PDF) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(p.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, p.height-y1, x2, p.height-y2)
+}
+
+// Gray sets the fill and stroke gray level (0 black, 1 white) for what follows
+func (p *PDF) Gray(level float64) {
+	fmt.Fprintf(p.page(), "%.2f g %.2f G\n", level, level)
+}
+
+// Image draws img with its top-left corner at (x, y), scaled to w x h
+func (p *PDF) Image(img *PDFImage, x, y, w, h float64) {
+	if img.index == 0 {
+		p.images = append(p.images, img)
+		img.index = len(p.images)
+	}
+	fmt.Fprintf(p.page(), "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, p.height-y-h, img.index)
+}
+
+// MaxPDFImageSide bounds the width and height of the images NewPDFImage accepts. A small compressed
+// PNG can declare huge dimensions, and decoding expands it to 3 bytes per pixel.
+const MaxPDFImageSide = 2000
+
+// ErrImageTooLarge is returned by NewPDFImage for images wider or taller than MaxPDFImageSide
+var ErrImageTooLarge = errors.New("image is too large; use one of at most 2000x2000 pixels")
+
+// NewPDFImage prepares an encoded JPEG, PNG or GIF for embedding. JPEGs are embedded as-is;
+// other formats are decoded and stored as compressed RGB.
+func NewPDFImage(data []byte) (*PDFImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxPDFImageSide || cfg.Height > MaxPDFImageSide {
+		return nil, ErrImageTooLarge
+	}
+
+	if format == "jpeg" && (cfg.ColorModel == color.YCbCrModel || cfg.ColorModel == color.GrayModel) {
+		colorSpace := "DeviceRGB"
+		if cfg.ColorModel == color.GrayModel {
+			colorSpace = "DeviceGray"
+		}
+		return &PDFImage{Width: cfg.Width, Height: cfg.Height, colorSpace: colorSpace, filter: "DCTDecode", data: data}, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+
+	// Flatten onto white so transparent logos print cleanly
+	bounds := img.Bounds()
+	raw := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			raw = append(raw, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return &PDFImage{Width: bounds.Dx(), Height: bounds.Dy(), colorSpace: "DeviceRGB", filter: "FlateDecode", data: compressed.Bytes()}, nil
+}
+
+// Bytes serialises the document
+func (p *PDF) Bytes() []byte {
+	if len(p.pages) == 0 {
+		p.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			out.WriteString("stream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+
+	// Object numbers: 1 catalog, 2 page tree, 3-4 fonts, then images, then page/content pairs
+	firstImage := 5
+	firstPage := firstImage + len(p.images)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)), nil)
+
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+
+	var xobjects []string
+	for i, img := range p.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>",
+			img.Width, img.Height, img.colorSpace, img.filter, len(img.data)), img.data)
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, firstImage+i))
+	}
+
+	resources := "<< /Font << /F1 3 0 R /F2 4 0 R >>"
+	if len(xobjects) > 0 {
+		resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+	}
+	resources += " >>"
+
+	for i, content := range p.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>",
+			p.width, p.height, resources, firstPage+i*2+1), nil)
+		object(fmt.Sprintf("<< /Length %d >>", content.Len()), content.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// TextWidth returns the width of s in points when set in Helvetica at the given size
+func TextWidth(s string, size float64, bold bool) float64 {
+	widths := helveticaWidths
+	if bold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfEscape converts s to a WinAnsi PDF string literal body
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Glyph widths for ASCII 32-126 from the standard Helvetica AFM metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
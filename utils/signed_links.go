@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signed links give customers read-only access to a single document without logging in.
+// A token is base64url(id || expiry) "." base64url(truncated HMAC-SHA256); it stays short enough for SMS.
+// The key is derived from JWT_SECRET (or LINK_SIGNING_SECRET) per purpose, so a link token can never
+// be used as a login token or for another kind of document.
+
+var (
+	ErrLinkInvalid = errors.New("link is invalid")
+	ErrLinkExpired = errors.New("link has expired")
+)
+
+// Purposes for signed links
+const (
+	LinkPurposeInvoice = "invoice-view"
+)
+
+// ErrPublicBaseURLNotSet is returned when PUBLIC_BASE_URL is needed but not configured
+var ErrPublicBaseURLNotSet = errors.New("PUBLIC_BASE_URL not set")
+
+// PublicBaseURL is where customers and providers reach this server, from PUBLIC_BASE_URL. It is never
+// taken from the request's Host or X-Forwarded-* headers, which the client controls.
+func PublicBaseURL() (string, error) {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")), "/")
+	if base == "" {
+		return "", ErrPublicBaseURLNotSet
+	}
+	return base, nil
+}
+
+func linkKey(purpose string) ([]byte, error) {
+	secret := os.Getenv("LINK_SIGNING_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET not set")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("salonpro-link:" + purpose))
+	return mac.Sum(nil), nil
+}
+
+// SignLinkToken creates a token for id that is valid until expiresAt
+func SignLinkToken(purpose string, id uuid.UUID, expiresAt time.Time) (string, error) {
+	key, err := linkKey(purpose)
+	if err != nil {
+		return "", err
+	}
+
+	payload := make([]byte, 24)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(expiresAt.Unix()))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	sig := mac.Sum(nil)[:16]
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(sig), nil
+}
+
+// VerifyLinkToken checks a token's signature and expiry and returns the id it was issued for
+func VerifyLinkToken(purpose, token string) (uuid.UUID, error) {
+	key, err := linkKey(purpose)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return uuid.Nil, ErrLinkInvalid
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil || len(payload) != 24 {
+		return uuid.Nil, ErrLinkInvalid
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return uuid.Nil, ErrLinkInvalid
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)[:16]) {
+		return uuid.Nil, ErrLinkInvalid
+	}
+
+	if time.Now().Unix() > int64(binary.BigEndian.Uint64(payload[16:])) {
+		return uuid.Nil, ErrLinkExpired
+	}
+
+	id, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, ErrLinkInvalid
+	}
+	return id, nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Twilio signs each webhook request with the account's auth token: X-Twilio-Signature is
+// base64(HMAC-SHA1(token, url + each POST parameter's name and value, sorted by name)).
+
+// ValidTwilioSignature reports whether signature was made with authToken for a POST to rawURL with params
+func ValidTwilioSignature(authToken, rawURL string, params url.Values, signature string) bool {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || authToken == "" {
+		return false
+	}
+
+	for _, candidate := range twilioURLVariants(rawURL) {
+		mac := hmac.New(sha1.New, []byte(authToken))
+		mac.Write([]byte(twilioSigningString(candidate, params)))
+		if hmac.Equal(expected, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
+}
+
+func twilioSigningString(rawURL string, params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(rawURL)
+	for _, key := range keys {
+		values := append([]string(nil), params[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			b.WriteString(key)
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// twilioURLVariants is the URL with and without the default port, since Twilio may have signed either
+func twilioURLVariants(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return []string{rawURL}
+	}
+
+	variants := []string{rawURL}
+	defaultPort := map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	switch u.Port() {
+	case "":
+		if defaultPort != "" {
+			withPort := *u
+			withPort.Host = u.Host + ":" + defaultPort
+			variants = append(variants, withPort.String())
+		}
+	case defaultPort:
+		withoutPort := *u
+		withoutPort.Host = u.Hostname()
+		variants = append(variants, withoutPort.String())
+	}
+	return variants
+}
+
+// TwilioSignatureMiddleware rejects webhook requests that were not signed with TWILIO_AUTH_TOKEN.
+// The URL checked is PUBLIC_BASE_URL plus the request path, so it must match the URL configured in Twilio.
+func TwilioSignatureMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authToken := strings.TrimSpace(os.Getenv("TWILIO_AUTH_TOKEN"))
+		baseURL, err := PublicBaseURL()
+		if authToken == "" || err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Twilio webhooks need TWILIO_AUTH_TOKEN and PUBLIC_BASE_URL"})
+			return
+		}
+
+		if err := c.Request.ParseForm(); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid form body"})
+			return
+		}
+
+		requestURL := baseURL + c.Request.URL.RequestURI()
+		if !ValidTwilioSignature(authToken, requestURL, c.Request.PostForm, c.GetHeader("X-Twilio-Signature")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Invalid Twilio signature"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestValidTwilioSignature(t *testing.T) {
+	// The example from Twilio's webhook security documentation
+	const (
+		authToken = "12345"
+		rawURL    = "https://mycompany.com/myapp.php?foo=1&bar=2"
+		signature = "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+	)
+	params := url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		url       string
+		params    url.Values
+		signature string
+		want      bool
+	}{
+		{"documented example", authToken, rawURL, params, signature, true},
+		{"default port added", authToken, "https://mycompany.com:443/myapp.php?foo=1&bar=2", params, signature, true},
+		{"other token", "54321", rawURL, params, signature, false},
+		{"other url", authToken, "https://mycompany.com/other.php?foo=1&bar=2", params, signature, false},
+		{"changed parameter", authToken, rawURL, url.Values{"CallSid": {"CA1234567890ABCDE"}, "Digits": {"9999"}}, signature, false},
+		{"not base64", authToken, rawURL, params, "not a signature", false},
+		{"no token", "", rawURL, params, signature, false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidTwilioSignature(tt.token, tt.url, tt.params, tt.signature); got != tt.want {
+			t.Errorf("%s: ValidTwilioSignature = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}